@@ -29,6 +29,12 @@ const (
 // computationally about 2x the cost, and adds some metadata over
 // head. The ARCCache is similar, but does not require setting any
 // parameters.
+//
+// The evict callback, if any, is invoked once for every value that leaves
+// the cache, whether it is evicted for capacity, removed, purged or dropped
+// by Resize. Moving an entry between the recent and frequent queues is not
+// an eviction, and ghost entries hold no value, so neither invokes it. As
+// with Cache, replacing the value of a cached key does not invoke it either.
 type TwoQueueCache[K comparable, V any] struct {
 	size        int
 	recentSize  int
@@ -37,8 +43,25 @@ type TwoQueueCache[K comparable, V any] struct {
 
 	recent      simplelru.LRUCache[K, V]
 	frequent    simplelru.LRUCache[K, V]
-	recentEvict simplelru.LRUCache[K, struct{}]
+	recentEvict *ghostList[K]
 	lock        sync.RWMutex
+
+	// transient holds the keys in recent added by AddTransient
+	// which have not been hit since
+	transient map[K]struct{}
+
+	evictedKeys []K
+	evictedVals []V
+	onEvictedCB func(k K, v V)
+
+	// onPromotedCB is called for keys moving from recent to frequent,
+	// optional, see SetPromotionCallback
+	onPromotedCB func(k K, v V)
+
+	stats *simplelru.StatsCounter
+
+	// weights is set when the cache is bounded by weight, see New2QWeighted
+	weights *twoQueueWeights[K, V]
 }
 
 // New2Q creates a new TwoQueueCache using the default
@@ -47,9 +70,21 @@ func New2Q[K comparable, V any](size int) (*TwoQueueCache[K, V], error) {
 	return New2QParams[K, V](size, Default2QRecentRatio, Default2QGhostEntries)
 }
 
+// New2QWithEvict creates a new TwoQueueCache using the default
+// values for the parameters and the given eviction callback.
+func New2QWithEvict[K comparable, V any](size int, onEvicted func(key K, value V)) (*TwoQueueCache[K, V], error) {
+	return New2QParamsWithEvict(size, Default2QRecentRatio, Default2QGhostEntries, onEvicted)
+}
+
 // New2QParams creates a new TwoQueueCache using the provided
 // parameter values.
 func New2QParams[K comparable, V any](size int, recentRatio, ghostRatio float64) (*TwoQueueCache[K, V], error) {
+	return New2QParamsWithEvict[K, V](size, recentRatio, ghostRatio, nil)
+}
+
+// New2QParamsWithEvict creates a new TwoQueueCache using the provided
+// parameter values and eviction callback.
+func New2QParamsWithEvict[K comparable, V any](size int, recentRatio, ghostRatio float64, onEvicted func(key K, value V)) (*TwoQueueCache[K, V], error) {
 	if size <= 0 {
 		return nil, errors.New("invalid size")
 	}
@@ -73,10 +108,6 @@ func New2QParams[K comparable, V any](size int, recentRatio, ghostRatio float64)
 	if err != nil {
 		return nil, err
 	}
-	recentEvict, err := simplelru.NewLRU[K, struct{}](evictSize, nil)
-	if err != nil {
-		return nil, err
-	}
 
 	// Initialize the cache
 	c := &TwoQueueCache[K, V]{
@@ -86,51 +117,128 @@ func New2QParams[K comparable, V any](size int, recentRatio, ghostRatio float64)
 		ghostRatio:  ghostRatio,
 		recent:      recent,
 		frequent:    frequent,
-		recentEvict: recentEvict,
+		recentEvict: newGhostList[K](evictSize),
+		transient:   make(map[K]struct{}),
+		onEvictedCB: onEvicted,
+		stats:       new(simplelru.StatsCounter),
+	}
+	if onEvicted != nil {
+		c.initEvictBuffers()
 	}
 	return c, nil
 }
 
+func (c *TwoQueueCache[K, V]) initEvictBuffers() {
+	c.evictedKeys = make([]K, 0, DefaultEvictedBufferSize)
+	c.evictedVals = make([]V, 0, DefaultEvictedBufferSize)
+}
+
+// onEvicted saves a value dropped from the cache, to be sent to the
+// externally registered callback by unlock.
+func (c *TwoQueueCache[K, V]) onEvicted(k K, v V) {
+	if c.onEvictedCB != nil {
+		c.evictedKeys = append(c.evictedKeys, k)
+		c.evictedVals = append(c.evictedVals, v)
+	}
+}
+
+// unlock releases the write lock and invokes the evict callback for the
+// values dropped while it was held, outside of the critical section.
+func (c *TwoQueueCache[K, V]) unlock() {
+	var ks []K
+	var vs []V
+	if c.onEvictedCB != nil && len(c.evictedKeys) > 0 {
+		ks, vs = c.evictedKeys, c.evictedVals
+		c.initEvictBuffers()
+	}
+	c.lock.Unlock()
+	for i := 0; i < len(ks); i++ {
+		c.onEvictedCB(ks[i], vs[i])
+	}
+}
+
 // Get looks up a key's value from the cache.
 func (c *TwoQueueCache[K, V]) Get(key K) (value V, ok bool) {
+	value, _, ok = c.GetPromoted(key)
+	return value, ok
+}
+
+// GetPromoted looks up a key's value from the cache like Get, and also
+// reports whether the lookup promoted the key from the recent to the
+// frequent queue. The value itself is moved between the queues unchanged,
+// so resources tied to it survive the promotion.
+func (c *TwoQueueCache[K, V]) GetPromoted(key K) (value V, promoted, ok bool) {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	value, promoted, ok = c.get(key)
+	onPromoted := c.onPromotedCB
+	c.lock.Unlock()
+	if promoted && onPromoted != nil {
+		onPromoted(key, value)
+	}
+	return value, promoted, ok
+}
+
+// get looks up a key's value like GetPromoted. Has to be called with lock!
+func (c *TwoQueueCache[K, V]) get(key K) (value V, promoted, ok bool) {
+	defer func() { c.stats.Lookup(ok) }()
 
 	// Check if this is a frequent value
 	if val, ok := c.frequent.Get(key); ok {
-		return val, ok
+		return val, false, ok
 	}
 
 	// If the value is contained in recent, then we
-	// promote it to frequent
+	// promote it to frequent, unless it is transient
+	// and this is its first hit
 	if val, ok := c.recent.Peek(key); ok {
+		if _, ok := c.transient[key]; ok {
+			delete(c.transient, key)
+			c.recent.Get(key)
+			return val, false, true
+		}
 		c.recent.Remove(key)
 		c.frequent.Add(key, val)
-		return val, ok
+		c.weigh(key, val, true, true)
+		return val, true, ok
 	}
 
 	// No hit
 	return
 }
 
-// Add adds a value to the cache.
+// Add adds a value to the cache. Adding a key that is in the recent queue
+// promotes it to the frequent one.
 func (c *TwoQueueCache[K, V]) Add(key K, value V) {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	promoted := c.add(key, value)
+	onPromoted := c.onPromotedCB
+	c.unlock()
+	if promoted && onPromoted != nil {
+		onPromoted(key, value)
+	}
+}
+
+// add adds a value to the cache, returning whether it promoted the key from
+// the recent to the frequent queue. Has to be called with lock!
+func (c *TwoQueueCache[K, V]) add(key K, value V) (promoted bool) {
+	c.stats.Add(1)
 
 	// Check if the value is frequently used already,
 	// and just update the value
 	if c.frequent.Contains(key) {
 		c.frequent.Add(key, value)
-		return
+		c.weigh(key, value, true, true)
+		return false
 	}
 
 	// Check if the value is recently used, and promote
 	// the value into the frequent list
 	if c.recent.Contains(key) {
+		delete(c.transient, key)
 		c.recent.Remove(key)
 		c.frequent.Add(key, value)
-		return
+		c.weigh(key, value, true, true)
+		return true
 	}
 
 	// If the value was recently evicted, add it to the
@@ -138,34 +246,90 @@ func (c *TwoQueueCache[K, V]) Add(key K, value V) {
 	if c.recentEvict.Contains(key) {
 		c.ensureSpace(true)
 		c.recentEvict.Remove(key)
+		c.forgetGhost(key)
 		c.frequent.Add(key, value)
-		return
+		c.weigh(key, value, true, true)
+		return false
 	}
 
 	// Add to the recently seen list
 	c.ensureSpace(false)
 	c.recent.Add(key, value)
+	c.weigh(key, value, false, false)
+	return false
 }
 
-// ensureSpace is used to ensure we have space in the cache
+// AddTransient adds a value to the cache that is expected to be used only
+// once, such as data read by a batch scan. Unlike Add, its first hit keeps
+// it in the recent queue and only a second one promotes it to frequent, and
+// once evicted it is not remembered as a ghost entry, so scans cannot push
+// out the frequently used working set. Keys that are already cached have
+// their value updated without being promoted.
+func (c *TwoQueueCache[K, V]) AddTransient(key K, value V) {
+	c.lock.Lock()
+	defer c.unlock()
+	c.stats.Add(1)
+
+	if c.frequent.Contains(key) {
+		c.frequent.Add(key, value)
+		c.weigh(key, value, true, true)
+		return
+	}
+	if c.recent.Contains(key) {
+		c.recent.Add(key, value)
+		c.weigh(key, value, false, false)
+		return
+	}
+
+	// A ghost entry is stale once the key is cached again, but unlike Add
+	// the key is not promoted to frequent
+	if c.recentEvict.Contains(key) {
+		c.recentEvict.Remove(key)
+		c.forgetGhost(key)
+	}
+
+	c.ensureSpace(false)
+	c.recent.Add(key, value)
+	c.transient[key] = struct{}{}
+	c.weigh(key, value, false, false)
+}
+
+// ensureSpace is used to ensure we have space in the cache.
+// Weighted caches make space once the new entry is weighed instead.
 func (c *TwoQueueCache[K, V]) ensureSpace(recentEvict bool) {
 	// If we have space, nothing to do
-	recentLen := c.recent.Len()
-	freqLen := c.frequent.Len()
-	if recentLen+freqLen < c.size {
+	if c.weights != nil || c.recent.Len()+c.frequent.Len() < c.size {
 		return
 	}
+	c.evictOne(recentEvict)
+}
 
+// evictOne evicts an entry from the recent or the frequent queue,
+// depending on how the recent one compares with its target size.
+func (c *TwoQueueCache[K, V]) evictOne(recentEvict bool) {
 	// If the recent buffer is larger than
 	// the target, evict from there
-	if recentLen > 0 && (recentLen > c.recentSize || (recentLen == c.recentSize && !recentEvict)) {
-		k, _, _ := c.recent.RemoveOldest()
-		c.recentEvict.Add(k, struct{}{})
+	recentUsed, recentSize := c.recentUsed(), int64(c.recentSize)
+	if c.recent.Len() > 0 && (recentUsed > recentSize || (recentUsed == recentSize && !recentEvict) || c.frequent.Len() == 0) {
+		k, v, _ := c.recent.RemoveOldest()
+		w := c.unweigh(k)
+		c.onEvicted(k, v)
+		c.stats.Evict(1)
+		if _, ok := c.transient[k]; ok {
+			delete(c.transient, k)
+			return
+		}
+		c.recentEvict.Add(k)
+		c.rememberGhost(k, w)
 		return
 	}
 
 	// Remove from the frequent list otherwise
-	c.frequent.RemoveOldest()
+	if k, v, ok := c.frequent.RemoveOldest(); ok {
+		c.unweigh(k)
+		c.onEvicted(k, v)
+		c.stats.Evict(1)
+	}
 }
 
 // Len returns the number of items in the cache.
@@ -175,6 +339,30 @@ func (c *TwoQueueCache[K, V]) Len() int {
 	return c.recent.Len() + c.frequent.Len()
 }
 
+// RecentLen returns the number of items in the recent queue, those seen
+// once since they entered the cache.
+func (c *TwoQueueCache[K, V]) RecentLen() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.recent.Len()
+}
+
+// FrequentLen returns the number of items in the frequent queue.
+func (c *TwoQueueCache[K, V]) FrequentLen() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.frequent.Len()
+}
+
+// GhostLen returns the number of keys in the ghost queue, those recently
+// evicted from the recent queue, which enter the frequent queue when added
+// again.
+func (c *TwoQueueCache[K, V]) GhostLen() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.recentEvict.Len()
+}
+
 // Cap returns the capacity of the cache
 func (c *TwoQueueCache[K, V]) Cap() int {
 	return c.size
@@ -183,7 +371,7 @@ func (c *TwoQueueCache[K, V]) Cap() int {
 // Resize changes the cache size.
 func (c *TwoQueueCache[K, V]) Resize(size int) (evicted int) {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	defer c.unlock()
 
 	// Recalculate the sub-sizes
 	recentSize := int(float64(size) * c.recentRatio)
@@ -191,6 +379,14 @@ func (c *TwoQueueCache[K, V]) Resize(size int) (evicted int) {
 	c.size = size
 	c.recentSize = recentSize
 
+	if c.weights != nil {
+		n := c.recent.Len() + c.frequent.Len()
+		c.trimWeight(true)
+		c.weights.ghostSize = int64(evictSize)
+		c.trimGhosts()
+		return n - c.recent.Len() - c.frequent.Len()
+	}
+
 	// ensureSpace
 	diff := c.recent.Len() + c.frequent.Len() - size
 	if diff < 0 {
@@ -208,6 +404,56 @@ func (c *TwoQueueCache[K, V]) Resize(size int) (evicted int) {
 	return diff
 }
 
+// SetRatios changes the parameters of the cache without dropping its
+// contents, e.g. from an admin endpoint. The recent queue converges to its
+// new target size as entries are evicted, while ghost entries beyond the new
+// ghost size are forgotten right away.
+func (c *TwoQueueCache[K, V]) SetRatios(recentRatio, ghostRatio float64) error {
+	if recentRatio < 0.0 || recentRatio > 1.0 {
+		return errors.New("invalid recent ratio")
+	}
+	if ghostRatio < 0.0 || ghostRatio > 1.0 {
+		return errors.New("invalid ghost ratio")
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.recentRatio, c.ghostRatio = recentRatio, ghostRatio
+	c.recentSize = int(float64(c.size) * recentRatio)
+	evictSize := int(float64(c.size) * ghostRatio)
+	if c.weights != nil {
+		c.weights.ghostSize = int64(evictSize)
+		c.trimGhosts()
+		return nil
+	}
+	c.recentEvict.Resize(evictSize)
+	return nil
+}
+
+// SetPromotionCallback sets a callback invoked outside of the lock whenever
+// a Get or an Add promotes a key from the recent to the frequent queue, with
+// the value it holds afterwards, e.g. to count promotions or warm a second
+// tier with the entries that proved useful. Keys re-added after their
+// eviction go to the frequent queue as well, but are not promotions. A nil
+// callback turns it off.
+func (c *TwoQueueCache[K, V]) SetPromotionCallback(onPromoted func(key K, value V)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onPromotedCB = onPromoted
+}
+
+// Shrink evicts the given fraction of the entries, between 0 and 1, in the
+// order capacity evictions would, e.g. to release memory when the process
+// nears its memory limit. Returns the number of evictions.
+func (c *TwoQueueCache[K, V]) Shrink(fraction float64) (evicted int) {
+	c.lock.Lock()
+	defer c.unlock()
+	n := shrinkCount(c.recent.Len()+c.frequent.Len(), fraction)
+	for ; evicted < n; evicted++ {
+		c.evictOne(true)
+	}
+	return evicted
+}
+
 // Keys returns a slice of the keys in the cache.
 // The frequently used keys are first in the returned slice.
 func (c *TwoQueueCache[K, V]) Keys() []K {
@@ -231,14 +477,22 @@ func (c *TwoQueueCache[K, V]) Values() []V {
 // Remove removes the provided key from the cache.
 func (c *TwoQueueCache[K, V]) Remove(key K) {
 	c.lock.Lock()
-	defer c.lock.Unlock()
-	if c.frequent.Remove(key) {
+	defer c.unlock()
+	if v, ok := c.frequent.Peek(key); ok {
+		c.frequent.Remove(key)
+		c.unweigh(key)
+		c.onEvicted(key, v)
 		return
 	}
-	if c.recent.Remove(key) {
+	if v, ok := c.recent.Peek(key); ok {
+		c.recent.Remove(key)
+		c.unweigh(key)
+		delete(c.transient, key)
+		c.onEvicted(key, v)
 		return
 	}
 	if c.recentEvict.Remove(key) {
+		c.forgetGhost(key)
 		return
 	}
 }
@@ -246,10 +500,24 @@ func (c *TwoQueueCache[K, V]) Remove(key K) {
 // Purge is used to completely clear the cache.
 func (c *TwoQueueCache[K, V]) Purge() {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	defer c.unlock()
+	if c.onEvictedCB != nil {
+		for _, l := range []simplelru.LRUCache[K, V]{c.frequent, c.recent} {
+			keys, vals := l.Keys(), l.Values()
+			for i := range keys {
+				c.onEvicted(keys[i], vals[i])
+			}
+		}
+	}
 	c.recent.Purge()
 	c.frequent.Purge()
 	c.recentEvict.Purge()
+	c.transient = make(map[K]struct{})
+	if c.weights != nil {
+		c.weights.entries = make(map[K]queueWeight)
+		c.weights.ghosts = make(map[K]int64)
+		c.weights.recent, c.weights.frequent, c.weights.ghost = 0, 0, 0
+	}
 }
 
 // Contains is used to check if the cache contains a key
@@ -0,0 +1,29 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import "time"
+
+// Expirable2Q is a thread-safe fixed size TwoQueueCache whose entries also
+// expire a fixed TTL after they were last added, like those of
+// expirable.LRU. Its Keys are listed the frequently used ones first.
+type Expirable2Q[K comparable, V any] struct {
+	*Expiring[K, V]
+}
+
+// NewExpirable2Q creates an Expirable2Q of the given size with the default
+// recent and ghost ratios. onEvict, optional, is called after the lock is
+// released for every value that leaves the cache, including expired ones.
+//
+// Providing 0 TTL turns expiring off. Otherwise expired entries are deleted
+// every 1/100th of ttl by a goroutine which runs until Close is called.
+func NewExpirable2Q[K comparable, V any](size int, onEvict func(key K, value V), ttl time.Duration, opts ...ExpiryOption) (*Expirable2Q[K, V], error) {
+	e, err := NewExpiring(size, onEvict, ttl, func(onEvict func(K, ExpiringValue[V])) (ExpiringPolicy[K, ExpiringValue[V]], error) {
+		return New2QParamsWithEvict(size, Default2QRecentRatio, Default2QGhostEntries, onEvict)
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Expirable2Q[K, V]{e}, nil
+}
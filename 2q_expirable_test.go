@@ -0,0 +1,90 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpirable2Q(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	evicted := make(map[int]int)
+	l, err := NewExpirable2Q(4, func(k, v int) { evicted[k] = v }, time.Hour, WithExpiryClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 4; i++ {
+		if l.Add(i, i) {
+			t.Fatalf("%d should not have evicted", i)
+		}
+	}
+	// 0 and 1 move to the frequent queue, so that 2 is evicted first
+	l.Get(0)
+	l.Get(1)
+	if !l.Add(4, 4) {
+		t.Fatalf("should have evicted")
+	}
+	if l.Contains(2) || !l.Contains(0) {
+		t.Fatalf("bad keys: %v", l.Keys())
+	}
+
+	clock.Advance(40 * time.Minute)
+	l.Add(0, 10)
+	clock.Advance(40 * time.Minute)
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("1 should have expired")
+	}
+	if v, ok := l.Peek(0); !ok || v != 10 {
+		t.Fatalf("0 should have been renewed: %v, %v", v, ok)
+	}
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []int{0}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	if vals := l.Values(); !reflect.DeepEqual(vals, []int{10}) {
+		t.Fatalf("bad values: %v", vals)
+	}
+
+	// the cleanup removes the expired entries only
+	l.deleteExpired()
+	if l.Len() != 1 {
+		t.Fatalf("expired entries should have been removed: %d", l.Len())
+	}
+	clock.Advance(time.Hour)
+	l.deleteExpired()
+	if l.Len() != 0 {
+		t.Fatalf("expired entries should have been removed: %d", l.Len())
+	}
+	want := map[int]int{0: 10, 1: 1, 2: 2, 3: 3, 4: 4}
+	if !reflect.DeepEqual(evicted, want) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+}
+
+func TestExpirable2Q_Close(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	l, err := NewExpirable2Q[int, int](4, nil, time.Millisecond, WithExpiryClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Close()
+	l.Close()
+
+	// the cleanup would run every 10µs if it was not stopped
+	clock.Advance(time.Second)
+	time.Sleep(10 * time.Millisecond)
+	if l.Len() != 1 {
+		t.Fatalf("nothing should be cleaned up after Close")
+	}
+	if l.Contains(1) {
+		t.Fatalf("1 should have expired")
+	}
+	if _, ok := l.Get(1); ok || l.Len() != 0 {
+		t.Fatalf("Get should remove the expired entry")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+// ghostList is the ghost queue of a TwoQueueCache: the keys recently
+// evicted from its recent queue, oldest first, without values. It is a ring
+// buffer of keys with a map from each key to its slot. Removing a key only
+// drops it from the map, and the stale slot is skipped once it reaches the
+// head of the ring or dropped when the ring grows. Not thread safe.
+type ghostList[K comparable] struct {
+	size  int
+	slots []ghostSlot[K]
+	head  int // index of the oldest slot
+	n     int // number of slots in use, including stale ones
+	seq   uint64
+	index map[K]uint64 // seq of the slot holding each key
+}
+
+// ghostSlot is a key in a ghostList, stale if the index maps the key to
+// another seq, or not at all.
+type ghostSlot[K comparable] struct {
+	key K
+	seq uint64
+}
+
+// minGhostSlots is the smallest ring a ghostList allocates.
+const minGhostSlots = 8
+
+func newGhostList[K comparable](size int) *ghostList[K] {
+	return &ghostList[K]{size: size, index: make(map[K]uint64)}
+}
+
+// Add adds a key as the newest one, moving it if it is already there, and
+// forgets the oldest keys beyond the size of the list.
+func (g *ghostList[K]) Add(key K) {
+	if g.size <= 0 {
+		return
+	}
+	if g.n == len(g.slots) {
+		g.grow()
+	}
+	g.seq++
+	g.index[key] = g.seq
+	g.slots[(g.head+g.n)%len(g.slots)] = ghostSlot[K]{key: key, seq: g.seq}
+	g.n++
+	g.trim()
+}
+
+// Contains checks if a key is in the list.
+func (g *ghostList[K]) Contains(key K) bool {
+	_, ok := g.index[key]
+	return ok
+}
+
+// Remove removes a key from the list, returning if it was there.
+func (g *ghostList[K]) Remove(key K) bool {
+	if _, ok := g.index[key]; !ok {
+		return false
+	}
+	delete(g.index, key)
+	return true
+}
+
+// RemoveOldest removes the oldest key from the list.
+func (g *ghostList[K]) RemoveOldest() (key K, ok bool) {
+	for g.n > 0 {
+		s := g.slots[g.head]
+		g.slots[g.head] = ghostSlot[K]{}
+		g.head = (g.head + 1) % len(g.slots)
+		g.n--
+		if seq, ok := g.index[s.key]; ok && seq == s.seq {
+			delete(g.index, s.key)
+			return s.key, true
+		}
+	}
+	return key, false
+}
+
+// Len returns the number of keys in the list.
+func (g *ghostList[K]) Len() int {
+	return len(g.index)
+}
+
+// Resize changes the size of the list, forgetting the oldest keys beyond it.
+func (g *ghostList[K]) Resize(size int) {
+	g.size = size
+	g.trim()
+}
+
+// Purge removes all the keys.
+func (g *ghostList[K]) Purge() {
+	g.slots, g.head, g.n = nil, 0, 0
+	g.index = make(map[K]uint64)
+}
+
+func (g *ghostList[K]) trim() {
+	for len(g.index) > g.size {
+		g.RemoveOldest()
+	}
+}
+
+// grow reallocates the full ring without its stale slots, to twice the
+// number of keys it holds so that adding them back is amortized.
+func (g *ghostList[K]) grow() {
+	n := 2 * len(g.index)
+	if n < minGhostSlots {
+		n = minGhostSlots
+	}
+	slots := make([]ghostSlot[K], n)
+	live := 0
+	for i := 0; i < g.n; i++ {
+		s := g.slots[(g.head+i)%len(g.slots)]
+		if seq, ok := g.index[s.key]; ok && seq == s.seq {
+			slots[live] = s
+			live++
+		}
+	}
+	g.slots, g.head, g.n = slots, 0, live
+}
@@ -4,6 +4,7 @@
 package lru
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -376,3 +377,372 @@ func Test2Q_Peek(t *testing.T) {
 		t.Errorf("should not have updated recent-ness of 1")
 	}
 }
+
+func Test2Q_AddTransient(t *testing.T) {
+	l, err := New2Q[int, int](4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// the first hit keeps a transient entry in recent
+	l.AddTransient(1, 1)
+	if _, ok := l.Get(1); !ok {
+		t.Fatalf("missing: 1")
+	}
+	if n := l.recent.Len(); n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+	if n := l.frequent.Len(); n != 0 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// the second one promotes it
+	if _, ok := l.Get(1); !ok {
+		t.Fatalf("missing: 1")
+	}
+	if n := l.frequent.Len(); n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// transient entries leave no ghost behind
+	for i := 10; i < 20; i++ {
+		l.AddTransient(i, i)
+	}
+	if n := l.recentEvict.Len(); n != 0 {
+		t.Fatalf("bad: %d", n)
+	}
+	if !l.Contains(1) {
+		t.Fatalf("scan should not evict frequent entries")
+	}
+	if len(l.transient) != l.recent.Len() {
+		t.Fatalf("transient keys out of sync: %d != %d", len(l.transient), l.recent.Len())
+	}
+
+	// a regular Add promotes a transient entry
+	l.Add(19, 190)
+	if _, ok := l.transient[19]; ok {
+		t.Fatalf("19 should no longer be transient")
+	}
+	if v, ok := l.frequent.Peek(19); !ok || v != 190 {
+		t.Fatalf("19 should have been promoted")
+	}
+
+	// a transient add of a ghost key drops the stale ghost entry
+	l.Purge()
+	for i := 0; i < 6; i++ {
+		l.Add(i, i)
+	}
+	if !l.recentEvict.Contains(0) {
+		t.Fatalf("0 should be a ghost entry")
+	}
+	l.Remove(5) // no eviction pushes 0 out of the ghost queue
+	l.AddTransient(0, 10)
+	if l.recentEvict.Contains(0) {
+		t.Fatalf("stale ghost entry for 0")
+	}
+	if v, ok := l.recent.Peek(0); !ok || v != 10 {
+		t.Fatalf("0 should be in recent: %v, %v", v, ok)
+	}
+
+	l.Purge()
+	if len(l.transient) != 0 {
+		t.Fatalf("transient keys should be purged")
+	}
+}
+
+func Test2Q_GetPromoted(t *testing.T) {
+	l, err := New2Q[int, *int](4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	v := new(int)
+	l.Add(1, v)
+
+	got, promoted, ok := l.GetPromoted(1)
+	if !ok || !promoted || got != v {
+		t.Fatalf("first hit should promote the same value: %v, %v", promoted, ok)
+	}
+	if _, promoted, ok := l.GetPromoted(1); !ok || promoted {
+		t.Fatalf("frequent hits should not promote: %v, %v", promoted, ok)
+	}
+	if _, promoted, ok := l.GetPromoted(2); ok || promoted {
+		t.Fatalf("misses should not promote: %v, %v", promoted, ok)
+	}
+
+	l.AddTransient(3, new(int))
+	if _, promoted, _ := l.GetPromoted(3); promoted {
+		t.Fatalf("first hit of a transient entry should not promote")
+	}
+	if _, promoted, _ := l.GetPromoted(3); !promoted {
+		t.Fatalf("second hit of a transient entry should promote")
+	}
+}
+
+func Test2Q_PromotionCallback(t *testing.T) {
+	l, err := New2QParams[int, int](4, 0.5, 1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var promoted []int
+	l.SetPromotionCallback(func(k, v int) {
+		// called outside of the lock
+		if !l.Contains(k) {
+			t.Fatalf("%d should be contained", k)
+		}
+		promoted = append(promoted, k, v)
+	})
+
+	l.Add(1, 1)
+	l.Get(1)
+	l.Get(1)
+	if !reflect.DeepEqual(promoted, []int{1, 1}) {
+		t.Fatalf("a hit should report the promotion: %v", promoted)
+	}
+
+	// re-adding a recent key promotes it with the new value
+	promoted = nil
+	l.Add(2, 2)
+	l.Add(2, 20)
+	l.Add(2, 200)
+	if !reflect.DeepEqual(promoted, []int{2, 20}) {
+		t.Fatalf("an addition should report the promotion: %v", promoted)
+	}
+
+	// keys coming back from the ghost list are not promotions
+	promoted = nil
+	for i := 3; i < 8; i++ {
+		l.Add(i, i)
+	}
+	if l.Contains(3) {
+		t.Fatalf("3 should have been evicted")
+	}
+	l.Add(3, 3)
+	if len(promoted) != 0 {
+		t.Fatalf("bad promotions: %v", promoted)
+	}
+
+	l.SetPromotionCallback(nil)
+	l.Get(7)
+	if len(promoted) != 0 {
+		t.Fatalf("bad promotions: %v", promoted)
+	}
+}
+
+func Test2Q_EvictCallback(t *testing.T) {
+	evicted := make(map[int]int)
+	l, err := New2QWithEvict(4, func(k, v int) {
+		if k != v {
+			t.Fatalf("evicted %d with value %d", k, v)
+		}
+		evicted[k]++
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Promotions between the queues are not evictions
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+		l.Get(i)
+	}
+	if len(evicted) != 0 {
+		t.Fatalf("promotions invoked the callback: %v", evicted)
+	}
+
+	for i := 4; i < 8; i++ {
+		l.Add(i, i)
+	}
+	l.Remove(7)
+	l.Resize(2)
+	l.Purge()
+
+	if len(evicted) != 8 {
+		t.Fatalf("bad: %v", evicted)
+	}
+	for k, n := range evicted {
+		if n != 1 {
+			t.Fatalf("%d evicted %d times", k, n)
+		}
+	}
+}
+
+func Test2Q_Shrink(t *testing.T) {
+	evicted := 0
+	l, err := New2QWithEvict(8, func(k, v int) { evicted++ })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		l.Add(i, i)
+	}
+	for i := 0; i < 4; i++ {
+		l.Get(i)
+	}
+
+	// the recent queue is shrunk down to its target first
+	if n := l.Shrink(0.5); n != 4 || evicted != 4 {
+		t.Fatalf("bad: %d, %d evicted", n, evicted)
+	}
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []int{2, 3, 6, 7}) {
+		t.Fatalf("bad: %v", keys)
+	}
+	if n := l.Shrink(1); n != 4 || l.Len() != 0 {
+		t.Fatalf("bad: %d, %d left", n, l.Len())
+	}
+}
+
+func Test2Q_Weighted(t *testing.T) {
+	weigher := func(k, v int) int64 { return int64(v) }
+	var evicted []int
+	l, err := New2QWeighted(100, weigher, 0.25, 0.5, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := New2QWeighted[int, int](100, nil, 0.25, 0.5, nil); err == nil {
+		t.Fatalf("expected an error without a weigher")
+	}
+
+	for i := 1; i <= 4; i++ {
+		l.Add(i, 10)
+		l.Get(i)
+	}
+	l.Add(10, 50)
+	if w := l.Weight(); w != 90 {
+		t.Fatalf("bad weight: %d", w)
+	}
+
+	// the recent queue is over its weight target, so it is evicted from
+	l.Add(11, 30)
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []int{1, 2, 3, 4, 11}) {
+		t.Fatalf("bad: %v", keys)
+	}
+	if w := l.Weight(); w != 70 {
+		t.Fatalf("bad weight: %d", w)
+	}
+
+	// the ghost hit goes to frequent, evicting from recent
+	l.Add(10, 50)
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []int{1, 2, 3, 4, 10}) {
+		t.Fatalf("bad: %v", keys)
+	}
+
+	// a huge entry does not flush the frequent queue
+	l.Add(20, 200)
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []int{1, 2, 3, 4, 10}) {
+		t.Fatalf("bad: %v", keys)
+	}
+	if !reflect.DeepEqual(evicted, []int{10, 11, 20}) {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+
+	if n := l.Resize(50); n != 4 || l.Weight() != 50 {
+		t.Fatalf("bad: %d, weight %d", n, l.Weight())
+	}
+	l.Remove(10)
+	if l.Len() != 0 || l.Weight() != 0 {
+		t.Fatalf("bad: %d, weight %d", l.Len(), l.Weight())
+	}
+}
+
+func Test2Q_SetRatios(t *testing.T) {
+	l, err := New2Q[int, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 12; i++ {
+		l.Add(i, i)
+	}
+	if l.recentEvict.Len() != 4 {
+		t.Fatalf("bad ghost len: %d", l.recentEvict.Len())
+	}
+
+	if err := l.SetRatios(1.5, 0.5); err == nil {
+		t.Fatalf("expected an error for an invalid recent ratio")
+	}
+	if err := l.SetRatios(0.5, 0.25); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if l.recentEvict.Len() != 2 || l.Len() != 8 {
+		t.Fatalf("bad: %d ghosts, %d entries", l.recentEvict.Len(), l.Len())
+	}
+
+	// the recent queue shrinks to its new target of 4 entries
+	for i := 0; i < 4; i++ {
+		l.Get(8 + i)
+	}
+	for i := 12; i < 16; i++ {
+		l.Add(i, i)
+	}
+	if l.recent.Len() != 4 || l.frequent.Len() != 4 {
+		t.Fatalf("bad: %d recent, %d frequent", l.recent.Len(), l.frequent.Len())
+	}
+}
+
+func Test2Q_QueueLens(t *testing.T) {
+	l, err := New2Q[int, int](4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 6; i++ {
+		l.Add(i, i)
+	}
+	l.Add(0, 0)
+	l.Get(5)
+	if r, f, g := l.RecentLen(), l.FrequentLen(), l.GhostLen(); r != 2 || f != 2 || g != 2 {
+		t.Fatalf("bad: %d recent, %d frequent, %d ghosts", r, f, g)
+	}
+
+	l.Purge()
+	if r, f, g := l.RecentLen(), l.FrequentLen(), l.GhostLen(); r != 0 || f != 0 || g != 0 {
+		t.Fatalf("bad: %d recent, %d frequent, %d ghosts", r, f, g)
+	}
+}
+
+func Test2Q_NoGhosts(t *testing.T) {
+	l, err := New2QParams[int, int](4, Default2QRecentRatio, 0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		l.Add(i, i)
+	}
+	if n := l.GhostLen(); n != 0 {
+		t.Fatalf("bad: %d", n)
+	}
+}
+
+func TestGhostList(t *testing.T) {
+	g := newGhostList[int](4)
+	for i := 0; i < 100; i++ {
+		g.Add(i)
+		g.Remove(i - 2)
+	}
+	// the ring only holds stale slots beyond twice the live keys
+	if g.Len() != 2 || len(g.slots) > minGhostSlots {
+		t.Fatalf("bad: %d keys, %d slots", g.Len(), len(g.slots))
+	}
+
+	g.Add(98)
+	g.Add(1)
+	g.Add(2)
+	g.Add(3)
+	if g.Contains(99) || !g.Contains(98) {
+		t.Fatalf("bad: %d keys", g.Len())
+	}
+	for _, want := range []int{98, 1, 2, 3} {
+		if k, ok := g.RemoveOldest(); !ok || k != want {
+			t.Fatalf("got %d, %v, want %d", k, ok, want)
+		}
+	}
+	if _, ok := g.RemoveOldest(); ok {
+		t.Fatalf("expected an empty list")
+	}
+
+	g.Resize(2)
+	for i := 0; i < 4; i++ {
+		g.Add(i)
+	}
+	if g.Len() != 2 || !g.Contains(2) || !g.Contains(3) {
+		t.Fatalf("bad: %d keys", g.Len())
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"math"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// twoQueueWeights tracks the weights of the entries of a weighted
+// TwoQueueCache, and of the keys in its ghost queue.
+type twoQueueWeights[K comparable, V any] struct {
+	weigher   simplelru.Weigher[K, V]
+	entries   map[K]queueWeight
+	recent    int64
+	frequent  int64
+	ghosts    map[K]int64 // weights the ghost keys were evicted with
+	ghost     int64
+	ghostSize int64
+}
+
+// queueWeight is the weight of an entry and the queue it is in.
+type queueWeight struct {
+	weight   int64
+	frequent bool
+}
+
+// New2QWeighted creates a TwoQueueCache bounded by the total weight of its
+// entries instead of their number, as computed by weigher, e.g. their size
+// in bytes. The recent queue is targeted at recentRatio of maxWeight, and
+// the ghost queue remembers the keys of recently evicted entries weighing up
+// to ghostRatio of maxWeight, so that a few huge entries cannot take over
+// either of them. Cap and Resize are in units of weight.
+func New2QWeighted[K comparable, V any](maxWeight int64, weigher simplelru.Weigher[K, V], recentRatio, ghostRatio float64, onEvicted func(key K, value V)) (*TwoQueueCache[K, V], error) {
+	if weigher == nil {
+		return nil, errors.New("must provide a weigher")
+	}
+	if maxWeight > math.MaxInt {
+		return nil, errors.New("must provide a max weight fitting in an int")
+	}
+	c, err := New2QParamsWithEvict(int(maxWeight), recentRatio, ghostRatio, onEvicted)
+	if err != nil {
+		return nil, err
+	}
+	// the queues are only bounded by weight
+	c.recent.Resize(math.MaxInt)
+	c.frequent.Resize(math.MaxInt)
+	c.recentEvict.Resize(math.MaxInt)
+	c.weights = &twoQueueWeights[K, V]{
+		weigher:   weigher,
+		entries:   make(map[K]queueWeight),
+		ghosts:    make(map[K]int64),
+		ghostSize: int64(float64(maxWeight) * ghostRatio),
+	}
+	return c, nil
+}
+
+// Weight returns the total weight of the entries, or 0 unless the cache was
+// created by New2QWeighted.
+func (c *TwoQueueCache[K, V]) Weight() int64 {
+	if c.weights == nil {
+		return 0
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.weights.recent + c.weights.frequent
+}
+
+// recentUsed returns how much of the cache the recent queue takes up, in
+// entries or by weight.
+func (c *TwoQueueCache[K, V]) recentUsed() int64 {
+	if c.weights == nil {
+		return int64(c.recent.Len())
+	}
+	return c.weights.recent
+}
+
+// total returns the total weight of the recent or frequent queue.
+func (w *twoQueueWeights[K, V]) total(frequent bool) *int64 {
+	if frequent {
+		return &w.frequent
+	}
+	return &w.recent
+}
+
+// weigh records the weight of value, just added to or updated in the
+// frequent or recent queue, and evicts entries until the cache is within its
+// max weight again. A no-op unless weighted.
+func (c *TwoQueueCache[K, V]) weigh(key K, value V, frequent, recentEvict bool) {
+	if c.weights == nil {
+		return
+	}
+	c.unweigh(key)
+	w := c.weights.weigher(key, value)
+	c.weights.entries[key] = queueWeight{weight: w, frequent: frequent}
+	*c.weights.total(frequent) += w
+	c.trimWeight(recentEvict)
+}
+
+// unweigh forgets the weight of a key removed from the cache, returning it.
+// A no-op unless weighted.
+func (c *TwoQueueCache[K, V]) unweigh(key K) int64 {
+	if c.weights == nil {
+		return 0
+	}
+	e, ok := c.weights.entries[key]
+	if !ok {
+		return 0
+	}
+	delete(c.weights.entries, key)
+	*c.weights.total(e.frequent) -= e.weight
+	return e.weight
+}
+
+// trimWeight evicts entries until the cache is within its max weight, always
+// keeping the last one.
+func (c *TwoQueueCache[K, V]) trimWeight(recentEvict bool) {
+	for c.weights.recent+c.weights.frequent > int64(c.size) && c.recent.Len()+c.frequent.Len() > 1 {
+		c.evictOne(recentEvict)
+	}
+}
+
+// rememberGhost records the weight an evicted key had in the ghost queue,
+// trimming it to its budget. A no-op unless weighted.
+func (c *TwoQueueCache[K, V]) rememberGhost(key K, w int64) {
+	if c.weights == nil {
+		return
+	}
+	c.weights.ghosts[key] = w
+	c.weights.ghost += w
+	c.trimGhosts()
+}
+
+// trimGhosts forgets the oldest ghost keys until the ghost queue is within
+// its budget.
+func (c *TwoQueueCache[K, V]) trimGhosts() {
+	for c.weights.ghost > c.weights.ghostSize {
+		k, ok := c.recentEvict.RemoveOldest()
+		if !ok {
+			break
+		}
+		c.forgetGhost(k)
+	}
+}
+
+// forgetGhost forgets the weight of a key removed from the ghost queue. A
+// no-op unless weighted.
+func (c *TwoQueueCache[K, V]) forgetGhost(key K) {
+	if c.weights == nil {
+		return
+	}
+	c.weights.ghost -= c.weights.ghosts[key]
+	delete(c.weights.ghosts, key)
+}
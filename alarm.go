@@ -0,0 +1,71 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// hitRatioAlarm checks the hit ratio of each window of lookups.
+type hitRatioAlarm struct {
+	window    time.Duration
+	threshold float64
+	fn        func(stats Stats)
+
+	lock  sync.Mutex
+	end   time.Time // of the current window
+	start Stats     // at the start of the current window
+}
+
+// WithHitRatioAlarm calls fn with the Stats of each window of the given
+// duration, according to the cache's clock, whose hit ratio was below
+// threshold, e.g. to resize or warm the cache or to raise an alert. Windows
+// without lookups are skipped. The ratio is checked by the first lookup
+// after a window ends, outside of the lock, so fn may use the cache, and a
+// window in which the stats were reset is not checked.
+func WithHitRatioAlarm[K comparable, V any](window time.Duration, threshold float64, fn func(stats Stats)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if window <= 0 {
+			return errors.New("must provide a positive window")
+		}
+		if fn == nil {
+			return errors.New("must provide an alarm callback")
+		}
+		c.alarm = &hitRatioAlarm{window: window, threshold: threshold, fn: fn}
+		return nil
+	}
+}
+
+// checkHitRatio calls the alarm callback if a window ended with a low hit
+// ratio, before a lookup. Must be called without holding the lock.
+func (c *Cache[K, V]) checkHitRatio() {
+	a := c.alarm
+	now := c.clock.Now()
+	a.lock.Lock()
+	if now.Before(a.end) {
+		a.lock.Unlock()
+		return
+	}
+	stats := c.stats.Stats()
+	start, first := a.start, a.end.IsZero()
+	a.start, a.end = stats, now.Add(a.window)
+	a.lock.Unlock()
+
+	if first || stats.Hits < start.Hits || stats.Misses < start.Misses ||
+		stats.Adds < start.Adds || stats.Evictions < start.Evictions {
+		return
+	}
+	window := Stats{
+		Hits:        stats.Hits - start.Hits,
+		Misses:      stats.Misses - start.Misses,
+		Adds:        stats.Adds - start.Adds,
+		Evictions:   stats.Evictions - start.Evictions,
+		Expirations: stats.Expirations - start.Expirations,
+	}
+	if window.Hits+window.Misses > 0 && window.HitRatio() < a.threshold {
+		a.fn(window)
+	}
+}
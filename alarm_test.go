@@ -0,0 +1,52 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHitRatioAlarm(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	var alarms []Stats
+	l, err := NewWithOpts(8, WithClock[int, int](clock),
+		WithHitRatioAlarm[int, int](time.Minute, 0.5, func(stats Stats) {
+			alarms = append(alarms, stats)
+		}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		l.Add(i, i)
+	}
+
+	// the first window has a hit ratio of 3/4
+	for i := 0; i < 4; i++ {
+		l.Get(i)
+	}
+	clock.Advance(time.Minute)
+
+	// the second one of 1/3
+	l.Get(1)
+	l.Get(3)
+	l.Get(4)
+	if len(alarms) != 0 {
+		t.Fatalf("unexpected alarms: %+v", alarms)
+	}
+	clock.Advance(time.Minute)
+	l.Get(1)
+	if len(alarms) != 1 || alarms[0] != (Stats{Hits: 1, Misses: 2}) {
+		t.Fatalf("bad alarms: %+v", alarms)
+	}
+
+	// windows in which the stats were reset are skipped
+	l.Get(2)
+	l.ResetStats()
+	clock.Advance(time.Minute)
+	l.Get(1)
+	if len(alarms) != 1 {
+		t.Fatalf("unexpected alarms: %+v", alarms)
+	}
+}
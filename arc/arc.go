@@ -4,6 +4,7 @@
 package arc
 
 import (
+	"errors"
 	"sync"
 
 	"github.com/hashicorp/golang-lru/v2/simplelru"
@@ -17,9 +18,16 @@ import (
 // it is roughly 2x the cost, and the extra memory overhead is linear
 // with the size of the cache. ARC has been patented by IBM, but is
 // similar to the TwoQueueCache (2Q) which requires setting parameters.
+//
+// The evict callback, if any, is invoked once for every value that leaves
+// the cache, whether it is replaced out of T1 or T2, removed or purged.
+// Promoting an entry from T1 to T2 is not an eviction, and B1 and B2 hold
+// no values, so neither invokes it. Replacing the value of a cached key
+// does not invoke it either.
 type ARCCache[K comparable, V any] struct {
-	size int // Size is the total capacity of the cache
-	p    int // P is the dynamic preference towards T1 or T2
+	size      int // Size is the total capacity of the cache
+	ghostSize int // GhostSize is the total capacity of B1 and B2
+	p         int // P is the dynamic preference towards T1 or T2
 
 	t1 simplelru.LRUCache[K, V]        // T1 is the LRU for recently accessed items
 	b1 simplelru.LRUCache[K, struct{}] // B1 is the LRU for evictions from t1
@@ -28,16 +36,54 @@ type ARCCache[K comparable, V any] struct {
 	b2 simplelru.LRUCache[K, struct{}] // B2 is the LRU for evictions from t2
 
 	lock sync.RWMutex
+
+	evictedKeys []K
+	evictedVals []V
+	onEvictedCB func(k K, v V)
+
+	stats *statsCounter
 }
 
+// DefaultARCGhostRatio is the default ratio of ghost entries kept in B1
+// and B2 to the size of the cache.
+const DefaultARCGhostRatio = 1.0
+
 // NewARC creates an ARC of the given size
 func NewARC[K comparable, V any](size int) (*ARCCache[K, V], error) {
+	return NewARCParams[K, V](size, DefaultARCGhostRatio)
+}
+
+// NewARCWithEvict creates an ARC of the given size with the given
+// eviction callback.
+func NewARCWithEvict[K comparable, V any](size int, onEvicted func(key K, value V)) (*ARCCache[K, V], error) {
+	return NewARCParamsWithEvict(size, DefaultARCGhostRatio, onEvicted)
+}
+
+// NewARCParams creates an ARC of the given size that remembers up to
+// size*ghostRatio recently evicted keys in B1 and B2. A larger history lets
+// the cache adapt to longer reuse distances, at the cost of the memory for
+// one key per ghost entry, as reported by GhostLen.
+func NewARCParams[K comparable, V any](size int, ghostRatio float64) (*ARCCache[K, V], error) {
+	return NewARCParamsWithEvict[K, V](size, ghostRatio, nil)
+}
+
+// NewARCParamsWithEvict is like NewARCParams, with the given eviction
+// callback.
+func NewARCParamsWithEvict[K comparable, V any](size int, ghostRatio float64, onEvicted func(key K, value V)) (*ARCCache[K, V], error) {
+	if ghostRatio <= 0 {
+		return nil, errors.New("invalid ghost ratio")
+	}
+	ghostSize := int(float64(size) * ghostRatio)
+	if ghostSize < 1 && size > 0 {
+		ghostSize = 1
+	}
+
 	// Create the sub LRUs
-	b1, err := simplelru.NewLRU[K, struct{}](size, nil)
+	b1, err := simplelru.NewLRU[K, struct{}](ghostSize, nil)
 	if err != nil {
 		return nil, err
 	}
-	b2, err := simplelru.NewLRU[K, struct{}](size, nil)
+	b2, err := simplelru.NewLRU[K, struct{}](ghostSize, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -52,20 +98,45 @@ func NewARC[K comparable, V any](size int) (*ARCCache[K, V], error) {
 
 	// Initialize the ARC
 	c := &ARCCache[K, V]{
-		size: size,
-		p:    0,
-		t1:   t1,
-		b1:   b1,
-		t2:   t2,
-		b2:   b2,
+		size:      size,
+		ghostSize: ghostSize,
+		p:         0,
+		t1:        t1,
+		b1:        b1,
+		t2:        t2,
+		b2:        b2,
+
+		onEvictedCB: onEvicted,
+		stats:       new(statsCounter),
 	}
 	return c, nil
 }
 
+// onEvicted saves a value dropped from the cache, to be sent to the
+// externally registered callback by unlock.
+func (c *ARCCache[K, V]) onEvicted(k K, v V) {
+	if c.onEvictedCB != nil {
+		c.evictedKeys = append(c.evictedKeys, k)
+		c.evictedVals = append(c.evictedVals, v)
+	}
+}
+
+// unlock releases the write lock and invokes the evict callback for the
+// values dropped while it was held, outside of the critical section.
+func (c *ARCCache[K, V]) unlock() {
+	ks, vs := c.evictedKeys, c.evictedVals
+	c.evictedKeys, c.evictedVals = nil, nil
+	c.lock.Unlock()
+	for i := 0; i < len(ks); i++ {
+		c.onEvictedCB(ks[i], vs[i])
+	}
+}
+
 // Get looks up a key's value from the cache.
 func (c *ARCCache[K, V]) Get(key K) (value V, ok bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	defer func() { c.stats.lookup(ok) }()
 
 	// If the value is contained in T1 (recent), then
 	// promote it to T2 (frequent)
@@ -87,7 +158,8 @@ func (c *ARCCache[K, V]) Get(key K) (value V, ok bool) {
 // Add adds a value to the cache.
 func (c *ARCCache[K, V]) Add(key K, value V) {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	defer c.unlock()
+	c.stats.add(1)
 
 	// Check if the value is contained in T1 (recent), and potentially
 	// promote it to frequent T2
@@ -166,11 +238,13 @@ func (c *ARCCache[K, V]) Add(key K, value V) {
 		c.replace(false)
 	}
 
-	// Keep the size of the ghost buffers trim
-	if c.b1.Len() > c.size-c.p {
+	// Keep the size of the ghost buffers trim, splitting the ghost
+	// capacity between them in proportion to P
+	b2Target := c.p * c.ghostSize / c.size
+	if c.b1.Len() > c.ghostSize-b2Target {
 		c.b1.RemoveOldest()
 	}
-	if c.b2.Len() > c.p {
+	if c.b2.Len() > b2Target {
 		c.b2.RemoveOldest()
 	}
 
@@ -182,19 +256,116 @@ func (c *ARCCache[K, V]) Add(key K, value V) {
 // based on the current learned value of P
 func (c *ARCCache[K, V]) replace(b2ContainsKey bool) {
 	t1Len := c.t1.Len()
-	if t1Len > 0 && (t1Len > c.p || (t1Len == c.p && b2ContainsKey)) {
-		k, _, ok := c.t1.RemoveOldest()
+	if t1Len > 0 && (t1Len > c.p || (t1Len == c.p && b2ContainsKey) || c.t2.Len() == 0) {
+		k, v, ok := c.t1.RemoveOldest()
 		if ok {
+			c.onEvicted(k, v)
+			c.stats.evict(1)
 			c.b1.Add(k, struct{}{})
 		}
 	} else {
-		k, _, ok := c.t2.RemoveOldest()
+		k, v, ok := c.t2.RemoveOldest()
 		if ok {
+			c.onEvicted(k, v)
+			c.stats.evict(1)
 			c.b2.Add(k, struct{}{})
 		}
 	}
 }
 
+// SetGhostRatio changes the number of ghost entries kept in B1 and B2 to
+// size*ghostRatio without dropping the contents of the cache, e.g. from an
+// admin endpoint. Ghost entries beyond the new ghost size are forgotten
+// right away.
+func (c *ARCCache[K, V]) SetGhostRatio(ghostRatio float64) error {
+	if ghostRatio <= 0 {
+		return errors.New("invalid ghost ratio")
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ghostSize := int(float64(c.size) * ghostRatio)
+	if ghostSize < 1 {
+		ghostSize = 1
+	}
+	c.ghostSize = ghostSize
+	c.trimGhosts()
+	return nil
+}
+
+// Resize changes the cache size, replacing entries out of T1 and T2 as
+// capacity evictions would if it shrinks, and scales B1 and B2 along. Returns
+// the number of evictions.
+func (c *ARCCache[K, V]) Resize(size int) (evicted int) {
+	c.lock.Lock()
+	defer c.unlock()
+	for ; c.t1.Len()+c.t2.Len() > size; evicted++ {
+		c.replace(false)
+	}
+	if c.size > 0 {
+		c.ghostSize = c.ghostSize * size / c.size
+	}
+	if c.ghostSize < 1 {
+		c.ghostSize = 1
+	}
+	c.size = size
+	if c.p > size {
+		c.p = size
+	}
+	c.t1.Resize(size)
+	c.t2.Resize(size)
+	c.trimGhosts()
+	return evicted
+}
+
+// trimGhosts forgets the ghost entries beyond ghostSize, sharing it between
+// B1 and B2 according to p. Has to be called with lock!
+func (c *ARCCache[K, V]) trimGhosts() {
+	b2Target := 0
+	if c.size > 0 {
+		b2Target = c.p * c.ghostSize / c.size
+	}
+	for c.b1.Len() > c.ghostSize-b2Target {
+		c.b1.RemoveOldest()
+	}
+	for c.b2.Len() > b2Target {
+		c.b2.RemoveOldest()
+	}
+	c.b1.Resize(c.ghostSize)
+	c.b2.Resize(c.ghostSize)
+}
+
+// Shrink evicts the given fraction of the entries, between 0 and 1, in the
+// order capacity evictions would, remembering their keys in B1 and B2, e.g.
+// to release memory when the process nears its memory limit. Returns the
+// number of evictions.
+func (c *ARCCache[K, V]) Shrink(fraction float64) (evicted int) {
+	c.lock.Lock()
+	defer c.unlock()
+	n := c.t1.Len() + c.t2.Len()
+	if fraction <= 0 {
+		return 0
+	}
+	if fraction < 1 {
+		n = int(float64(n) * fraction)
+	}
+	for ; evicted < n; evicted++ {
+		c.replace(false)
+	}
+	return evicted
+}
+
+// Stats returns the numbers of lookups with Get, additions with Add, and
+// evictions from T1 and T2 since the cache was created or ResetStats was
+// called. Entries of ARCCache do not expire, so Expirations is always 0.
+func (c *ARCCache[K, V]) Stats() Stats {
+	return c.stats.stats()
+}
+
+// ResetStats sets the numbers returned by Stats back to zero.
+func (c *ARCCache[K, V]) ResetStats() {
+	c.stats.reset()
+}
+
 // Len returns the number of cached entries
 func (c *ARCCache[K, V]) Len() int {
 	c.lock.RLock()
@@ -207,6 +378,38 @@ func (c *ARCCache[K, V]) Cap() int {
 	return c.size
 }
 
+// GhostLen returns the number of evicted keys remembered in B1 and B2
+func (c *ARCCache[K, V]) GhostLen() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.b1.Len() + c.b2.Len()
+}
+
+// ARCState is a snapshot of the adaptive state of an ARCCache, as returned
+// by State. A P close to the capacity and a long B1 mean the workload is
+// dominated by recency, a P close to 0 and a long B2 by frequency.
+type ARCState struct {
+	P  int // target length of T1, adapted on every hit in B1 or B2
+	T1 int // entries seen once recently
+	T2 int // entries seen at least twice recently
+	B1 int // keys recently evicted from T1
+	B2 int // keys recently evicted from T2
+}
+
+// State returns the target length of T1 and the lengths of the four lists,
+// all taken at the same time.
+func (c *ARCCache[K, V]) State() ARCState {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return ARCState{
+		P:  c.p,
+		T1: c.t1.Len(),
+		T2: c.t2.Len(),
+		B1: c.b1.Len(),
+		B2: c.b2.Len(),
+	}
+}
+
 // Keys returns all the cached keys
 func (c *ARCCache[K, V]) Keys() []K {
 	c.lock.RLock()
@@ -228,11 +431,15 @@ func (c *ARCCache[K, V]) Values() []V {
 // Remove is used to purge a key from the cache
 func (c *ARCCache[K, V]) Remove(key K) {
 	c.lock.Lock()
-	defer c.lock.Unlock()
-	if c.t1.Remove(key) {
+	defer c.unlock()
+	if v, ok := c.t1.Peek(key); ok {
+		c.t1.Remove(key)
+		c.onEvicted(key, v)
 		return
 	}
-	if c.t2.Remove(key) {
+	if v, ok := c.t2.Peek(key); ok {
+		c.t2.Remove(key)
+		c.onEvicted(key, v)
 		return
 	}
 	if c.b1.Remove(key) {
@@ -246,7 +453,15 @@ func (c *ARCCache[K, V]) Remove(key K) {
 // Purge is used to clear the cache
 func (c *ARCCache[K, V]) Purge() {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	defer c.unlock()
+	if c.onEvictedCB != nil {
+		for _, l := range []simplelru.LRUCache[K, V]{c.t1, c.t2} {
+			keys, vals := l.Keys(), l.Values()
+			for i := range keys {
+				c.onEvicted(keys[i], vals[i])
+			}
+		}
+	}
 	c.t1.Purge()
 	c.t2.Purge()
 	c.b1.Purge()
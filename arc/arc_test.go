@@ -396,3 +396,209 @@ func TestARC_Peek(t *testing.T) {
 		t.Errorf("should not have updated recent-ness of 1")
 	}
 }
+
+// Test that NewARCParams bounds the ghost lists independently of the size
+func TestARC_GhostRatio(t *testing.T) {
+	if _, err := NewARCParams[int, int](128, 0); err == nil {
+		t.Fatalf("should have failed")
+	}
+
+	l, err := NewARCParams[int, int](128, 4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 1024; i++ {
+		l.Add(i, i)
+	}
+	if n := l.GhostLen(); n <= 128 || n > 512 {
+		t.Fatalf("bad ghost len: %v", n)
+	}
+	if l.Len() != 128 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+
+	// a key evicted long ago is still recognized and goes straight to T2
+	l.Add(500, 500)
+	if n := l.t2.Len(); n != 1 {
+		t.Fatalf("bad t2 len: %v", n)
+	}
+
+	d, err := NewARC[int, int](128)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 1024; i++ {
+		d.Add(i, i)
+	}
+	if n := d.GhostLen(); n > 128 {
+		t.Fatalf("bad ghost len: %v", n)
+	}
+}
+
+func TestARC_EvictCallback(t *testing.T) {
+	evicted := make(map[int]int)
+	l, err := NewARCWithEvict(4, func(k, v int) {
+		if k != v {
+			t.Fatalf("evicted %d with value %d", k, v)
+		}
+		evicted[k]++
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Promotions from T1 to T2 are not evictions
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+		l.Get(i)
+	}
+	if len(evicted) != 0 {
+		t.Fatalf("promotions invoked the callback: %v", evicted)
+	}
+
+	for i := 4; i < 8; i++ {
+		l.Add(i, i)
+	}
+	if len(evicted) != 4 {
+		t.Fatalf("bad: %v", evicted)
+	}
+	l.Remove(7)
+	l.Purge()
+
+	if len(evicted) != 8 {
+		t.Fatalf("bad: %v", evicted)
+	}
+	for k, n := range evicted {
+		if n != 1 {
+			t.Fatalf("%d evicted %d times", k, n)
+		}
+	}
+}
+
+func TestARC_Shrink(t *testing.T) {
+	evicted := 0
+	l, err := NewARCWithEvict(8, func(k, v int) { evicted++ })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		l.Add(i, i)
+	}
+	for i := 0; i < 4; i++ {
+		l.Get(i)
+	}
+
+	if n := l.Shrink(0.5); n != 4 || evicted != 4 || l.Len() != 4 {
+		t.Fatalf("bad: %d, %d evicted, %d left", n, evicted, l.Len())
+	}
+	if l.GhostLen() != 4 {
+		t.Fatalf("evicted keys should be remembered: %d", l.GhostLen())
+	}
+	if n := l.Shrink(1); n != 4 || l.Len() != 0 {
+		t.Fatalf("bad: %d, %d left", n, l.Len())
+	}
+}
+
+func TestARC_Resize(t *testing.T) {
+	evicted := 0
+	l, err := NewARCWithEvict(8, func(k, v int) { evicted++ })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 16; i++ {
+		l.Add(i, i)
+	}
+	evicted = 0
+
+	if n := l.Resize(4); n != 4 || evicted != 4 || l.Len() != 4 || l.Cap() != 4 {
+		t.Fatalf("bad: %d, %d evicted, %d left", n, evicted, l.Len())
+	}
+	if l.GhostLen() != 4 {
+		t.Fatalf("ghost entries should shrink along: %d", l.GhostLen())
+	}
+	for i := 16; i < 32; i++ {
+		l.Add(i, i)
+	}
+	if l.Len() != 4 || l.GhostLen() != 4 {
+		t.Fatalf("bad: %d entries, %d ghosts", l.Len(), l.GhostLen())
+	}
+
+	if n := l.Resize(8); n != 0 {
+		t.Fatalf("growing should not evict: %d", n)
+	}
+	for i := 32; i < 48; i++ {
+		l.Add(i, i)
+	}
+	if l.Len() != 8 || l.GhostLen() != 8 {
+		t.Fatalf("bad: %d entries, %d ghosts", l.Len(), l.GhostLen())
+	}
+}
+
+func TestARC_SetGhostRatio(t *testing.T) {
+	l, err := NewARC[int, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 16; i++ {
+		l.Add(i, i)
+	}
+	if l.GhostLen() != 8 {
+		t.Fatalf("bad ghost len: %d", l.GhostLen())
+	}
+
+	if err := l.SetGhostRatio(0); err == nil {
+		t.Fatalf("expected an error for an invalid ghost ratio")
+	}
+	if err := l.SetGhostRatio(0.25); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if l.GhostLen() != 2 || l.Len() != 8 {
+		t.Fatalf("bad: %d ghosts, %d entries", l.GhostLen(), l.Len())
+	}
+	for i := 16; i < 20; i++ {
+		l.Add(i, i)
+	}
+	if l.GhostLen() != 2 {
+		t.Fatalf("bad ghost len: %d", l.GhostLen())
+	}
+}
+
+func TestARC_Stats(t *testing.T) {
+	l, err := NewARC[int, int](2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Get(1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	l.Get(4)
+
+	want := Stats{Hits: 1, Misses: 1, Adds: 3, Evictions: 1}
+	if s := l.Stats(); s != want {
+		t.Fatalf("got %+v, want %+v", s, want)
+	}
+	l.ResetStats()
+	if s := l.Stats(); s != (Stats{}) {
+		t.Fatalf("bad: %+v", s)
+	}
+}
+
+func TestARC_State(t *testing.T) {
+	l, err := NewARC[int, int](4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		l.Add(i, i)
+	}
+	if s := l.State(); s != (ARCState{T1: 4, B1: 1}) {
+		t.Fatalf("bad: %+v", s)
+	}
+
+	// a hit in B1 grows the target of T1
+	l.Add(0, 0)
+	if s := l.State(); s != (ARCState{P: 1, T1: 3, T2: 1, B1: 1}) {
+		t.Fatalf("bad: %+v", s)
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package arc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// ExpirableARC is a thread-safe fixed size ARCCache whose entries also
+// expire a fixed TTL after they were last added, like those of
+// expirable.LRU, whose methods it has. Expired entries are no longer
+// returned, and are removed by a background goroutine until Close is
+// called, invoking the evict callback. Its Keys are listed those in T1
+// first, and GetOldest and RemoveOldest act on the next entry to expire.
+//
+// It works as lru.Expiring over an ARCCache does, which it is not built on
+// as long as this module requires a golang-lru/v2 release without it.
+type ExpirableARC[K comparable, V any] struct {
+	arc     *ARCCache[K, expiringValue[V]]
+	onEvict func(key K, value V)
+	ttl     time.Duration
+	clock   Clock
+
+	// expiry holds the cached keys from the first to the last to expire,
+	// since they all expire ttl after they were added
+	expiry *simplelru.LRU[K, time.Time]
+
+	// evicted entries are buffered while mu is held and passed to onEvict
+	// by unlock, so that the callback may call back into the cache
+	evictedKeys []K
+	evictedVals []V
+
+	mu        sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// expiringValue is a value stored in an ExpirableARC.
+type expiringValue[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Clock is the source of the current time entries expire by, such as
+// lru.RealClock, or lru.FakeClock in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is a Clock returning the system time.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// ExpiryOption configures the expiry of the entries of an ExpirableARC.
+type ExpiryOption func(*expiryOptions)
+
+type expiryOptions struct {
+	clock Clock
+}
+
+// WithExpiryClock sets the clock entries expire by, the system time by
+// default.
+func WithExpiryClock(clock Clock) ExpiryOption {
+	return func(o *expiryOptions) {
+		if clock != nil {
+			o.clock = clock
+		}
+	}
+}
+
+// expirableCleanups is how many times per TTL the cleanup goroutine of an
+// ExpirableARC looks for expired entries.
+const expirableCleanups = 100
+
+// NewExpirableARC creates an ExpirableARC of the given size with the default
+// ghost ratio. onEvict, optional, is called after the lock is released for
+// every value that leaves the cache, including expired ones.
+//
+// Providing 0 TTL turns expiring off. Otherwise expired entries are deleted
+// every 1/100th of ttl by a goroutine which runs until Close is called.
+func NewExpirableARC[K comparable, V any](size int, onEvict func(key K, value V), ttl time.Duration, opts ...ExpiryOption) (*ExpirableARC[K, V], error) {
+	o := expiryOptions{clock: realClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := &ExpirableARC[K, V]{onEvict: onEvict, ttl: ttl, clock: o.clock, done: make(chan struct{})}
+	var err error
+	c.arc, err = NewARCParamsWithEvict(size, DefaultARCGhostRatio, c.onEvicted)
+	if err != nil {
+		return nil, err
+	}
+	c.expiry, err = simplelru.NewLRU[K, time.Time](size, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		interval := ttl / expirableCleanups
+		if interval <= 0 {
+			interval = 1
+		}
+		go func(done <-chan struct{}) {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					c.deleteExpired()
+				}
+			}
+		}(c.done)
+	}
+	return c, nil
+}
+
+// onEvicted is called by the ARCCache, with mu held, for values leaving it.
+func (c *ExpirableARC[K, V]) onEvicted(k K, v expiringValue[V]) {
+	c.expiry.Remove(k)
+	if c.onEvict != nil {
+		c.evictedKeys = append(c.evictedKeys, k)
+		c.evictedVals = append(c.evictedVals, v.value)
+	}
+}
+
+// unlock releases mu and invokes the evict callback for the values dropped
+// while it was held, outside of the critical section.
+func (c *ExpirableARC[K, V]) unlock() {
+	ks, vs := c.evictedKeys, c.evictedVals
+	c.evictedKeys, c.evictedVals = nil, nil
+	c.mu.Unlock()
+	for i := 0; i < len(ks); i++ {
+		c.onEvict(ks[i], vs[i])
+	}
+}
+
+// Add adds a value to the cache, resetting its expiration time. Returns
+// true if an eviction occurred.
+func (c *ExpirableARC[K, V]) Add(key K, value V) (evicted bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.clock.Now().Add(c.ttl)
+	}
+	// evictions remove other keys from expiry before key is added to it
+	n := c.expiry.Len()
+	c.arc.Add(key, expiringValue[V]{value: value, expiresAt: expiresAt})
+	evicted = c.expiry.Len() < n
+	c.expiry.Add(key, expiresAt)
+	return evicted
+}
+
+// Get looks up a key's value from the cache, updating its recency and
+// frequency unless it expired.
+func (c *ExpirableARC[K, V]) Get(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	if v, ok := c.arc.Peek(key); ok && c.expired(v) {
+		c.arc.Remove(key)
+		return value, false
+	}
+	v, ok := c.arc.Get(key)
+	return v.value, ok
+}
+
+// Contains checks if a key is in the cache and has not expired, without
+// updating recency or frequency.
+func (c *ExpirableARC[K, V]) Contains(key K) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Peek returns the value of a key that has not expired, without updating
+// recency or frequency.
+func (c *ExpirableARC[K, V]) Peek(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.arc.Peek(key)
+	if !ok || c.expired(v) {
+		return value, false
+	}
+	return v.value, true
+}
+
+// Remove removes the provided key from the cache, returning if the key
+// was contained.
+func (c *ExpirableARC[K, V]) Remove(key K) (present bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	present = c.expiry.Contains(key)
+	c.arc.Remove(key)
+	return present
+}
+
+// GetOldest returns the entry that has not expired and was added the longest
+// ago, which is the next to expire, removing the expired ones before it.
+func (c *ExpirableARC[K, V]) GetOldest() (key K, value V, ok bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.removeExpired()
+	key, _, ok = c.expiry.GetOldest()
+	if !ok {
+		return key, value, false
+	}
+	v, _ := c.arc.Peek(key)
+	return key, v.value, true
+}
+
+// RemoveOldest removes the entry GetOldest returns, invoking the evict
+// callback.
+func (c *ExpirableARC[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.removeExpired()
+	key, _, ok = c.expiry.GetOldest()
+	if !ok {
+		return key, value, false
+	}
+	v, _ := c.arc.Peek(key)
+	c.arc.Remove(key)
+	return key, v.value, true
+}
+
+// Purge clears the cache completely, invoking the evict callback for every
+// value.
+func (c *ExpirableARC[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.unlock()
+	c.arc.Purge()
+}
+
+// Keys returns the keys of the entries that have not expired, those in T1
+// first.
+func (c *ExpirableARC[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := c.arc.Keys()
+	live := keys[:0]
+	for _, k := range keys {
+		if v, _ := c.arc.Peek(k); !c.expired(v) {
+			live = append(live, k)
+		}
+	}
+	return live
+}
+
+// Values returns the values of the entries that have not expired, in the
+// order of Keys.
+func (c *ExpirableARC[K, V]) Values() []V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	vals := c.arc.Values()
+	live := make([]V, 0, len(vals))
+	for _, v := range vals {
+		if !c.expired(v) {
+			live = append(live, v.value)
+		}
+	}
+	return live
+}
+
+// Len returns the number of cached entries, including expired ones that
+// were not removed yet.
+func (c *ExpirableARC[K, V]) Len() int {
+	return c.arc.Len()
+}
+
+// Cap returns the capacity of the cache.
+func (c *ExpirableARC[K, V]) Cap() int {
+	return c.arc.Cap()
+}
+
+// Resize changes the cache size, replacing entries as the ARCCache would if
+// it shrinks. Returns the number of evictions.
+func (c *ExpirableARC[K, V]) Resize(size int) (evicted int) {
+	c.mu.Lock()
+	defer c.unlock()
+	evicted = c.arc.Resize(size)
+	c.expiry.Resize(size)
+	return evicted
+}
+
+// Close stops the goroutine deleting expired entries. Expired entries are
+// still not returned afterwards, but are only removed by lookups.
+func (c *ExpirableARC[K, V]) Close() {
+	// under lock, so that no cleanup pass runs once Close returned
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// expired reports whether v expired. Has to be called with lock!
+func (c *ExpirableARC[K, V]) expired(v expiringValue[V]) bool {
+	return c.ttl > 0 && c.clock.Now().After(v.expiresAt)
+}
+
+// deleteExpired removes the entries that expired, first to last.
+func (c *ExpirableARC[K, V]) deleteExpired() {
+	c.mu.Lock()
+	defer c.unlock()
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	c.removeExpired()
+}
+
+// removeExpired removes the entries that expired, first to last. Has to be
+// called with lock!
+func (c *ExpirableARC[K, V]) removeExpired() {
+	if c.ttl <= 0 {
+		return
+	}
+	now := c.clock.Now()
+	for {
+		key, expiresAt, ok := c.expiry.GetOldest()
+		if !ok || expiresAt.After(now) {
+			return
+		}
+		c.arc.Remove(key)
+		c.expiry.Remove(key)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package arc
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock for tests, whose time only changes when advanced.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestExpirableARC(t *testing.T) {
+	var lock sync.Mutex
+	evicted := make(map[int]int)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l, err := NewExpirableARC(4, func(k, v int) {
+		lock.Lock()
+		defer lock.Unlock()
+		evicted[k] = v
+	}, time.Hour, WithExpiryClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 4; i++ {
+		if l.Add(i, i) {
+			t.Fatalf("%d should not have evicted", i)
+		}
+	}
+	// 0 and 1 move to T2, so that 2 is replaced out of T1 first
+	l.Get(0)
+	l.Get(1)
+	if !l.Add(4, 4) {
+		t.Fatalf("should have evicted")
+	}
+	if l.Contains(2) || !l.Contains(0) {
+		t.Fatalf("bad keys: %v", l.Keys())
+	}
+
+	clock.Advance(40 * time.Minute)
+	l.Add(0, 10)
+	clock.Advance(40 * time.Minute)
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("1 should have expired")
+	}
+	if v, ok := l.Peek(0); !ok || v != 10 {
+		t.Fatalf("0 should have been renewed: %v, %v", v, ok)
+	}
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []int{0}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	// the cleanup removes the rest
+	clock.Advance(time.Hour)
+	l.deleteExpired()
+	if l.Len() != 0 {
+		t.Fatalf("expired entries should have been removed: %d", l.Len())
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	want := map[int]int{0: 10, 1: 1, 2: 2, 3: 3, 4: 4}
+	if !reflect.DeepEqual(evicted, want) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+}
+
+func TestExpirableARC_NoTTL(t *testing.T) {
+	l, err := NewExpirableARC[int, int](2, nil, 0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Get(1)
+	l.Add(3, 3)
+	keys := l.Keys()
+	sort.Ints(keys)
+	if !reflect.DeepEqual(keys, []int{1, 3}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	if !l.Remove(1) || l.Remove(1) {
+		t.Fatalf("bad remove")
+	}
+	l.Purge()
+	if l.Len() != 0 || len(l.Values()) != 0 {
+		t.Fatalf("bad len after purge")
+	}
+	if _, err := NewExpirableARC[int, int](0, nil, time.Second); err == nil {
+		t.Fatalf("should fail for a zero size")
+	}
+}
+
+func TestExpirableARC_Close(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l, err := NewExpirableARC[int, int](2, nil, time.Millisecond, WithExpiryClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Close()
+	l.Close()
+	l.Add(1, 1)
+	clock.Advance(time.Second)
+	if l.Contains(1) {
+		t.Fatalf("1 should have expired")
+	}
+	if _, ok := l.Get(1); ok || l.Len() != 0 {
+		t.Fatalf("Get should have removed 1: %d", l.Len())
+	}
+}
+
+func TestExpirableARC_Oldest(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	var evicted []int
+	l, err := NewExpirableARC(4, func(k, v int) { evicted = append(evicted, k) }, time.Hour, WithExpiryClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+	if _, _, ok := l.GetOldest(); ok {
+		t.Fatalf("empty cache should have no oldest entry")
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i*10)
+		clock.Advance(time.Minute)
+	}
+	// re-adding renews an entry, hits don't
+	l.Add(0, 0)
+	l.Get(1)
+	if k, v, ok := l.GetOldest(); !ok || k != 1 || v != 10 {
+		t.Fatalf("bad oldest: %v, %v, %v", k, v, ok)
+	}
+	if k, _, ok := l.RemoveOldest(); !ok || k != 1 || l.Contains(1) {
+		t.Fatalf("bad removed oldest: %v, %v", k, ok)
+	}
+
+	// expired entries are removed on the way
+	clock.Advance(time.Hour - time.Minute)
+	if k, _, ok := l.GetOldest(); !ok || k != 0 {
+		t.Fatalf("bad oldest: %v, %v", k, ok)
+	}
+	if !reflect.DeepEqual(evicted, []int{1, 2, 3}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+
+	evicted = nil
+	l.Add(5, 5)
+	l.Add(6, 6)
+	if n := l.Resize(1); n != 2 || l.Len() != 1 || l.Cap() != 1 || len(evicted) != 2 {
+		t.Fatalf("bad resize: %d, %d, %v", n, l.Len(), evicted)
+	}
+	l.Add(7, 7)
+	if l.Len() != 1 {
+		t.Fatalf("bad len: %d", l.Len())
+	}
+}
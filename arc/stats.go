@@ -0,0 +1,77 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package arc
+
+import "sync/atomic"
+
+// Stats are the numbers of operations of an ARCCache, as returned by its
+// Stats method. It mirrors simplelru.Stats, which is newer than the release
+// of golang-lru/v2 this module requires.
+type Stats struct {
+	Hits        uint64 // lookups finding their key
+	Misses      uint64 // lookups not finding their key, or finding it expired
+	Adds        uint64 // additions and updates of values
+	Evictions   uint64 // entries removed to make room, including by Resize
+	Expirations uint64 // entries removed for having expired
+}
+
+// HitRatio returns the fraction of lookups that were hits, or 0 if there
+// were none.
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// statsCounter counts the operations of a cache for Stats. It is safe for
+// concurrent use, and must be allocated on its own so that its counters are
+// aligned for atomic access on 32-bit platforms.
+type statsCounter struct {
+	hits      uint64
+	misses    uint64
+	adds      uint64
+	evictions uint64
+}
+
+// lookup counts a hit if ok, a miss otherwise.
+func (s *statsCounter) lookup(ok bool) {
+	if ok {
+		atomic.AddUint64(&s.hits, 1)
+	} else {
+		atomic.AddUint64(&s.misses, 1)
+	}
+}
+
+// add counts n additions.
+func (s *statsCounter) add(n int) {
+	if n > 0 {
+		atomic.AddUint64(&s.adds, uint64(n))
+	}
+}
+
+// evict counts n evictions.
+func (s *statsCounter) evict(n int) {
+	if n > 0 {
+		atomic.AddUint64(&s.evictions, uint64(n))
+	}
+}
+
+// stats returns the current counts.
+func (s *statsCounter) stats() Stats {
+	return Stats{
+		Hits:      atomic.LoadUint64(&s.hits),
+		Misses:    atomic.LoadUint64(&s.misses),
+		Adds:      atomic.LoadUint64(&s.adds),
+		Evictions: atomic.LoadUint64(&s.evictions),
+	}
+}
+
+// reset sets all the counts back to zero.
+func (s *statsCounter) reset() {
+	atomic.StoreUint64(&s.hits, 0)
+	atomic.StoreUint64(&s.misses, 0)
+	atomic.StoreUint64(&s.adds, 0)
+	atomic.StoreUint64(&s.evictions, 0)
+}
@@ -0,0 +1,32 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import "github.com/hashicorp/golang-lru/v2/internal"
+
+// WithAutoClose closes values implementing io.Closer once they leave the
+// cache, whether they are evicted, removed, purged or replaced by an update
+// of their key, so that no evict callback is needed to release them. Values
+// handed back to the caller, such as by Pop, are not closed. Close is called
+// in a new goroutine, after the evict callback if any, and its errors are
+// passed to onError unless it is nil. Adding a value that is already cached
+// under the same key does not close it.
+func WithAutoClose[K comparable, V any](onError func(key K, err error)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.autoClose = &internal.AutoCloser[K]{OnError: onError}
+		return nil
+	}
+}
+
+// initAutoClose closes values after the evict callback. Replaced values
+// are closed by onReplaced.
+func (c *Cache[K, V]) initAutoClose() {
+	cb := c.onEvictedCB
+	c.onEvictedCB = func(k K, v V) {
+		defer c.autoClose.Close(k, v)
+		if cb != nil {
+			cb(k, v)
+		}
+	}
+}
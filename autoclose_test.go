@@ -0,0 +1,82 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type testCloser struct {
+	id     int
+	err    error
+	closed chan<- int
+}
+
+func (c *testCloser) Close() error {
+	c.closed <- c.id
+	return c.err
+}
+
+// wantClosed waits for the ids of the closed values.
+func wantClosed(t *testing.T, closed <-chan int, want ...int) {
+	t.Helper()
+	var got []int
+	for len(got) < len(want) {
+		select {
+		case id := <-closed:
+			got = append(got, id)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out with %v closed, want %v", got, want)
+		}
+	}
+	sort.Ints(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad closed: %v, want %v", got, want)
+	}
+	select {
+	case id := <-closed:
+		t.Fatalf("%d should not have been closed", id)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestCacheAutoClose(t *testing.T) {
+	closed := make(chan int, 16)
+	errs := make(chan error, 16)
+	l, err := NewWithOpts(2, WithAutoClose[int, *testCloser](func(key int, err error) {
+		errs <- err
+	}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	value := func(id int) *testCloser { return &testCloser{id: id, closed: closed} }
+
+	v1 := value(1)
+	l.Add(1, v1)
+	l.Add(1, v1) // same value, not closed
+	l.Add(1, value(2))
+	wantClosed(t, closed, 1)
+
+	l.Add(2, value(3))
+	l.Add(3, value(4)) // evicts 1
+	wantClosed(t, closed, 2)
+
+	l.Remove(2)
+	wantClosed(t, closed, 3)
+
+	l.Pop(3)
+	wantClosed(t, closed)
+
+	boom := errors.New("boom")
+	l.Add(4, &testCloser{id: 5, err: boom, closed: closed})
+	l.Purge()
+	wantClosed(t, closed, 5)
+	if err := <-errs; err != boom {
+		t.Fatalf("bad error: %v", err)
+	}
+}
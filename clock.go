@@ -0,0 +1,27 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// Clock is the source of the current time shared by all caches with
+// expiring entries, such as the negative results of Cache and the entries
+// of the expirable package, so that expiry can be tested without sleeping.
+type Clock = simplelru.Clock
+
+// RealClock is a Clock returning the system time, used by default.
+type RealClock = simplelru.RealClock
+
+// FakeClock is a Clock for tests, whose time only changes when it is told
+// to. It is safe for concurrent use.
+type FakeClock = simplelru.FakeClock
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return simplelru.NewFakeClock(now)
+}
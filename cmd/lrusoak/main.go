@@ -0,0 +1,359 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Command lrusoak hammers every cache type of the module with mixed
+// concurrent operations, TTL churn, Resize and Purge for as long as asked,
+// to reproduce rare corruptions and panics under load and to validate
+// concurrency changes. It is best built with the race detector:
+//
+//	go run -race ./cmd/lrusoak -duration 4h
+//
+// Every report interval it checks that no cache holds more entries than its
+// capacity, that the number of goroutines did not grow, and that the heap
+// stays below -max-heap after a collection. Once the run ends, the caches
+// are closed and the goroutines they started must be gone. It exits with
+// status 1 at the first failed check, and panics are left to crash it.
+//
+// ARCCache lives in its own module and is not covered.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+var (
+	duration = flag.Duration("duration", time.Hour, "how long to run")
+	workers  = flag.Int("workers", 4*runtime.GOMAXPROCS(0), "number of concurrent workers")
+	keys     = flag.Int("keys", 10000, "number of distinct keys")
+	size     = flag.Int("size", 1000, "capacity of the caches")
+	ttl      = flag.Duration("ttl", 50*time.Millisecond, "TTL of the expiring caches")
+	report   = flag.Duration("report", 10*time.Second, "interval between checks")
+	maxHeap  = flag.Uint64("max-heap", 1<<30, "heap size in bytes above which to fail")
+	seed     = flag.Int64("seed", time.Now().UnixNano(), "seed of the random operations")
+)
+
+// target is a cache under test.
+type target struct {
+	name  string
+	cap   int                            // most entries it may hold
+	op    func(r *rand.Rand, key, n int) // runs the operation picked by n
+	len   func() int
+	close func() // stops its goroutines, optional
+}
+
+// Operations are picked by a number n in [0, opRange): lookups below
+// opGet, additions below opAdd, and so on up to purges.
+const (
+	opGet    = 500
+	opAdd    = 850
+	opRemove = 950
+	opRead   = 990
+	opResize = 998
+	opRange  = 1000
+)
+
+func main() {
+	flag.Parse()
+	if *workers <= 0 || *keys <= 0 || *size <= 0 || *ttl <= 0 || *report <= 0 {
+		fmt.Fprintln(os.Stderr, "lrusoak: -workers, -keys, -size, -ttl and -report must be positive")
+		os.Exit(2)
+	}
+	fmt.Printf("lrusoak: seed %d, %d workers, %d keys, size %d\n", *seed, *workers, *keys, *size)
+
+	baseline := settledGoroutines(0)
+	targets, err := newTargets()
+	if err != nil {
+		fail("creating caches: %v", err)
+	}
+
+	var ops uint64
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < *workers; w++ {
+		wg.Add(1)
+		go func(r *rand.Rand) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for i := 0; i < 100; i++ {
+					t := targets[r.Intn(len(targets))]
+					t.op(r, r.Intn(*keys), r.Intn(opRange))
+				}
+				atomic.AddUint64(&ops, 100)
+			}
+		}(rand.New(rand.NewSource(*seed + int64(w))))
+	}
+
+	// goroutines started by the caches are all running by the first report
+	running := 0
+	start := time.Now()
+	ticker := time.NewTicker(*report)
+	for now := range ticker.C {
+		n := check(targets)
+		if running == 0 {
+			running = n
+		} else if n > running {
+			fail("goroutines grew from %d to %d", running, n)
+		}
+		if now.Sub(start) >= *duration {
+			break
+		}
+	}
+	ticker.Stop()
+	close(stop)
+	wg.Wait()
+
+	for _, t := range targets {
+		if t.close != nil {
+			t.close()
+		}
+	}
+	// the cleanup goroutine of expirable.LRU cannot be stopped
+	if n := settledGoroutines(baseline + 1); n > baseline+1 {
+		fail("%d goroutines left after closing the caches, want %d", n, baseline+1)
+	}
+	fmt.Printf("lrusoak: ok, %d operations in %v\n", atomic.LoadUint64(&ops), time.Since(start).Round(time.Second))
+}
+
+// check verifies the lengths of the caches and the heap size, prints a
+// report, and returns the number of goroutines.
+func check(targets []*target) int {
+	for _, t := range targets {
+		if n := t.len(); n > t.cap {
+			fail("%s holds %d entries, more than its capacity of %d", t.name, n, t.cap)
+		}
+	}
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	if m.HeapAlloc > *maxHeap {
+		fail("heap of %d bytes, above %d", m.HeapAlloc, *maxHeap)
+	}
+	n := runtime.NumGoroutine()
+	fmt.Printf("lrusoak: %d goroutines, heap %d KB\n", n, m.HeapAlloc>>10)
+	return n
+}
+
+// settledGoroutines waits up to a few seconds for the number of goroutines
+// to drop to want, and returns it.
+func settledGoroutines(want int) int {
+	n := runtime.NumGoroutine()
+	for i := 0; i < 50 && n > want; i++ {
+		time.Sleep(100 * time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	return n
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "lrusoak: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+// resizeTo returns a random size between half the capacity and all of it.
+func resizeTo(r *rand.Rand) int {
+	return *size/2 + r.Intn(*size/2+1)
+}
+
+func newTargets() ([]*target, error) {
+	cache, err := lru.NewWithOpts(*size, lru.WithEvictCallback(func(k, v int) {}))
+	if err != nil {
+		return nil, err
+	}
+	sharded, err := lru.NewSharded[int, int](*size, 8, func(k int) uint64 { return uint64(k) })
+	if err != nil {
+		return nil, err
+	}
+	twoQueue, err := lru.New2QWithEvict(*size, func(k, v int) {})
+	if err != nil {
+		return nil, err
+	}
+	expirable2Q, err := lru.NewExpirable2Q(*size, func(k, v int) {}, *ttl)
+	if err != nil {
+		return nil, err
+	}
+	intCache, err := lru.NewIntCacheWithEvict(*size, func(k int64, v int) {})
+	if err != nil {
+		return nil, err
+	}
+	tenants := *size / 10
+	if tenants == 0 {
+		tenants = 1
+	}
+	nested, err := lru.NewNested(tenants, *ttl, func(tenant int) (*lru.Cache[int, int], error) {
+		return lru.NewWithEvict(*size, func(k, v int) {})
+	})
+	if err != nil {
+		return nil, err
+	}
+	expiring := expirable.NewLRU(*size, func(k, v int) {}, *ttl)
+
+	return []*target{
+		{
+			name: "Cache",
+			cap:  cache.Cap(),
+			op: func(r *rand.Rand, key, n int) {
+				switch {
+				case n < opGet:
+					cache.Get(key)
+				case n < opAdd:
+					cache.Add(key, n)
+				case n < opRemove:
+					cache.Remove(key)
+				case n < opRead:
+					cache.PeekOldestN(10)
+				case n < opResize:
+					cache.Resize(resizeTo(r))
+				default:
+					cache.Purge()
+				}
+			},
+			len: cache.Len,
+		},
+		{
+			name: "ShardedCache",
+			cap:  sharded.Cap(),
+			op: func(r *rand.Rand, key, n int) {
+				switch {
+				case n < opGet:
+					sharded.Get(key)
+				case n < opAdd:
+					sharded.Add(key, n)
+				case n < opRemove:
+					sharded.Remove(key)
+				case n < opRead:
+					sharded.Keys()
+				case n < opResize:
+					sharded.Resize(resizeTo(r))
+				default:
+					sharded.Purge()
+				}
+			},
+			len: sharded.Len,
+		},
+		{
+			name: "TwoQueueCache",
+			cap:  twoQueue.Cap(),
+			op: func(r *rand.Rand, key, n int) {
+				switch {
+				case n < opGet:
+					twoQueue.Get(key)
+				case n < opAdd:
+					twoQueue.Add(key, n)
+				case n < opRemove:
+					twoQueue.Remove(key)
+				case n < opRead:
+					twoQueue.Keys()
+				case n < opResize:
+					twoQueue.Resize(resizeTo(r))
+				default:
+					twoQueue.Purge()
+				}
+			},
+			len: twoQueue.Len,
+		},
+		{
+			name: "Expirable2Q",
+			cap:  expirable2Q.Cap(),
+			op: func(r *rand.Rand, key, n int) {
+				switch {
+				case n < opGet:
+					expirable2Q.Get(key)
+				case n < opAdd:
+					expirable2Q.Add(key, n)
+				case n < opRemove:
+					expirable2Q.Remove(key)
+				case n < opResize:
+					expirable2Q.Keys()
+				default:
+					expirable2Q.Purge()
+				}
+			},
+			len:   expirable2Q.Len,
+			close: expirable2Q.Close,
+		},
+		{
+			name: "expirable.LRU",
+			cap:  *size,
+			op: func(r *rand.Rand, key, n int) {
+				switch {
+				case n < opGet:
+					expiring.Get(key)
+				case n < opAdd:
+					expiring.Add(key, n)
+				case n < opRemove:
+					expiring.Remove(key)
+				case n < opRead:
+					expiring.Keys()
+				case n < opResize:
+					if n%2 == 0 {
+						expiring.SetTTL(*ttl/2 + time.Duration(r.Int63n(int64(*ttl))))
+					} else {
+						expiring.Resize(resizeTo(r))
+					}
+				default:
+					expiring.Purge()
+				}
+			},
+			len: expiring.Len,
+		},
+		{
+			name: "IntCache",
+			cap:  intCache.Cap(),
+			op: func(r *rand.Rand, key, n int) {
+				switch {
+				case n < opGet:
+					intCache.Get(int64(key))
+				case n < opAdd:
+					intCache.Add(int64(key), n)
+				case n < opRemove:
+					intCache.Remove(int64(key))
+				case n < opResize:
+					intCache.Keys()
+				default:
+					intCache.Purge()
+				}
+			},
+			len: intCache.Len,
+		},
+		{
+			name: "Nested",
+			cap:  tenants,
+			op: func(r *rand.Rand, key, n int) {
+				tenant := key % (2 * tenants)
+				switch {
+				case n < opGet:
+					nested.Get(tenant, key)
+				case n < opAdd:
+					if _, err := nested.Add(tenant, key, n); err != nil {
+						fail("Nested: %v", err)
+					}
+				case n < opRemove:
+					nested.Remove(tenant)
+				case n < opResize:
+					if inner, err := nested.Inner(tenant); err == nil {
+						inner.Keys()
+					}
+				default:
+					nested.Purge()
+				}
+			},
+			len:   nested.Len,
+			close: nested.Close,
+		},
+	}, nil
+}
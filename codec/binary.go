@@ -0,0 +1,159 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Binary returns a compact codec for keys and values that are strings,
+// byte slices, or int, int64 or uint64 integers. Strings and byte slices are
+// written as their length followed by their bytes, and integers as varints.
+// It fails for other types.
+func Binary[K comparable, V any]() (Codec[K, V], error) {
+	putKey, readKey, err := binaryFuncs[K]()
+	if err != nil {
+		return nil, err
+	}
+	putValue, readValue, err := binaryFuncs[V]()
+	if err != nil {
+		return nil, err
+	}
+	return binaryCodec[K, V]{putKey, readKey, putValue, readValue}, nil
+}
+
+// binaryPut appends the encoding of v to b, binaryRead reads it back.
+type (
+	binaryPut[T any]  func(b []byte, v T) []byte
+	binaryRead[T any] func(r *bufio.Reader) (T, error)
+)
+
+func binaryFuncs[T any]() (binaryPut[T], binaryRead[T], error) {
+	var put any
+	var read any
+	switch any(*new(T)).(type) {
+	case string:
+		put = binaryPut[string](func(b []byte, v string) []byte {
+			return append(appendUvarint(b, uint64(len(v))), v...)
+		})
+		read = binaryRead[string](func(r *bufio.Reader) (string, error) {
+			b, err := readBytes(r)
+			return string(b), err
+		})
+	case []byte:
+		put = binaryPut[[]byte](func(b []byte, v []byte) []byte {
+			return append(appendUvarint(b, uint64(len(v))), v...)
+		})
+		read = binaryRead[[]byte](readBytes)
+	case int:
+		put = binaryPut[int](func(b []byte, v int) []byte {
+			return appendVarint(b, int64(v))
+		})
+		read = binaryRead[int](func(r *bufio.Reader) (int, error) {
+			n, err := binary.ReadVarint(r)
+			return int(n), err
+		})
+	case int64:
+		put = binaryPut[int64](appendVarint)
+		read = binaryRead[int64](func(r *bufio.Reader) (int64, error) {
+			return binary.ReadVarint(r)
+		})
+	case uint64:
+		put = binaryPut[uint64](appendUvarint)
+		read = binaryRead[uint64](func(r *bufio.Reader) (uint64, error) {
+			return binary.ReadUvarint(r)
+		})
+	default:
+		return nil, nil, errors.New("binary codec requires string, []byte, int, int64 or uint64 keys and values")
+	}
+	return put.(binaryPut[T]), read.(binaryRead[T]), nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+// readBytesChunk is the most readBytes allocates up front. Longer slices
+// are copied as they arrive, so that a corrupt length fails with an error
+// once the stream runs out instead of exhausting memory.
+const readBytesChunk = 64 << 10
+
+// readBytes reads a length-prefixed byte slice.
+func readBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > math.MaxInt64 {
+		return nil, errors.New("binary codec: length out of range")
+	}
+	if n <= readBytesChunk {
+		b := make([]byte, n)
+		if _, err = io.ReadFull(r, b); err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return b, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(readBytesChunk)
+	if _, err = io.CopyN(&buf, r, int64(n)); err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), err
+}
+
+type binaryCodec[K comparable, V any] struct {
+	putKey    binaryPut[K]
+	readKey   binaryRead[K]
+	putValue  binaryPut[V]
+	readValue binaryRead[V]
+}
+
+func (binaryCodec[K, V]) Name() string { return "binary" }
+
+func (c binaryCodec[K, V]) NewEncoder(w io.Writer) Encoder[K, V] {
+	return &binaryEncoder[K, V]{c: c, w: w}
+}
+
+func (c binaryCodec[K, V]) NewDecoder(r io.Reader) Decoder[K, V] {
+	return &binaryDecoder[K, V]{c: c, r: bufio.NewReader(r)}
+}
+
+type binaryEncoder[K comparable, V any] struct {
+	c   binaryCodec[K, V]
+	w   io.Writer
+	buf []byte
+}
+
+func (e *binaryEncoder[K, V]) Encode(key K, value V) error {
+	e.buf = e.c.putValue(e.c.putKey(e.buf[:0], key), value)
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+type binaryDecoder[K comparable, V any] struct {
+	c binaryCodec[K, V]
+	r *bufio.Reader
+}
+
+func (d *binaryDecoder[K, V]) Decode() (key K, value V, err error) {
+	if key, err = d.c.readKey(d.r); err != nil {
+		return key, value, err
+	}
+	if value, err = d.c.readValue(d.r); err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return key, value, err
+}
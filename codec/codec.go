@@ -0,0 +1,156 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package codec provides encodings of cache entries for snapshots, journals
+// and other persistence features, so that they don't hard-code one. Gob and
+// JSON work for most key and value types, Binary is a compact encoding for
+// strings, integers and byte slices, and other encodings such as protobuf
+// can be plugged in by implementing Codec.
+package codec
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// Codec creates encoders writing entries to a stream, and decoders reading
+// them back.
+type Codec[K comparable, V any] interface {
+	// Name identifies the codec in a Registry.
+	Name() string
+	NewEncoder(w io.Writer) Encoder[K, V]
+	NewDecoder(r io.Reader) Decoder[K, V]
+}
+
+// Encoder writes entries to a stream.
+type Encoder[K comparable, V any] interface {
+	Encode(key K, value V) error
+}
+
+// Decoder reads the entries written by an Encoder of the same codec, and
+// returns io.EOF once the stream ends between two entries.
+type Decoder[K comparable, V any] interface {
+	Decode() (key K, value V, err error)
+}
+
+// Gob returns a codec encoding entries with encoding/gob. Interface keys or
+// values have to be registered with gob.Register.
+func Gob[K comparable, V any]() Codec[K, V] {
+	return gobCodec[K, V]{}
+}
+
+type gobCodec[K comparable, V any] struct{}
+
+func (gobCodec[K, V]) Name() string { return "gob" }
+
+func (gobCodec[K, V]) NewEncoder(w io.Writer) Encoder[K, V] {
+	return &gobEncoder[K, V]{enc: gob.NewEncoder(w)}
+}
+
+func (gobCodec[K, V]) NewDecoder(r io.Reader) Decoder[K, V] {
+	return &gobDecoder[K, V]{dec: gob.NewDecoder(r)}
+}
+
+type gobEncoder[K comparable, V any] struct {
+	enc *gob.Encoder
+}
+
+func (e *gobEncoder[K, V]) Encode(key K, value V) error {
+	return e.enc.Encode(simplelru.Entry[K, V]{Key: key, Value: value})
+}
+
+type gobDecoder[K comparable, V any] struct {
+	dec *gob.Decoder
+}
+
+func (d *gobDecoder[K, V]) Decode() (key K, value V, err error) {
+	var e simplelru.Entry[K, V]
+	if err = d.dec.Decode(&e); err != nil {
+		return key, value, err
+	}
+	return e.Key, e.Value, nil
+}
+
+// JSON returns a codec encoding entries with encoding/json, one object per
+// line with the fields Key and Value.
+func JSON[K comparable, V any]() Codec[K, V] {
+	return jsonCodec[K, V]{}
+}
+
+type jsonCodec[K comparable, V any] struct{}
+
+func (jsonCodec[K, V]) Name() string { return "json" }
+
+func (jsonCodec[K, V]) NewEncoder(w io.Writer) Encoder[K, V] {
+	return &jsonEncoder[K, V]{enc: json.NewEncoder(w)}
+}
+
+func (jsonCodec[K, V]) NewDecoder(r io.Reader) Decoder[K, V] {
+	return &jsonDecoder[K, V]{dec: json.NewDecoder(r)}
+}
+
+type jsonEncoder[K comparable, V any] struct {
+	enc *json.Encoder
+}
+
+func (e *jsonEncoder[K, V]) Encode(key K, value V) error {
+	return e.enc.Encode(simplelru.Entry[K, V]{Key: key, Value: value})
+}
+
+type jsonDecoder[K comparable, V any] struct {
+	dec *json.Decoder
+}
+
+func (d *jsonDecoder[K, V]) Decode() (key K, value V, err error) {
+	var e simplelru.Entry[K, V]
+	if err = d.dec.Decode(&e); err != nil {
+		return key, value, err
+	}
+	return e.Key, e.Value, nil
+}
+
+// Registry holds codecs by name, e.g. to pick the one named in a
+// configuration file or a snapshot header. It is safe for concurrent use.
+type Registry[K comparable, V any] struct {
+	lock   sync.RWMutex
+	codecs map[string]Codec[K, V]
+}
+
+// NewRegistry returns a registry holding Gob and JSON, and Binary if K and V
+// are supported by it.
+func NewRegistry[K comparable, V any]() *Registry[K, V] {
+	r := &Registry[K, V]{codecs: make(map[string]Codec[K, V])}
+	r.codecs["gob"] = Gob[K, V]()
+	r.codecs["json"] = JSON[K, V]()
+	if c, err := Binary[K, V](); err == nil {
+		r.codecs[c.Name()] = c
+	}
+	return r
+}
+
+// Register adds a codec under its name, failing if the name is taken.
+func (r *Registry[K, V]) Register(c Codec[K, V]) error {
+	if c == nil {
+		return errors.New("must provide a codec")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.codecs[c.Name()]; ok {
+		return errors.New("codec already registered: " + c.Name())
+	}
+	r.codecs[c.Name()] = c
+	return nil
+}
+
+// Lookup returns the codec registered under name.
+func (r *Registry[K, V]) Lookup(name string) (c Codec[K, V], ok bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	c, ok = r.codecs[name]
+	return c, ok
+}
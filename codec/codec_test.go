@@ -0,0 +1,127 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package codec
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+func roundTrip[K comparable, V any](t *testing.T, c Codec[K, V], entries []simplelru.Entry[K, V]) {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := c.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e.Key, e.Value); err != nil {
+			t.Fatalf("%s: encode: %v", c.Name(), err)
+		}
+	}
+	dec := c.NewDecoder(&buf)
+	var got []simplelru.Entry[K, V]
+	for {
+		k, v, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%s: decode: %v", c.Name(), err)
+		}
+		got = append(got, simplelru.Entry[K, V]{Key: k, Value: v})
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Fatalf("%s: got %v, want %v", c.Name(), got, entries)
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	entries := []simplelru.Entry[string, []byte]{{Key: "a", Value: []byte("1")}, {Key: "", Value: []byte{0, 255}}, {Key: "ccc", Value: []byte("333")}}
+	r := NewRegistry[string, []byte]()
+	for _, name := range []string{"gob", "json", "binary"} {
+		c, ok := r.Lookup(name)
+		if !ok {
+			t.Fatalf("%s should be registered", name)
+		}
+		roundTrip(t, c, entries)
+	}
+
+	ints := []simplelru.Entry[int, int64]{{Key: -1, Value: 1 << 40}, {Key: 300, Value: -5}}
+	c, err := Binary[int, int64]()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	roundTrip(t, c, ints)
+}
+
+func TestBinaryTruncated(t *testing.T) {
+	c, err := Binary[string, uint64]()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := c.NewEncoder(&buf).Encode("key", 1000); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for n := 1; n < buf.Len(); n++ {
+		_, _, err := c.NewDecoder(bytes.NewReader(buf.Bytes()[:n])).Decode()
+		if err != io.ErrUnexpectedEOF {
+			t.Fatalf("truncated to %d bytes: got %v", n, err)
+		}
+	}
+}
+
+func TestBinaryCorruptLength(t *testing.T) {
+	c, err := Binary[string, []byte]()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, n := range []uint64{1 << 40, math.MaxInt64, math.MaxUint64} {
+		stream := appendUvarint(nil, n)
+		stream = append(stream, "short"...)
+		_, _, err := c.NewDecoder(bytes.NewReader(stream)).Decode()
+		if err == nil {
+			t.Fatalf("length %d: should have failed", n)
+		}
+	}
+
+	// long slices are still read in full
+	long := bytes.Repeat([]byte("x"), 3*readBytesChunk+1)
+	roundTrip(t, c, []simplelru.Entry[string, []byte]{{Key: "long", Value: long}})
+}
+
+type custom struct{}
+
+func (custom) Name() string                                    { return "gob" }
+func (custom) NewEncoder(w io.Writer) Encoder[string, float64] { return nil }
+func (custom) NewDecoder(r io.Reader) Decoder[string, float64] { return nil }
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry[string, float64]()
+	if _, ok := r.Lookup("binary"); ok {
+		t.Fatalf("binary does not support float64 values")
+	}
+	if _, err := Binary[string, float64](); err == nil {
+		t.Fatalf("binary should fail for float64 values")
+	}
+	if err := r.Register(custom{}); err == nil {
+		t.Fatalf("should not replace a registered codec")
+	}
+	if err := r.Register(nil); err == nil {
+		t.Fatalf("should fail for a nil codec")
+	}
+	if err := r.Register(renamed{custom{}}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if c, ok := r.Lookup("custom"); !ok || c.Name() != "custom" {
+		t.Fatalf("custom codec not found")
+	}
+}
+
+type renamed struct{ custom }
+
+func (renamed) Name() string { return "custom" }
@@ -0,0 +1,116 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// Codec compresses values stored by a Cache created with WithValueCompression.
+// Decompress receives the output of Compress. Both must not retain src.
+type Codec interface {
+	Compress(src []byte) []byte
+	Decompress(src []byte) ([]byte, error)
+}
+
+// CompressionThreshold is the minimum length in bytes of the values
+// WithValueCompression compresses; shorter ones rarely shrink enough to pay
+// for the CPU time.
+const CompressionThreshold = 256
+
+// the first byte of every stored value tells whether the rest is compressed
+const (
+	rawValue byte = iota
+	compressedValue
+)
+
+// WithValueCompression transparently compresses string or []byte values of
+// at least CompressionThreshold bytes with codec when they are added, and
+// decompresses them when they are read, trading CPU time for capacity.
+// Values that don't shrink are stored as is. Added and returned []byte
+// values are copied, so later changes to them don't affect the cache. If
+// codec fails to decompress an entry, lookups report a miss and bulk
+// accessors and the evict callback see the zero value.
+func WithValueCompression[K comparable, V any](codec Codec) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if codec == nil {
+			return errors.New("must provide a codec")
+		}
+		vc := &valueCodec[V]{codec: codec}
+		switch any(*new(V)).(type) {
+		case string:
+			vc.toBytes = func(v V) []byte { return []byte(any(v).(string)) }
+			vc.fromBytes = func(b []byte) V { return any(string(b)).(V) }
+		case []byte:
+			vc.toBytes = func(v V) []byte { return any(v).([]byte) }
+			vc.fromBytes = func(b []byte) V { return any(b).(V) }
+		default:
+			return errors.New("value compression requires string or []byte values")
+		}
+		c.codec = vc
+		return nil
+	}
+}
+
+// valueCodec converts between values and their stored form.
+type valueCodec[V any] struct {
+	codec     Codec
+	toBytes   func(V) []byte
+	fromBytes func([]byte) V
+}
+
+func (vc *valueCodec[V]) encode(v V) V {
+	b := vc.toBytes(v)
+	if len(b) >= CompressionThreshold {
+		if z := vc.codec.Compress(b); len(z) < len(b) {
+			return vc.fromBytes(append([]byte{compressedValue}, z...))
+		}
+	}
+	return vc.fromBytes(append([]byte{rawValue}, b...))
+}
+
+func (vc *valueCodec[V]) decode(v V) (value V, ok bool) {
+	b := vc.toBytes(v)
+	if len(b) == 0 {
+		return
+	}
+	if b[0] == rawValue {
+		// copy, so that callers changing a []byte value don't change the
+		// stored one
+		return vc.fromBytes(append([]byte(nil), b[1:]...)), true
+	}
+	b, err := vc.codec.Decompress(b[1:])
+	if err != nil {
+		return
+	}
+	return vc.fromBytes(b), true
+}
+
+// encode returns the stored form of value.
+func (c *Cache[K, V]) encode(value V) V {
+	if c.codec == nil {
+		return value
+	}
+	return c.codec.encode(value)
+}
+
+// decode returns the value stored as value, if ok.
+func (c *Cache[K, V]) decode(value V, ok bool) (V, bool) {
+	if c.codec == nil || !ok {
+		return value, ok
+	}
+	return c.codec.decode(value)
+}
+
+// decodeEntries decodes the values of entries in place.
+func (c *Cache[K, V]) decodeEntries(entries []simplelru.Entry[K, V]) []simplelru.Entry[K, V] {
+	if c.codec != nil {
+		for i := range entries {
+			entries[i].Value, _ = c.codec.decode(entries[i].Value)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"strings"
+	"testing"
+)
+
+type flateCodec struct {
+	compressed int
+}
+
+func (f *flateCodec) Compress(src []byte) []byte {
+	f.compressed++
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestSpeed)
+	w.Write(src)
+	w.Close()
+	return buf.Bytes()
+}
+
+func (f *flateCodec) Decompress(src []byte) ([]byte, error) {
+	return io.ReadAll(flate.NewReader(bytes.NewReader(src)))
+}
+
+func TestCacheValueCompression(t *testing.T) {
+	codec := &flateCodec{}
+	var evicted []string
+	l, err := NewWithOpts(2,
+		WithEvictCallback(func(k int, v string) { evicted = append(evicted, v) }),
+		WithValueCompression[int, string](codec))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	long := strings.Repeat("compressible ", 100)
+	l.Add(1, "short")
+	l.Add(2, long)
+	if codec.compressed != 1 {
+		t.Fatalf("only long values should be compressed: %v", codec.compressed)
+	}
+	if stored, _ := l.lru.Peek(2); len(stored) >= len(long) {
+		t.Fatalf("value should be stored compressed: %v", len(stored))
+	}
+
+	if v, ok := l.Get(1); !ok || v != "short" {
+		t.Fatalf("bad: %q", v)
+	}
+	if v, ok := l.Peek(2); !ok || v != long {
+		t.Fatalf("bad: %q", v)
+	}
+	if vals := l.Values(); len(vals) != 2 || vals[0] != long || vals[1] != "short" {
+		t.Fatalf("bad values: %v", vals)
+	}
+	if prev, ok, _ := l.PeekOrAdd(2, "other"); !ok || prev != long {
+		t.Fatalf("bad: %q", prev)
+	}
+
+	l.Add(3, "three")
+	if len(evicted) != 1 || evicted[0] != long {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+	if entries := l.PopOldestN(1); len(entries) != 1 || entries[0].Value != "short" {
+		t.Fatalf("bad entries: %v", entries)
+	}
+}
+
+func TestCacheValueCompressionBytes(t *testing.T) {
+	l, err := NewWithOpts(2, WithValueCompression[int, []byte](&flateCodec{}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	val := bytes.Repeat([]byte{'x'}, 1000)
+	l.Add(1, val)
+	val[0] = 'y'
+	if v, ok := l.Get(1); !ok || len(v) != 1000 || v[0] != 'x' {
+		t.Fatalf("bad: %v, %v", len(v), ok)
+	}
+
+	// values stored as is are copied when read
+	l.Add(2, []byte("short"))
+	v, _ := l.Get(2)
+	v[0] = 'S'
+	if v, ok := l.Get(2); !ok || string(v) != "short" {
+		t.Fatalf("bad: %q, %v", v, ok)
+	}
+
+	if _, err := NewWithOpts(2, WithValueCompression[int, int](&flateCodec{})); err == nil {
+		t.Fatalf("should have failed for non string values")
+	}
+	if _, err := NewWithOpts(2, WithValueCompression[int, string](nil)); err == nil {
+		t.Fatalf("should have failed for a nil codec")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrComputePanicked is returned by GetOrCompute to the callers waiting on
+// a compute function that panicked.
+var ErrComputePanicked = errors.New("lru: compute function panicked")
+
+// computeCall is a compute function in progress for GetOrCompute.
+type computeCall[V any] struct {
+	done  chan struct{} // closed once value and err are set
+	value V
+	err   error
+}
+
+// GetOrCompute looks up a key's value from the cache, and on a miss calls
+// compute and adds the value it returns. Concurrent callers missing the
+// same key wait for a single call of compute and share its result. Errors
+// are returned to all of them without being cached. compute is called
+// without holding the lock, so it may use the cache.
+func (c *Cache[K, V]) GetOrCompute(key K, compute func() (V, error)) (value V, err error) {
+	return c.GetOrComputeContext(context.Background(), key, compute)
+}
+
+// GetOrComputeContext is GetOrCompute, except that callers waiting for the
+// compute function of another caller give up with ctx's error once ctx is
+// done. ctx does not affect a compute function already running, which may
+// capture it to observe the cancellation itself.
+func (c *Cache[K, V]) GetOrComputeContext(ctx context.Context, key K, compute func() (V, error)) (value V, err error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+
+	c.computeLock.Lock()
+	if call, ok := c.computing[key]; ok {
+		c.computeLock.Unlock()
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			return value, ctx.Err()
+		}
+	}
+	// a call may have completed since the lookup
+	if value, ok := c.Peek(key); ok {
+		c.computeLock.Unlock()
+		return value, nil
+	}
+	if c.computing == nil {
+		c.computing = make(map[K]*computeCall[V])
+	}
+	call := &computeCall[V]{done: make(chan struct{}), err: ErrComputePanicked}
+	c.computing[key] = call
+	c.computeLock.Unlock()
+
+	defer func() {
+		c.computeLock.Lock()
+		delete(c.computing, key)
+		c.computeLock.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = compute()
+	if call.err == nil {
+		c.Add(key, call.value)
+	}
+	return call.value, call.err
+}
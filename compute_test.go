@@ -0,0 +1,140 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCache_GetOrCompute(t *testing.T) {
+	l, err := New[int, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var calls int64
+	release := make(chan struct{})
+	compute := func() (int, error) {
+		atomic.AddInt64(&calls, 1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := l.GetOrCompute(1, compute)
+			if err != nil {
+				t.Errorf("err: %v", err)
+			}
+			results[i] = v
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt64(&calls); n != 1 {
+		t.Fatalf("compute should run once, ran %d times", n)
+	}
+	for _, v := range results {
+		if v != 42 {
+			t.Fatalf("bad: %v", results)
+		}
+	}
+	if v, ok := l.Peek(1); !ok || v != 42 {
+		t.Fatalf("computed value should be cached: %v, %v", v, ok)
+	}
+
+	// errors are returned but not cached
+	errFailed := errors.New("failed")
+	if _, err := l.GetOrCompute(2, func() (int, error) { return 0, errFailed }); err != errFailed {
+		t.Fatalf("bad: %v", err)
+	}
+	if l.Contains(2) {
+		t.Fatalf("errors should not be cached")
+	}
+	if len(l.computing) != 0 {
+		t.Fatalf("finished calls should be forgotten: %v", l.computing)
+	}
+}
+
+func TestCache_GetOrComputePanic(t *testing.T) {
+	l, err := New[int, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		l.GetOrCompute(1, func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	done := make(chan error)
+	go func() {
+		_, err := l.GetOrCompute(1, func() (int, error) { return 1, nil })
+		done <- err
+	}()
+	close(release)
+	// the waiter, if it joined the panicking call, gets an error; if it
+	// arrived after, it computes the value itself
+	if err := <-done; err != nil && err != ErrComputePanicked {
+		t.Fatalf("bad: %v", err)
+	}
+	if len(l.computing) != 0 {
+		t.Fatalf("panicked calls should be forgotten: %v", l.computing)
+	}
+}
+
+func TestCache_GetOrComputeContext(t *testing.T) {
+	l, err := New[int, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err := l.GetOrCompute(1, func() (int, error) {
+			close(started)
+			<-release
+			return 42, nil
+		})
+		if v != 42 || err != nil {
+			t.Errorf("bad: %v, %v", v, err)
+		}
+	}()
+	<-started
+
+	// a waiter gives up once its context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.GetOrComputeContext(ctx, 1, func() (int, error) {
+		t.Errorf("compute should not run for a waiter")
+		return 0, nil
+	}); err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+
+	close(release)
+	<-done
+	if v, err := l.GetOrComputeContext(ctx, 1, nil); v != 42 || err != nil {
+		t.Fatalf("hits should not need the context: %v, %v", v, err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// concurrentCache is the subset of the cache APIs exercised by
+// BenchmarkConcurrent.
+type concurrentCache interface {
+	Add(key, value int64)
+	Get(key int64) (int64, bool)
+}
+
+type lruAdapter struct{ *Cache[int64, int64] }
+
+func (a lruAdapter) Add(key, value int64) { a.Cache.Add(key, value) }
+
+type intCacheAdapter struct{ *IntCache[int64] }
+
+func (a intCacheAdapter) Add(key, value int64) { a.IntCache.Add(key, value) }
+
+// BenchmarkConcurrent compares the thread-safe caches under different
+// read/write mixes and goroutine counts, over a key space twice the size
+// of the caches. Run with -cpu to vary GOMAXPROCS as well.
+func BenchmarkConcurrent(b *testing.B) {
+	const size = 8192
+	caches := []struct {
+		name string
+		new  func() concurrentCache
+	}{
+		{"Cache", func() concurrentCache {
+			l, _ := New[int64, int64](size)
+			return lruAdapter{l}
+		}},
+		{"TwoQueueCache", func() concurrentCache {
+			l, _ := New2Q[int64, int64](size)
+			return l
+		}},
+		{"IntCache", func() concurrentCache {
+			l, _ := NewIntCache[int64](size)
+			return intCacheAdapter{l}
+		}},
+	}
+	for _, cache := range caches {
+		for _, readPct := range []int{50, 90, 99} {
+			for _, goroutines := range []int{1, 4, 16} {
+				name := fmt.Sprintf("%s/reads=%d%%/goroutines=%d", cache.name, readPct, goroutines)
+				b.Run(name, func(b *testing.B) {
+					benchmarkConcurrent(b, cache.new(), readPct, goroutines, 2*size)
+				})
+			}
+		}
+	}
+}
+
+func benchmarkConcurrent(b *testing.B, c concurrentCache, readPct, goroutines, keys int) {
+	for i := 0; i < keys; i++ {
+		c.Add(int64(i), int64(i))
+	}
+	b.SetParallelism(goroutines)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(getRand(b)))
+		for pb.Next() {
+			k := r.Int63n(int64(keys))
+			if r.Intn(100) < readPct {
+				c.Get(k)
+			} else {
+				c.Add(k, k)
+			}
+		}
+	})
+}
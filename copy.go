@@ -0,0 +1,154 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"io"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/codec"
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// DefaultCopyBatchSize is the number of entries CopyTo adds at once unless
+// CopyOptions.BatchSize is set.
+const DefaultCopyBatchSize = 256
+
+// CopyOptions configures CopyTo, EncodeTo and LoadFrom.
+type CopyOptions struct {
+	// BatchSize is the number of entries added to the destination under a
+	// single lock acquisition, DefaultCopyBatchSize if 0.
+	BatchSize int
+
+	// Rate bounds the number of entries copied per second, to spread the
+	// load of warming a cache in service. Unlimited if 0.
+	Rate int
+
+	// Clock and Sleep pace Rate, RealClock and time.Sleep if nil.
+	Clock Clock
+	Sleep func(d time.Duration)
+}
+
+// CopyTo warms dst with the entries of c, e.g. to hand a working set over to
+// a new cache. The entries are read from oldest to newest as by Range, and
+// added in batches by AddMany, so that their recency order is preserved and
+// they end up the most recently used ones in dst. Entries of c are not
+// touched. Returns the number of entries copied.
+func (c *Cache[K, V]) CopyTo(dst *Cache[K, V], opts CopyOptions) (copied int) {
+	copied, _ = c.copyBatches(opts, func(entries []simplelru.Entry[K, V]) error {
+		dst.AddMany(entries)
+		return nil
+	})
+	return copied
+}
+
+// EncodeTo writes the entries of c to enc like CopyTo adds them, e.g. to
+// hand a working set over to another process through a socket, which reads
+// it with LoadFrom and the same codec. Returns the number of entries
+// written, and the first error of enc, which stops the copy.
+func (c *Cache[K, V]) EncodeTo(enc codec.Encoder[K, V], opts CopyOptions) (copied int, err error) {
+	return c.copyBatches(opts, func(entries []simplelru.Entry[K, V]) error {
+		for _, e := range entries {
+			if err := enc.Encode(e.Key, e.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LoadFrom adds the entries read from dec, such as those written by
+// EncodeTo, in batches by AddMany until dec returns io.EOF. Returns the
+// number of entries added, and any other error of dec, which stops the
+// load.
+func (c *Cache[K, V]) LoadFrom(dec codec.Decoder[K, V], opts CopyOptions) (copied int, err error) {
+	p := newCopyPacer(opts)
+	batch := make([]simplelru.Entry[K, V], 0, p.batch)
+	for {
+		key, value, err := dec.Decode()
+		if err == nil {
+			batch = append(batch, simplelru.Entry[K, V]{Key: key, Value: value})
+			if len(batch) < p.batch {
+				continue
+			}
+		}
+		if len(batch) > 0 {
+			c.AddMany(batch)
+			copied += len(batch)
+			batch = batch[:0]
+		}
+		if err == io.EOF {
+			return copied, nil
+		}
+		if err != nil {
+			return copied, err
+		}
+		p.wait(copied)
+	}
+}
+
+// copyBatches passes the entries of c to put in batches, from oldest to
+// newest, at the pace set by opts, until put fails.
+func (c *Cache[K, V]) copyBatches(opts CopyOptions, put func(entries []simplelru.Entry[K, V]) error) (copied int, err error) {
+	p := newCopyPacer(opts)
+	batch := make([]simplelru.Entry[K, V], 0, p.batch)
+	flush := func() bool {
+		if err = put(batch); err != nil {
+			return false
+		}
+		copied += len(batch)
+		batch = batch[:0]
+		return true
+	}
+	c.Range(func(key K, value V) bool {
+		if len(batch) == p.batch {
+			if !flush() {
+				return false
+			}
+			// the last batch is not waited for
+			p.wait(copied)
+		}
+		batch = append(batch, simplelru.Entry[K, V]{Key: key, Value: value})
+		return true
+	})
+	if err == nil && len(batch) > 0 {
+		flush()
+	}
+	return copied, err
+}
+
+// copyPacer spreads copied entries over time to honor CopyOptions.Rate.
+type copyPacer struct {
+	batch int
+	rate  int
+	clock Clock
+	sleep func(d time.Duration)
+	start time.Time
+}
+
+func newCopyPacer(opts CopyOptions) *copyPacer {
+	p := &copyPacer{batch: opts.BatchSize, rate: opts.Rate, clock: opts.Clock, sleep: opts.Sleep}
+	if p.batch <= 0 {
+		p.batch = DefaultCopyBatchSize
+	}
+	if p.clock == nil {
+		p.clock = RealClock{}
+	}
+	if p.sleep == nil {
+		p.sleep = time.Sleep
+	}
+	p.start = p.clock.Now()
+	return p
+}
+
+// wait sleeps until copied entries are due at the rate.
+func (p *copyPacer) wait(copied int) {
+	if p.rate <= 0 {
+		return
+	}
+	due := p.start.Add(time.Duration(copied) * time.Second / time.Duration(p.rate))
+	if d := due.Sub(p.clock.Now()); d > 0 {
+		p.sleep(d)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/codec"
+)
+
+func TestCacheCopyTo(t *testing.T) {
+	src, err := New[int, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dst, err := New[int, int](4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 6; i++ {
+		src.Add(i, i*10)
+	}
+	src.Get(0)
+	dst.Add(100, 100)
+
+	if n := src.CopyTo(dst, CopyOptions{BatchSize: 2}); n != 6 {
+		t.Fatalf("copied %d entries, want 6", n)
+	}
+	// the hottest entries of src are kept, in their order
+	if keys := dst.Keys(); !reflect.DeepEqual(keys, []int{3, 4, 5, 0}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	if v, ok := dst.Peek(0); !ok || v != 0 {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+	if src.Len() != 6 {
+		t.Fatalf("the source should be left untouched")
+	}
+}
+
+func TestCacheCopyToRate(t *testing.T) {
+	src, err := New[int, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dst, err := New[int, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		src.Add(i, i)
+	}
+	clock := NewFakeClock(time.Unix(0, 0))
+	var slept time.Duration
+	src.CopyTo(dst, CopyOptions{BatchSize: 2, Rate: 100, Clock: clock, Sleep: func(d time.Duration) {
+		slept += d
+		clock.Advance(d)
+	}})
+	// the last batch is not waited for
+	if slept != 60*time.Millisecond {
+		t.Fatalf("bad wait: %v", slept)
+	}
+	if dst.Len() != 8 {
+		t.Fatalf("bad len: %d", dst.Len())
+	}
+}
+
+func TestCacheEncodeTo(t *testing.T) {
+	src, err := New[string, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dst, err := New[string, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		src.Add(k, i)
+	}
+	src.Get("a")
+
+	c, err := codec.Binary[string, int]()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var buf bytes.Buffer
+	if n, err := src.EncodeTo(c.NewEncoder(&buf), CopyOptions{BatchSize: 2}); n != 5 || err != nil {
+		t.Fatalf("bad encode: %d, %v", n, err)
+	}
+	if n, err := dst.LoadFrom(c.NewDecoder(&buf), CopyOptions{BatchSize: 2}); n != 5 || err != nil {
+		t.Fatalf("bad load: %d, %v", n, err)
+	}
+	if keys := dst.Keys(); !reflect.DeepEqual(keys, []string{"b", "c", "d", "e", "a"}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	if v, ok := dst.Peek("e"); !ok || v != 4 {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+
+	// a truncated stream fails after loading the entries before the cut
+	buf.Reset()
+	src.EncodeTo(c.NewEncoder(&buf), CopyOptions{})
+	dst.Purge()
+	n, err := dst.LoadFrom(c.NewDecoder(bytes.NewReader(buf.Bytes()[:buf.Len()-1])), CopyOptions{})
+	if n != 4 || err != io.ErrUnexpectedEOF {
+		t.Fatalf("bad load: %d, %v", n, err)
+	}
+
+	// an encoder error stops the copy
+	errFull := errors.New("full")
+	n, err = src.EncodeTo(&failingEncoder[string, int]{left: 3, err: errFull}, CopyOptions{BatchSize: 2})
+	if n != 2 || err != errFull {
+		t.Fatalf("bad encode: %d, %v", n, err)
+	}
+}
+
+// failingEncoder fails once left entries were encoded.
+type failingEncoder[K comparable, V any] struct {
+	left int
+	err  error
+}
+
+func (e *failingEncoder[K, V]) Encode(K, V) error {
+	if e.left == 0 {
+		return e.err
+	}
+	e.left--
+	return nil
+}
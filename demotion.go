@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// demotionQueue holds the keys added to a cache in the order they reach the
+// demotion age.
+type demotionQueue[K comparable, V any] struct {
+	age time.Duration
+	fn  func(key K, value V)
+
+	lock   sync.Mutex
+	queue  []demotion[K]
+	head   int
+	queued map[K]time.Time // when the queued instance of each key was added
+}
+
+// demotion is a key due for demotion once its entry added at addedAt
+// reaches the demotion age.
+type demotion[K comparable] struct {
+	key     K
+	addedAt time.Time
+}
+
+// WithDemotionCallback calls fn once for every entry still cached age after
+// it was added according to the cache's clock, e.g. to move older values to
+// a cheaper representation by adding the converted value back. Updating a
+// value does not restart its age, so fn is not called again for it, but an
+// entry added again after leaving the cache is. Entries are checked at the
+// start of each Add and Get rather than by a timer, so fn may be called
+// later than age on an idle cache. fn is called outside of the lock, and
+// may use the cache.
+func WithDemotionCallback[K comparable, V any](age time.Duration, fn func(key K, value V)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if age <= 0 {
+			return errors.New("must provide a positive demotion age")
+		}
+		if fn == nil {
+			return errors.New("must provide a demotion callback")
+		}
+		c.demotion = &demotionQueue[K, V]{age: age, fn: fn, queued: make(map[K]time.Time)}
+		return nil
+	}
+}
+
+// trackDemotion queues key for demotion if it was just added. Has to be
+// called with lock!
+func (c *Cache[K, V]) trackDemotion(key K) {
+	d := c.demotion
+	if d == nil {
+		return
+	}
+	info, ok := c.lru.Info(key)
+	if !ok || c.clock.Now().Sub(info.AddedAt) >= d.age {
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if addedAt, ok := d.queued[key]; ok && addedAt.Equal(info.AddedAt) {
+		return
+	}
+	d.queued[key] = info.AddedAt
+	d.queue = append(d.queue, demotion[K]{key: key, addedAt: info.AddedAt})
+}
+
+// demote calls the demotion callback for the entries that reached the
+// demotion age.
+func (c *Cache[K, V]) demote() {
+	d := c.demotion
+	now := c.clock.Now()
+	d.lock.Lock()
+	var due []demotion[K]
+	for ; d.head < len(d.queue) && now.Sub(d.queue[d.head].addedAt) >= d.age; d.head++ {
+		e := d.queue[d.head]
+		if addedAt, ok := d.queued[e.key]; ok && addedAt.Equal(e.addedAt) {
+			delete(d.queued, e.key)
+		}
+		due = append(due, e)
+	}
+	if d.head > len(d.queue)/2 {
+		d.queue = append(d.queue[:0], d.queue[d.head:]...)
+		d.head = 0
+	}
+	d.lock.Unlock()
+	if len(due) == 0 {
+		return
+	}
+
+	entries := make([]simplelru.Entry[K, V], 0, len(due))
+	c.lock.RLock()
+	for _, e := range due {
+		if info, ok := c.lru.Info(e.key); ok && info.AddedAt.Equal(e.addedAt) {
+			value, _ := c.lru.Peek(e.key)
+			entries = append(entries, simplelru.Entry[K, V]{Key: e.key, Value: value})
+		}
+	}
+	c.lock.RUnlock()
+	for _, e := range entries {
+		value, _ := c.decode(e.Value, true)
+		d.fn(e.Key, value)
+	}
+}
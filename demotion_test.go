@@ -0,0 +1,52 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDemotionCallback(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	var demoted []int
+	var l *Cache[int, int]
+	l, err := NewWithOpts(8, WithClock[int, int](clock),
+		WithDemotionCallback(time.Minute, func(k, v int) {
+			demoted = append(demoted, k)
+			l.Add(k, -v) // demote in place
+		}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	clock.Advance(30 * time.Second)
+	l.Add(3, 3)
+	l.Add(1, 10) // updates don't restart the age
+	l.Remove(2)
+	clock.Advance(30 * time.Second)
+	l.Get(3)
+	if !reflect.DeepEqual(demoted, []int{1}) {
+		t.Fatalf("bad demoted: %v", demoted)
+	}
+	if v, _ := l.Peek(1); v != -10 {
+		t.Fatalf("1 should have been demoted in place: %d", v)
+	}
+
+	// entries added again after leaving the cache are demoted again
+	l.Add(2, 2)
+	clock.Advance(time.Minute)
+	l.Get(1)
+	if !reflect.DeepEqual(demoted, []int{1, 3, 2}) {
+		t.Fatalf("bad demoted: %v", demoted)
+	}
+	clock.Advance(time.Hour)
+	l.Get(1)
+	if len(demoted) != 3 {
+		t.Fatalf("entries should only be demoted once: %v", demoted)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import "errors"
+
+// NewDeterministic constructs a cache for the unit tests of code embedding
+// one, whose behavior only depends on the calls made to it: its clock is
+// clock, which only moves when told to, and the work it would do in new
+// goroutines is done synchronously by the calls causing it. Prefetches run
+// before Get returns, and WithAutoClose closes values in the goroutine
+// evicting them, which for replaced values is while the cache is locked.
+// Operation latencies reported by WithLatencyObserver are still measured
+// in wall time. opts are applied afterwards, as by NewWithOpts.
+//
+// With WithApproximatedLRU, the entries to evict are sampled with the fixed
+// deterministicSeed rather than a random one, so they are reproducible as
+// well.
+func NewDeterministic[K comparable, V any](size int, clock *FakeClock, opts ...Option[K, V]) (*Cache[K, V], error) {
+	if clock == nil {
+		return nil, errors.New("must provide a clock")
+	}
+	deterministic := func(c *Cache[K, V]) error {
+		c.deterministic = true
+		return nil
+	}
+	return NewWithOpts(size, append([]Option[K, V]{deterministic, WithClock[K, V](clock)}, opts...)...)
+}
+
+// deterministicSeed seeds the sampling of WithApproximatedLRU in the caches
+// constructed by NewDeterministic.
+const deterministicSeed = 0x9e3779b97f4a7c15
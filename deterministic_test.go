@@ -0,0 +1,81 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+func TestNewDeterministic(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	closed := make(chan int, 4)
+	l, err := NewDeterministic(2, clock,
+		WithPrefetcher(func(k int) []simplelru.Entry[int, io.Closer] {
+			return []simplelru.Entry[int, io.Closer]{{Key: k + 1, Value: &testCloser{id: k + 1, closed: closed}}}
+		}),
+		WithAutoClose[int, io.Closer](nil))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// the prefetch is done by the time Get returns
+	l.Add(1, &testCloser{id: 1, closed: closed})
+	if _, ok := l.Get(4); ok {
+		t.Fatalf("4 should miss")
+	}
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []int{5, 1}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	// evicted values are closed by the time Add returns
+	l.Add(2, &testCloser{id: 2, closed: closed})
+	select {
+	case id := <-closed:
+		if id != 5 {
+			t.Fatalf("closed %d, want 5", id)
+		}
+	default:
+		t.Fatalf("5 should have been closed")
+	}
+
+	// negative results expire by the fake clock
+	errFailed := errors.New("failed")
+	l.AddError(3, errFailed, time.Minute)
+	if _, err, ok := l.GetResult(3); !ok || err != errFailed {
+		t.Fatalf("bad result: %v, %v", err, ok)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, _, ok := l.GetResult(3); ok {
+		t.Fatalf("the negative result should have expired")
+	}
+
+	if _, err := NewDeterministic[int, int](2, nil); err == nil {
+		t.Fatalf("should fail without a clock")
+	}
+}
+
+func TestNewDeterministic_Sampling(t *testing.T) {
+	evictions := func() []int {
+		var evicted []int
+		l, err := NewDeterministic(16, NewFakeClock(time.Unix(0, 0)),
+			WithEvictCallback(func(k, v int) { evicted = append(evicted, k) }),
+			WithApproximatedLRU[int, int](2))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		for i := 0; i < 64; i++ {
+			l.Add(i, i)
+		}
+		return evicted
+	}
+	if first := evictions(); !reflect.DeepEqual(evictions(), first) {
+		t.Fatalf("evictions should be reproducible: %v", first)
+	}
+}
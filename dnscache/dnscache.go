@@ -0,0 +1,145 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package dnscache provides a cache of DNS answers following resource
+// record caching semantics: each answer is kept for the TTL it came with
+// from upstream, lookups report the TTL remaining, and negative answers
+// (NXDOMAIN or NODATA) are cached under their own ceiling as in RFC 2308.
+package dnscache
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// Question identifies an answer by owner name and record type. Names are
+// compared case-insensitively and with or without a trailing dot.
+type Question struct {
+	Name string
+	Type uint16
+}
+
+// Cache is a thread-safe fixed size LRU cache of answers of type A, e.g.
+// a slice of resource records.
+type Cache[A any] struct {
+	lru            *lru.Cache[Question, answer[A]]
+	maxTTL         time.Duration
+	maxNegativeTTL time.Duration
+	clock          lru.Clock
+}
+
+// answer is a cached answer with its absolute expiry time.
+type answer[A any] struct {
+	value     A
+	negative  bool
+	expiresAt time.Time
+}
+
+// Option configures a Cache constructed by New.
+type Option func(*options)
+
+type options struct {
+	clock lru.Clock
+}
+
+// WithClock sets the clock answers expire by, lru.RealClock by default.
+func WithClock(clock lru.Clock) Option {
+	return func(o *options) {
+		if clock != nil {
+			o.clock = clock
+		}
+	}
+}
+
+// New creates a Cache of up to size answers. TTLs from upstream are capped
+// at maxTTL for positive answers and at maxNegativeTTL for negative ones.
+func New[A any](size int, maxTTL, maxNegativeTTL time.Duration, opts ...Option) (*Cache[A], error) {
+	if maxTTL < 0 || maxNegativeTTL < 0 {
+		return nil, errors.New("must provide non-negative TTL ceilings")
+	}
+	o := options{clock: lru.RealClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	l, err := lru.New[Question, answer[A]](size)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cache[A]{
+		lru:            l,
+		maxTTL:         maxTTL,
+		maxNegativeTTL: maxNegativeTTL,
+		clock:          o.clock,
+	}
+	return c, nil
+}
+
+// Add caches the answer to q for ttl, capped at the positive TTL ceiling.
+// Answers with a TTL of zero are not cached, as they may only be used for
+// the transaction in progress.
+func (c *Cache[A]) Add(q Question, value A, ttl time.Duration) {
+	c.add(q, answer[A]{value: value}, ttl, c.maxTTL)
+}
+
+// AddNegative caches the absence of an answer to q for ttl, usually the
+// minimum of the SOA record of the zone, capped at the negative TTL
+// ceiling.
+func (c *Cache[A]) AddNegative(q Question, ttl time.Duration) {
+	c.add(q, answer[A]{negative: true}, ttl, c.maxNegativeTTL)
+}
+
+func (c *Cache[A]) add(q Question, a answer[A], ttl, maxTTL time.Duration) {
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+	q = normalize(q)
+	if ttl <= 0 {
+		c.lru.Remove(q)
+		return
+	}
+	a.expiresAt = c.clock.Now().Add(ttl)
+	c.lru.Add(q, a)
+}
+
+// Get looks up the answer to q, returning the TTL it has left, to be
+// passed on to clients, and whether it is negative. Expired answers are
+// removed and not returned.
+func (c *Cache[A]) Get(q Question) (value A, ttl time.Duration, negative, ok bool) {
+	q = normalize(q)
+	a, ok := c.lru.Get(q)
+	if !ok {
+		return value, 0, false, false
+	}
+	ttl = a.expiresAt.Sub(c.clock.Now())
+	if ttl <= 0 {
+		c.lru.Remove(q)
+		return value, 0, false, false
+	}
+	return a.value, ttl, a.negative, true
+}
+
+// Remove removes the answer to q, returning if it was cached.
+func (c *Cache[A]) Remove(q Question) bool {
+	return c.lru.Remove(normalize(q))
+}
+
+// Purge removes all answers.
+func (c *Cache[A]) Purge() {
+	c.lru.Purge()
+}
+
+// Len returns the number of cached answers, including expired ones not
+// looked up since.
+func (c *Cache[A]) Len() int {
+	return c.lru.Len()
+}
+
+// normalize returns q with its name in lower case and without a trailing
+// dot.
+func normalize(q Question) Question {
+	q.Name = strings.ToLower(strings.TrimSuffix(q.Name, "."))
+	return q
+}
@@ -0,0 +1,72 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package dnscache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+const typeA = 1
+
+func TestCache(t *testing.T) {
+	clock := lru.NewFakeClock(time.Now())
+	c, err := New[[]string](8, time.Hour, 5*time.Minute, WithClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	q := Question{"Example.COM.", typeA}
+	c.Add(q, []string{"192.0.2.1"}, 10*time.Minute)
+	clock.Advance(4 * time.Minute)
+
+	v, ttl, negative, ok := c.Get(Question{"example.com", typeA})
+	if !ok || negative || !reflect.DeepEqual(v, []string{"192.0.2.1"}) {
+		t.Fatalf("bad: %v, %v, %v", v, negative, ok)
+	}
+	if ttl != 6*time.Minute {
+		t.Fatalf("remaining TTL should count down: %v", ttl)
+	}
+
+	clock.Advance(6 * time.Minute)
+	if _, _, _, ok := c.Get(q); ok {
+		t.Fatalf("answer should have expired")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("expired answers should be removed on lookup")
+	}
+
+	// TTLs are capped at the ceilings
+	c.Add(q, []string{"192.0.2.2"}, 24*time.Hour)
+	if _, ttl, _, _ := c.Get(q); ttl != time.Hour {
+		t.Fatalf("bad: %v", ttl)
+	}
+	nx := Question{"nx.example.com", typeA}
+	c.AddNegative(nx, time.Hour)
+	if v, ttl, negative, ok := c.Get(nx); !ok || !negative || v != nil || ttl != 5*time.Minute {
+		t.Fatalf("bad: %v, %v, %v, %v", v, ttl, negative, ok)
+	}
+
+	// zero TTLs are not cached, and replace earlier answers
+	c.Add(q, []string{"192.0.2.3"}, 0)
+	if _, _, _, ok := c.Get(q); ok {
+		t.Fatalf("zero TTL answers should not be cached")
+	}
+
+	if !c.Remove(nx) || c.Len() != 0 {
+		t.Fatalf("bad: %d", c.Len())
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New[int](0, time.Hour, time.Hour); err == nil {
+		t.Fatalf("should reject a zero size")
+	}
+	if _, err := New[int](8, -1, time.Hour); err == nil {
+		t.Fatalf("should reject a negative TTL ceiling")
+	}
+}
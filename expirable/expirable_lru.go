@@ -4,26 +4,112 @@
 package expirable
 
 import (
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/golang-lru/v2/internal"
+	"github.com/hashicorp/golang-lru/v2/simplelru"
 )
 
 // EvictCallback is used to get a callback when a cache entry is evicted
 type EvictCallback[K comparable, V any] func(key K, value V)
 
+// EvictReason tells why an entry left the cache, see WithEvictReasonCallback.
+type EvictReason int
+
+const (
+	// Evicted entries were removed to make room, by an addition or Shrink.
+	Evicted EvictReason = iota
+	// Expired entries were removed by the cleanup after their TTL elapsed.
+	Expired
+	// Removed entries were removed by Remove, RemoveMany or RemoveOldest.
+	Removed
+	// Replaced values were replaced by an update of their key.
+	Replaced
+	// Purged entries were removed by Purge.
+	Purged
+	// Resized entries were removed by Resize to fit the new size.
+	Resized
+)
+
+// String returns the lowercase name of the reason, suitable as a metric
+// label.
+func (r EvictReason) String() string {
+	switch r {
+	case Evicted:
+		return "evicted"
+	case Expired:
+		return "expired"
+	case Removed:
+		return "removed"
+	case Replaced:
+		return "replaced"
+	case Purged:
+		return "purged"
+	case Resized:
+		return "resized"
+	}
+	return "unknown"
+}
+
 // LRU implements a thread-safe LRU with expirable entries.
+//
+// The evict callbacks run after the lock is released, one at a time, in
+// the order the entries left the cache, whether they were removed by the
+// cleanup goroutine, by an operation such as Remove or Purge, or by another
+// callback calling back into the cache. Each cleanup pass expires entries
+// from the first to the last to expire. The callbacks for an operation
+// usually run before it returns, but may instead be run by the goroutine
+// already running those of an earlier one.
 type LRU[K comparable, V any] struct {
 	size      int
+	peakSize  int // largest size since items was last allocated, 0 if unlimited
 	evictList *internal.LruList[K, V]
 	items     map[K]*internal.Entry[K, V]
 	onEvict   EvictCallback[K, V]
+	stats     *simplelru.StatsCounter
+
+	// evicted entries are buffered while the lock is held and passed to
+	// onEvict by unlock, so that the callback may call back into the cache
+	evictedKeys    []K
+	evictedVals    []V
+	evictedReasons []EvictReason
+
+	// onEvictReason is passed evicted entries with the reason, optional
+	onEvictReason func(key K, value V, reason EvictReason)
+
+	// onExpireBatch receives the entries expired by each cleanup pass, optional
+	onExpireBatch func(entries []simplelru.Entry[K, V])
+
+	// maxExpirations bounds the entries expired per lock acquisition, optional
+	maxExpirations int
+
+	// autoClose closes values leaving the cache, optional
+	autoClose *internal.AutoCloser[K]
+
+	// execute schedules the callbacks for evicted entries, optional
+	execute func(fn func())
+
+	// pending holds the deliveries of evicted entries in the order of the
+	// evictions, run one at a time by the goroutine that set delivering
+	pending    []func()
+	delivering bool
 
 	// expirable options
-	mu   sync.Mutex
-	ttl  time.Duration
-	done chan struct{}
+	mu    sync.Mutex
+	ttl   time.Duration
+	done  chan struct{}
+	clock simplelru.Clock
+	base  time.Time // entry expiry times are in nanoseconds since base
+
+	// retick changes the interval of the cleanup goroutine, if it is running
+	retick chan time.Duration
+
+	// coarseNow caches now() for WithCoarseClock, refreshed every coarse
+	coarse    time.Duration
+	coarseNow *int64
 
 	// buckets for expiration
 	buckets []bucket[K, V]
@@ -34,7 +120,8 @@ type LRU[K comparable, V any] struct {
 // bucket is a container for holding entries to be expired
 type bucket[K comparable, V any] struct {
 	entries     map[K]*internal.Entry[K, V]
-	newestEntry time.Time
+	newestEntry int64 // see internal.Entry.ExpiresAt
+	peak        int   // largest number of entries since entries was allocated
 }
 
 // noEvictionTTL - very long ttl to prevent eviction
@@ -44,6 +131,18 @@ const noEvictionTTL = time.Hour * 24 * 365 * 10
 // casting it as uint8 explicitly requires type conversions in multiple places
 const numBuckets = 100
 
+// shrinkFactor is how many times smaller than its peak the cache has to get
+// before Resize reallocates the internal maps to return memory.
+const shrinkFactor = 2
+
+// rangeChunkSize is how many entries Range copies under a single lock
+// acquisition.
+const rangeChunkSize = 4096
+
+// minBucketReclaimSize is how many entries a bucket must have held at its peak
+// for its map to be reallocated once it becomes empty.
+const minBucketReclaimSize = 64
+
 // NewLRU returns a new thread-safe cache with expirable entries.
 //
 // Size parameter set to 0 makes cache of unlimited size, e.g. turns LRU mechanism off.
@@ -51,7 +150,15 @@ const numBuckets = 100
 // Providing 0 TTL turns expiring off.
 //
 // Delete expired entries every 1/100th of ttl value. Goroutine which deletes expired entries runs indefinitely.
+//
+// onEvict is called after the lock is released, so it may call back into the cache.
 func NewLRU[K comparable, V any](size int, onEvict EvictCallback[K, V], ttl time.Duration) *LRU[K, V] {
+	return NewLRUWithOpts(size, onEvict, ttl)
+}
+
+// NewLRUWithOpts returns a new thread-safe cache with expirable entries, configured by opts.
+// See NewLRU for the meaning of the other parameters.
+func NewLRUWithOpts[K comparable, V any](size int, onEvict EvictCallback[K, V], ttl time.Duration, opts ...Option[K, V]) *LRU[K, V] {
 	if size < 0 {
 		size = 0
 	}
@@ -62,10 +169,13 @@ func NewLRU[K comparable, V any](size int, onEvict EvictCallback[K, V], ttl time
 	res := LRU[K, V]{
 		ttl:       ttl,
 		size:      size,
+		peakSize:  size,
 		evictList: internal.NewList[K, V](),
 		items:     make(map[K]*internal.Entry[K, V]),
 		onEvict:   onEvict,
+		stats:     new(simplelru.StatsCounter),
 		done:      make(chan struct{}),
+		clock:     simplelru.RealClock{},
 	}
 
 	// initialize the buckets
@@ -74,23 +184,31 @@ func NewLRU[K comparable, V any](size int, onEvict EvictCallback[K, V], ttl time
 		res.buckets[i] = bucket[K, V]{entries: make(map[K]*internal.Entry[K, V])}
 	}
 
-	// enable deleteExpired() running in separate goroutine for cache with non-zero TTL
-	//
-	// Important: done channel is never closed, so deleteExpired() goroutine will never exit,
-	// it's decided to add functionality to close it in the version later than v2.
-	if res.ttl != noEvictionTTL {
-		go func(done <-chan struct{}) {
-			ticker := time.NewTicker(res.ttl / numBuckets)
+	for _, opt := range opts {
+		opt(&res)
+	}
+	res.base = res.clock.Now()
+
+	if res.coarse > 0 {
+		res.coarseNow = new(int64)
+		go func(done <-chan struct{}, coarseNow *int64) {
+			ticker := time.NewTicker(res.coarse)
 			defer ticker.Stop()
 			for {
 				select {
 				case <-done:
 					return
 				case <-ticker.C:
-					res.deleteExpired()
+					atomic.StoreInt64(coarseNow, int64(res.clock.Now().Sub(res.base)))
 				}
 			}
-		}(res.done)
+		}(res.done, res.coarseNow)
+	}
+
+	// enable deleteExpired() running in separate goroutine for cache with non-zero TTL,
+	// until Close is called
+	if res.ttl != noEvictionTTL {
+		res.startCleanup()
 	}
 	return &res
 }
@@ -99,17 +217,14 @@ func NewLRU[K comparable, V any](size int, onEvict EvictCallback[K, V], ttl time
 // onEvict is called for each evicted key.
 func (c *LRU[K, V]) Purge() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
 	for k, v := range c.items {
-		if c.onEvict != nil {
-			c.onEvict(k, v.Value)
-		}
+		c.onEvicted(k, v.Value, Purged)
 		delete(c.items, k)
 	}
-	for _, b := range c.buckets {
-		for _, ent := range b.entries {
-			delete(b.entries, ent.Key)
-		}
+	for i := range c.buckets {
+		c.buckets[i].entries = make(map[K]*internal.Entry[K, V])
+		c.buckets[i].peak = 0
 	}
 	c.evictList.Init()
 }
@@ -119,28 +234,48 @@ func (c *LRU[K, V]) Purge() {
 // or the size was not exceeded.
 func (c *LRU[K, V]) Add(key K, value V) (evicted bool) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	now := time.Now()
+	defer c.unlock()
+	c.stats.Add(1)
+	return c.add(key, value, c.now()+int64(c.ttl))
+}
+
+// AddMany adds the entries in order, as if by Add, under a single lock
+// acquisition. Returns the number of evictions.
+func (c *LRU[K, V]) AddMany(entries []simplelru.Entry[K, V]) (evicted int) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.stats.Add(len(entries))
+	expiresAt := c.now() + int64(c.ttl)
+	for _, e := range entries {
+		if c.add(e.Key, e.Value, expiresAt) {
+			evicted++
+		}
+	}
+	return evicted
+}
 
+// add adds a value expiring at expiresAt. Has to be called with lock!
+func (c *LRU[K, V]) add(key K, value V, expiresAt int64) (evicted bool) {
 	// Check for existing item
 	if ent, ok := c.items[key]; ok {
 		c.evictList.MoveToFront(ent)
 		c.removeFromBucket(ent) // remove the entry from its current bucket as expiresAt is renewed
+		c.replaced(key, ent.Value, value)
 		ent.Value = value
-		ent.ExpiresAt = now.Add(c.ttl)
+		ent.ExpiresAt = expiresAt
 		c.addToBucket(ent)
 		return false
 	}
 
 	// Add new item
-	ent := c.evictList.PushFrontExpirable(key, value, now.Add(c.ttl))
+	ent := c.evictList.PushFrontExpirable(key, value, expiresAt)
 	c.items[key] = ent
 	c.addToBucket(ent) // adds the entry to the appropriate bucket and sets entry.expireBucket
 
 	evict := c.size > 0 && c.evictList.Length() > c.size
 	// Verify size not exceeded
 	if evict {
-		c.removeOldest()
+		c.removeOldest(Evicted)
 	}
 	return evict
 }
@@ -149,10 +284,30 @@ func (c *LRU[K, V]) Add(key K, value V) (evicted bool) {
 func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.get(key)
+}
+
+// GetMany looks up the values of keys as if by Get in order, under a single
+// lock acquisition, and returns those found.
+func (c *LRU[K, V]) GetMany(keys []K) map[K]V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	found := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, ok := c.get(key); ok {
+			found[key] = value
+		}
+	}
+	return found
+}
+
+// get looks up a key's value. Has to be called with lock!
+func (c *LRU[K, V]) get(key K) (value V, ok bool) {
+	defer func() { c.stats.Lookup(ok) }()
 	var ent *internal.Entry[K, V]
 	if ent, ok = c.items[key]; ok {
 		// Expired item check
-		if time.Now().After(ent.ExpiresAt) {
+		if c.now() > ent.ExpiresAt {
 			return value, false
 		}
 		c.evictList.MoveToFront(ent)
@@ -178,7 +333,7 @@ func (c *LRU[K, V]) Peek(key K) (value V, ok bool) {
 	var ent *internal.Entry[K, V]
 	if ent, ok = c.items[key]; ok {
 		// Expired item check
-		if time.Now().After(ent.ExpiresAt) {
+		if c.now() > ent.ExpiresAt {
 			return value, false
 		}
 		return ent.Value, true
@@ -186,24 +341,98 @@ func (c *LRU[K, V]) Peek(key K) (value V, ok bool) {
 	return
 }
 
+// Touch restarts the TTL of a cached entry that has not expired, as Add
+// would, without fetching or replacing its value or updating its
+// "recently used"-ness. Returns false if the key is not cached or expired.
+func (c *LRU[K, V]) Touch(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.updateTTL(key, c.ttl)
+}
+
+// UpdateTTL sets the remaining lifetime of a cached entry that has not
+// expired to ttl, without updating its "recently used"-ness. A ttl beyond
+// the TTL of the cache is shortened to it, and a ttl of 0 or less expires
+// the entry right away. Returns false if the key is not cached or expired.
+func (c *LRU[K, V]) UpdateTTL(key K, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.updateTTL(key, ttl)
+}
+
+// updateTTL implements UpdateTTL. Has to be called with lock!
+func (c *LRU[K, V]) updateTTL(key K, ttl time.Duration) bool {
+	ent, ok := c.items[key]
+	now := c.now()
+	if !ok || now > ent.ExpiresAt {
+		return false
+	}
+	left := ttl
+	if left > c.ttl {
+		left = c.ttl
+	}
+	if left < 0 {
+		left = 0
+	}
+	c.removeFromBucket(ent)
+	ent.ExpiresAt = now + int64(left)
+	if ttl <= 0 {
+		ent.ExpiresAt = now - 1
+	}
+	c.addToBucketID(ent, c.bucketFor(left, cleanupInterval(c.ttl)))
+	return true
+}
+
 // Remove removes the provided key from the cache, returning if the
 // key was contained.
 func (c *LRU[K, V]) Remove(key K) bool {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
 	if ent, ok := c.items[key]; ok {
-		c.removeElement(ent)
+		c.removeElement(ent, Removed)
 		return true
 	}
 	return false
 }
 
+// RemoveMany removes the provided keys from the cache under a single lock
+// acquisition, returning how many were present.
+func (c *LRU[K, V]) RemoveMany(keys []K) (removed int) {
+	c.mu.Lock()
+	defer c.unlock()
+	for _, key := range keys {
+		if ent, ok := c.items[key]; ok {
+			c.removeElement(ent, Removed)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Pop removes the provided key from the cache and returns its value,
+// without invoking the evict callback. Expired entries are not returned
+// and are left for the cleanup to evict.
+func (c *LRU[K, V]) Pop(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ent *internal.Entry[K, V]
+	if ent, ok = c.items[key]; ok {
+		// Expired item check
+		if c.now() > ent.ExpiresAt {
+			return value, false
+		}
+		c.unlinkElement(ent)
+		return ent.Value, true
+	}
+	return
+}
+
 // RemoveOldest removes the oldest item from the cache.
 func (c *LRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
 	if ent := c.evictList.Back(); ent != nil {
-		c.removeElement(ent)
+		c.removeElement(ent, Removed)
 		return ent.Key, ent.Value, true
 	}
 	return
@@ -219,15 +448,37 @@ func (c *LRU[K, V]) GetOldest() (key K, value V, ok bool) {
 	return
 }
 
+// PeekOldestN returns up to n of the oldest entries, from oldest to newest,
+// without updating their "recently used"-ness. Expired entries are filtered out.
+func (c *LRU[K, V]) PeekOldestN(n int) []simplelru.Entry[K, V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n > c.evictList.Length() {
+		n = c.evictList.Length()
+	}
+	if n <= 0 {
+		return nil
+	}
+	entries := make([]simplelru.Entry[K, V], 0, n)
+	now := c.now()
+	for ent := c.evictList.Back(); ent != nil && len(entries) < n; ent = ent.PrevEntry() {
+		if now > ent.ExpiresAt {
+			continue
+		}
+		entries = append(entries, simplelru.Entry[K, V]{Key: ent.Key, Value: ent.Value})
+	}
+	return entries
+}
+
 // Keys returns a slice of the keys in the cache, from oldest to newest.
 // Expired entries are filtered out.
 func (c *LRU[K, V]) Keys() []K {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	keys := make([]K, 0, len(c.items))
-	now := time.Now()
+	now := c.now()
 	for ent := c.evictList.Back(); ent != nil; ent = ent.PrevEntry() {
-		if now.After(ent.ExpiresAt) {
+		if now > ent.ExpiresAt {
 			continue
 		}
 		keys = append(keys, ent.Key)
@@ -235,15 +486,64 @@ func (c *LRU[K, V]) Keys() []K {
 	return keys
 }
 
+// Range calls fn for each entry from oldest to newest, until fn returns
+// false. Expired entries are skipped. The entries are copied rangeChunkSize
+// at a time and fn is called without the lock, so it may use the cache. Like
+// Keys, Range only sees a consistent snapshot if there are no concurrent
+// writes: entries removed before their chunk was copied are skipped, and
+// those moved or added meanwhile may be visited again or not at all. At most
+// as many entries are visited as the cache held when Range started.
+func (c *LRU[K, V]) Range(fn func(key K, value V) bool) {
+	c.mu.Lock()
+	left := len(c.items)
+	next := c.evictList.Back()
+	c.mu.Unlock()
+	size := left
+	if size > rangeChunkSize {
+		size = rangeChunkSize
+	}
+	chunk := make([]simplelru.Entry[K, V], 0, size)
+	var last *internal.Entry[K, V]
+	for next != nil && left > 0 {
+		c.mu.Lock()
+		if c.items[next.Key] != next {
+			// the entry to resume from was removed
+			if last != nil && c.items[last.Key] == last {
+				next = last.PrevEntry()
+			} else {
+				next = c.evictList.Back()
+			}
+		}
+		chunk = chunk[:0]
+		now := c.now()
+		for n := 0; next != nil && n < cap(chunk) && left > 0; next = next.PrevEntry() {
+			last = next
+			left--
+			n++
+			if now > next.ExpiresAt {
+				continue
+			}
+			chunk = append(chunk, simplelru.Entry[K, V]{Key: next.Key, Value: next.Value})
+		}
+		c.mu.Unlock()
+
+		for _, e := range chunk {
+			if !fn(e.Key, e.Value) {
+				return
+			}
+		}
+	}
+}
+
 // Values returns a slice of the values in the cache, from oldest to newest.
 // Expired entries are filtered out.
 func (c *LRU[K, V]) Values() []V {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	values := make([]V, 0, len(c.items))
-	now := time.Now()
+	now := c.now()
 	for ent := c.evictList.Back(); ent != nil; ent = ent.PrevEntry() {
-		if now.After(ent.ExpiresAt) {
+		if now > ent.ExpiresAt {
 			continue
 		}
 		values = append(values, ent.Value)
@@ -251,6 +551,210 @@ func (c *LRU[K, V]) Values() []V {
 	return values
 }
 
+// ExpiryForecast splits the TTL from now into the given number of equal
+// intervals and returns how many entries expire in each of them. Expired
+// entries awaiting cleanup are counted in the first interval.
+func (c *LRU[K, V]) ExpiryForecast(buckets int) []int {
+	if buckets <= 0 {
+		return nil
+	}
+	forecast := make([]int, buckets)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	interval := c.ttl / time.Duration(buckets)
+	for ent := c.evictList.Back(); ent != nil; ent = ent.PrevEntry() {
+		i := 0
+		if interval > 0 {
+			if left := time.Duration(ent.ExpiresAt - now); left > 0 {
+				i = int(left / interval)
+			}
+		}
+		if i >= buckets {
+			i = buckets - 1
+		}
+		forecast[i]++
+	}
+	return forecast
+}
+
+// SnapshotEntry is an entry with its absolute expiry time, as returned by
+// Snapshot. It can be encoded with encoding/gob or encoding/json if its key
+// and value can.
+type SnapshotEntry[K comparable, V any] struct {
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+}
+
+// Snapshot returns the unexpired entries from oldest to newest with their
+// expiry times, to be passed to Restore, e.g. after a restart.
+func (c *LRU[K, V]) Snapshot() []SnapshotEntry[K, V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := make([]SnapshotEntry[K, V], 0, len(c.items))
+	now := c.now()
+	for ent := c.evictList.Back(); ent != nil; ent = ent.PrevEntry() {
+		if now > ent.ExpiresAt {
+			continue
+		}
+		entries = append(entries, SnapshotEntry[K, V]{Key: ent.Key, Value: ent.Value, ExpiresAt: c.base.Add(time.Duration(ent.ExpiresAt))})
+	}
+	return entries
+}
+
+// Restore adds entries returned by Snapshot, from oldest to newest, keeping
+// their expiry times instead of starting a new TTL. Expired entries are
+// skipped, and expiry times beyond the TTL of this cache are shortened to
+// it. Returns the number of entries added.
+func (c *LRU[K, V]) Restore(entries []SnapshotEntry[K, V]) (restored int) {
+	c.mu.Lock()
+	defer c.unlock()
+	now := c.clock.Now()
+	tick := cleanupInterval(c.ttl)
+	for _, e := range entries {
+		left := e.ExpiresAt.Sub(now)
+		if left <= 0 {
+			continue
+		}
+		if left > c.ttl {
+			left = c.ttl
+		}
+		expiresAt := int64(now.Add(left).Sub(c.base))
+		if ent, ok := c.items[e.Key]; ok {
+			c.evictList.MoveToFront(ent)
+			c.removeFromBucket(ent)
+			c.replaced(e.Key, ent.Value, e.Value)
+			ent.Value = e.Value
+			ent.ExpiresAt = expiresAt
+			c.addToBucketID(ent, c.bucketFor(left, tick))
+		} else {
+			ent := c.evictList.PushFrontExpirable(e.Key, e.Value, expiresAt)
+			c.items[e.Key] = ent
+			c.addToBucketID(ent, c.bucketFor(left, tick))
+			if c.size > 0 && c.evictList.Length() > c.size {
+				c.removeOldest(Evicted)
+			}
+		}
+		restored++
+	}
+	return restored
+}
+
+// startCleanup starts the goroutine running deleteExpired every 1/100th of
+// the TTL. Has to be called with lock, or before the cache is shared!
+func (c *LRU[K, V]) startCleanup() {
+	c.retick = make(chan time.Duration, 1)
+	go func(done <-chan struct{}, retick <-chan time.Duration, interval time.Duration) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case interval := <-retick:
+				ticker.Reset(interval)
+			case <-ticker.C:
+				c.deleteExpired()
+			}
+		}
+	}(c.done, c.retick, cleanupInterval(c.ttl))
+}
+
+// SetTTL changes the TTL of the cache, e.g. from an admin endpoint, without
+// dropping its contents. Entries keep the time they were last added at, so
+// they expire ttl after it: shortening the TTL may expire some of them
+// right away, in the next cleanup passes. Providing 0 TTL turns expiring
+// off, also for the entries already cached.
+func (c *LRU[K, V]) SetTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = noEvictionTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	shift := int64(ttl - c.ttl)
+	c.ttl = ttl
+
+	// rebucket the entries for their new expiry times
+	for i := range c.buckets {
+		c.buckets[i] = bucket[K, V]{entries: make(map[K]*internal.Entry[K, V])}
+	}
+	now := c.now()
+	tick := cleanupInterval(ttl)
+	for ent := c.evictList.Back(); ent != nil; ent = ent.PrevEntry() {
+		ent.ExpiresAt += shift
+		left := time.Duration(ent.ExpiresAt - now)
+		if left < 0 {
+			left = 0
+		}
+		c.addToBucketID(ent, c.bucketFor(left, tick))
+	}
+
+	if c.retick == nil {
+		if ttl != noEvictionTTL {
+			c.startCleanup()
+		}
+		return
+	}
+	select {
+	case <-c.retick:
+	default:
+	}
+	c.retick <- tick
+}
+
+// bucketFor returns the first bucket the cleanup reaches after an entry
+// expiring in left has expired. Has to be called with lock!
+func (c *LRU[K, V]) bucketFor(left, tick time.Duration) uint8 {
+	k := int(left / tick)
+	if k >= numBuckets {
+		k = numBuckets - 1
+	}
+	return uint8((int(c.nextCleanupBucket) + k) % numBuckets)
+}
+
+// cleanupInterval returns the interval between the cleanups of a cache with
+// the given TTL, 1/100th of it, but at least a nanosecond so that tiny TTLs
+// don't make the cleanup spin or bucketFor divide by zero.
+func cleanupInterval(ttl time.Duration) time.Duration {
+	if tick := ttl / numBuckets; tick > 0 {
+		return tick
+	}
+	return 1
+}
+
+// Shrink evicts the given fraction of the entries, between 0 and 1, oldest
+// first, e.g. to release memory when the process nears its memory limit.
+// Returns the number of evictions.
+func (c *LRU[K, V]) Shrink(fraction float64) (evicted int) {
+	c.mu.Lock()
+	defer c.unlock()
+	n := c.evictList.Length()
+	if fraction <= 0 {
+		return 0
+	}
+	if fraction < 1 {
+		n = int(float64(n) * fraction)
+	}
+	for ; evicted < n; evicted++ {
+		c.removeOldest(Evicted)
+	}
+	return evicted
+}
+
+// Stats returns the numbers of lookups with Get, additions with Add,
+// capacity evictions and expirations removed by the cleanup goroutine since
+// the cache was created or ResetStats was called. Lookups finding an
+// expired entry count as misses.
+func (c *LRU[K, V]) Stats() simplelru.Stats {
+	return c.stats.Stats()
+}
+
+// ResetStats sets the numbers returned by Stats back to zero.
+func (c *LRU[K, V]) ResetStats() {
+	c.stats.Reset()
+}
+
 // Len returns the number of items in the cache.
 func (c *LRU[K, V]) Len() int {
 	c.mu.Lock()
@@ -261,9 +765,10 @@ func (c *LRU[K, V]) Len() int {
 // Resize changes the cache size. Size of 0 means unlimited.
 func (c *LRU[K, V]) Resize(size int) (evicted int) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
 	if size <= 0 {
 		c.size = 0
+		c.peakSize = 0
 		return 0
 	}
 	diff := c.evictList.Length() - size
@@ -271,76 +776,318 @@ func (c *LRU[K, V]) Resize(size int) (evicted int) {
 		diff = 0
 	}
 	for i := 0; i < diff; i++ {
-		c.removeOldest()
+		c.removeOldest(Resized)
 	}
 	c.size = size
+	if c.peakSize == 0 || size < c.peakSize/shrinkFactor {
+		c.shrinkItems()
+	} else if size > c.peakSize {
+		c.peakSize = size
+	}
 	return diff
 }
 
-// Close destroys cleanup goroutine. To clean up the cache, run Purge() before Close().
-// func (c *LRU[K, V]) Close() {
-//	c.mu.Lock()
-//	defer c.mu.Unlock()
-//	select {
-//	case <-c.done:
-//		return
-//	default:
-//	}
-//	close(c.done)
-// }
+// Close destroys the cleanup goroutine, and the one refreshing the time with
+// WithCoarseClock, after which the clock is read on every operation again.
+// Expired entries are still not returned, but are no longer removed. To clean
+// up the cache, run Purge() before Close().
+func (c *LRU[K, V]) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	close(c.done)
+	c.coarseNow = nil
+}
+
+// shrinkItems rebuilds the items and bucket maps sized to the current contents,
+// since Go maps never release their buckets once grown. Has to be called with lock!
+func (c *LRU[K, V]) shrinkItems() {
+	items := make(map[K]*internal.Entry[K, V], len(c.items))
+	for k, ent := range c.items {
+		items[k] = ent
+	}
+	c.items = items
+	for i := range c.buckets {
+		entries := make(map[K]*internal.Entry[K, V], len(c.buckets[i].entries))
+		for k, ent := range c.buckets[i].entries {
+			entries[k] = ent
+		}
+		c.buckets[i].entries = entries
+		c.buckets[i].peak = len(entries)
+	}
+	c.peakSize = c.size
+}
+
+// now returns the current time in nanoseconds since base.
+func (c *LRU[K, V]) now() int64 {
+	if c.coarseNow != nil {
+		return atomic.LoadInt64(c.coarseNow)
+	}
+	return int64(c.clock.Now().Sub(c.base))
+}
+
+// tickCoarseClock refreshes the time returned by now with WithCoarseClock.
+func (c *LRU[K, V]) tickCoarseClock() {
+	atomic.StoreInt64(c.coarseNow, int64(c.clock.Now().Sub(c.base)))
+}
 
 // removeOldest removes the oldest item from the cache. Has to be called with lock!
-func (c *LRU[K, V]) removeOldest() {
+func (c *LRU[K, V]) removeOldest(reason EvictReason) {
 	if ent := c.evictList.Back(); ent != nil {
-		c.removeElement(ent)
+		c.removeElement(ent, reason)
+		c.stats.Evict(1)
 	}
 }
 
 // removeElement is used to remove a given list element from the cache. Has to be called with lock!
-func (c *LRU[K, V]) removeElement(e *internal.Entry[K, V]) {
+func (c *LRU[K, V]) removeElement(e *internal.Entry[K, V], reason EvictReason) {
+	c.unlinkElement(e)
+	c.onEvicted(e.Key, e.Value, reason)
+}
+
+// onEvicted buffers an evicted entry for unlock. Has to be called with lock!
+func (c *LRU[K, V]) onEvicted(k K, v V, reason EvictReason) {
+	if c.onEvict != nil || c.autoClose != nil || c.onEvictReason != nil {
+		c.evictedKeys = append(c.evictedKeys, k)
+		c.evictedVals = append(c.evictedVals, v)
+		c.evictedReasons = append(c.evictedReasons, reason)
+	}
+}
+
+// unlock releases the lock and then passes the entries evicted while it was
+// held to onEvict and onEvictReason, closing them afterwards with
+// WithAutoClose. Replaced values are only passed to onEvictReason.
+func (c *LRU[K, V]) unlock() {
+	ks, vs, rs := c.evictedKeys, c.evictedVals, c.evictedReasons
+	c.evictedKeys, c.evictedVals, c.evictedReasons = nil, nil, nil
+	if len(ks) > 0 {
+		c.schedule(func() { c.deliver(ks, vs, rs) })
+	}
+	c.flush()
+}
+
+// schedule queues a delivery of evicted entries, through execute if set.
+// Has to be called with lock!
+func (c *LRU[K, V]) schedule(fn func()) {
+	if c.execute != nil {
+		c.pending = append(c.pending, func() { c.execute(fn) })
+		return
+	}
+	c.pending = append(c.pending, fn)
+}
+
+// flush releases the lock and runs the pending deliveries in order, unless
+// another goroutine is running them already, in which case that goroutine
+// runs the new ones too once it is done. Callbacks thus never run
+// concurrently, and see the evictions in the order they happened, while
+// those calling back into the cache don't deadlock.
+func (c *LRU[K, V]) flush() {
+	if c.delivering || len(c.pending) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	c.delivering = true
+	var pending []func()
+	done := false
+	defer func() {
+		if !done {
+			// a callback panicked, requeue the deliveries it cut short
+			// ahead of newer ones for the next unlock to run
+			c.mu.Lock()
+			c.pending = append(pending, c.pending...)
+			c.delivering = false
+			c.mu.Unlock()
+		}
+	}()
+	for len(c.pending) > 0 {
+		pending = c.pending
+		c.pending = nil
+		c.mu.Unlock()
+		for len(pending) > 0 {
+			fn := pending[0]
+			pending = pending[1:]
+			fn()
+		}
+		c.mu.Lock()
+	}
+	c.delivering = false
+	done = true
+	c.mu.Unlock()
+}
+
+// deliver passes evicted entries to the callbacks. If one of them panics,
+// the following entries are still delivered before the panic goes on.
+func (c *LRU[K, V]) deliver(ks []K, vs []V, rs []EvictReason) {
+	i := 0
+	defer func() {
+		if i < len(ks) {
+			c.deliver(ks[i+1:], vs[i+1:], rs[i+1:])
+		}
+	}()
+	for ; i < len(ks); i++ {
+		if c.onEvictReason != nil {
+			c.onEvictReason(ks[i], vs[i], rs[i])
+		}
+		if rs[i] == Replaced {
+			continue
+		}
+		if c.onEvict != nil {
+			c.onEvict(ks[i], vs[i])
+		}
+		if c.autoClose != nil {
+			c.autoClose.Close(ks[i], vs[i])
+		}
+	}
+}
+
+// replaced passes a value replaced by an update to onEvictReason, and
+// closes it with WithAutoClose. Has to be called with lock!
+func (c *LRU[K, V]) replaced(key K, old, new V) {
+	if c.onEvictReason != nil {
+		c.evictedKeys = append(c.evictedKeys, key)
+		c.evictedVals = append(c.evictedVals, old)
+		c.evictedReasons = append(c.evictedReasons, Replaced)
+	}
+	if c.autoClose != nil {
+		c.autoClose.CloseReplaced(key, old, new)
+	}
+}
+
+// unlinkElement removes a given list element from the cache without invoking onEvict. Has to be called with lock!
+func (c *LRU[K, V]) unlinkElement(e *internal.Entry[K, V]) {
 	c.evictList.Remove(e)
 	delete(c.items, e.Key)
 	c.removeFromBucket(e)
-	if c.onEvict != nil {
-		c.onEvict(e.Key, e.Value)
-	}
 }
 
 // deleteExpired deletes expired records from the oldest bucket, waiting for the newest entry
-// in it to expire first.
+// in it to expire first. With a batch callback, the expired entries are delivered to it
+// in a single call after the lock is released, or one call per batch with
+// WithMaxExpirationsPerLock.
 func (c *LRU[K, V]) deleteExpired() {
 	c.mu.Lock()
 	bucketIdx := c.nextCleanupBucket
-	timeToExpire := time.Until(c.buckets[bucketIdx].newestEntry)
-	// wait for newest entry to expire before cleanup without holding lock
+	timeToExpire := time.Duration(c.buckets[bucketIdx].newestEntry - c.now())
 	if timeToExpire > 0 {
+		// other clocks don't advance with real time, retry on the next tick
+		if _, ok := c.clock.(simplelru.RealClock); !ok {
+			c.mu.Unlock()
+			return
+		}
+		// wait for newest entry to expire before cleanup without holding lock
 		c.mu.Unlock()
 		time.Sleep(timeToExpire)
 		c.mu.Lock()
 	}
-	for _, ent := range c.buckets[bucketIdx].entries {
-		c.removeElement(ent)
+	// the bucket is sorted once, and then expired in batches of at most
+	// maxExpirations, skipping the entries removed or moved to another
+	// bucket while the lock was released in between
+	var sorted []*internal.Entry[K, V]
+	for {
+		entries := c.buckets[bucketIdx].entries
+		if len(sorted) == 0 {
+			sorted = expiryOrder(entries)
+		}
+		n := len(sorted)
+		if c.maxExpirations > 0 && n > c.maxExpirations {
+			n = c.maxExpirations
+		}
+		batch := sorted[:n]
+		sorted = sorted[n:]
+		var expired []simplelru.Entry[K, V]
+		if c.onExpireBatch != nil {
+			expired = make([]simplelru.Entry[K, V], 0, n)
+		}
+		removed := 0
+		for _, ent := range batch {
+			if entries[ent.Key] != ent {
+				continue
+			}
+			removed++
+			if c.onExpireBatch != nil {
+				expired = append(expired, simplelru.Entry[K, V]{Key: ent.Key, Value: ent.Value})
+				c.unlinkElement(ent)
+				continue
+			}
+			c.removeElement(ent, Expired)
+		}
+		c.stats.Expire(removed)
+		done := len(entries) == 0
+		if done {
+			c.nextCleanupBucket = (c.nextCleanupBucket + 1) % numBuckets
+		}
+		c.expireBatch(expired)
+		c.unlock()
+		if done {
+			return
+		}
+		// let the operations waiting for the lock in before the next batch
+		c.mu.Lock()
 	}
-	c.nextCleanupBucket = (c.nextCleanupBucket + 1) % numBuckets
-	c.mu.Unlock()
+}
+
+// expireBatch queues the delivery of entries expired with a batch callback
+// to it, closing them afterwards with WithAutoClose. Has to be called with
+// lock!
+func (c *LRU[K, V]) expireBatch(expired []simplelru.Entry[K, V]) {
+	if len(expired) == 0 {
+		return
+	}
+	c.schedule(func() {
+		c.onExpireBatch(expired)
+		if c.autoClose != nil {
+			for _, e := range expired {
+				c.autoClose.Close(e.Key, e.Value)
+			}
+		}
+	})
+}
+
+// expiryOrder returns the entries of a bucket from the first to the last to
+// expire.
+func expiryOrder[K comparable, V any](entries map[K]*internal.Entry[K, V]) []*internal.Entry[K, V] {
+	sorted := make([]*internal.Entry[K, V], 0, len(entries))
+	for _, ent := range entries {
+		sorted = append(sorted, ent)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ExpiresAt < sorted[j].ExpiresAt })
+	return sorted
 }
 
 // addToBucket adds entry to expire bucket so that it will be cleaned up when the time comes. Has to be called with lock!
 func (c *LRU[K, V]) addToBucket(e *internal.Entry[K, V]) {
-	bucketID := (numBuckets + c.nextCleanupBucket - 1) % numBuckets
+	c.addToBucketID(e, (numBuckets+c.nextCleanupBucket-1)%numBuckets)
+}
+
+// addToBucketID adds the entry to the given bucket. Has to be called with lock!
+func (c *LRU[K, V]) addToBucketID(e *internal.Entry[K, V], bucketID uint8) {
 	e.ExpireBucket = bucketID
 	c.buckets[bucketID].entries[e.Key] = e
-	if c.buckets[bucketID].newestEntry.Before(e.ExpiresAt) {
+	if n := len(c.buckets[bucketID].entries); n > c.buckets[bucketID].peak {
+		c.buckets[bucketID].peak = n
+	}
+	if c.buckets[bucketID].newestEntry < e.ExpiresAt {
 		c.buckets[bucketID].newestEntry = e.ExpiresAt
 	}
 }
 
-// removeFromBucket removes the entry from its corresponding bucket. Has to be called with lock!
+// removeFromBucket removes the entry from its corresponding bucket, reallocating
+// the bucket's map once a large bucket becomes empty, as after a burst of expiry,
+// so that its memory is returned. Has to be called with lock!
 func (c *LRU[K, V]) removeFromBucket(e *internal.Entry[K, V]) {
-	delete(c.buckets[e.ExpireBucket].entries, e.Key)
+	b := &c.buckets[e.ExpireBucket]
+	delete(b.entries, e.Key)
+	if len(b.entries) == 0 && b.peak >= minBucketReclaimSize {
+		b.entries = make(map[K]*internal.Entry[K, V])
+		b.peak = 0
+	}
 }
 
 // Cap returns the capacity of the cache
 func (c *LRU[K, V]) Cap() int {
 	return c.size
-}
\ No newline at end of file
+}
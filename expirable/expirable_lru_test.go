@@ -4,12 +4,17 @@
 package expirable
 
 import (
+	"bytes"
 	"crypto/rand"
+	"encoding/gob"
 	"fmt"
 	"math"
 	"math/big"
 	"reflect"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -122,6 +127,16 @@ func BenchmarkLRU_Freq_WithExpire(b *testing.B) {
 	b.Logf("hit: %d miss: %d ratio: %f", hit, miss, float64(hit)/float64(hit+miss))
 }
 
+// BenchmarkLRU_AddMemory reports the memory used per entry, as B/op
+func BenchmarkLRU_AddMemory(b *testing.B) {
+	l := NewLRU[int64, int64](0, nil, time.Hour)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Add(int64(i), int64(i))
+	}
+}
+
 func TestLRUInterface(_ *testing.T) {
 	var _ simplelru.LRUCache[int, int] = &LRU[int, int]{}
 }
@@ -173,6 +188,40 @@ func TestLRUNoPurge(t *testing.T) {
 	}
 }
 
+func TestLRUResizeShrink(t *testing.T) {
+	lc := NewLRU[int, int](1000, nil, time.Hour)
+	for i := 0; i < 1000; i++ {
+		lc.Add(i, i)
+	}
+
+	items := lc.items
+	if lc.Resize(2) != 998 {
+		t.Fatalf("evicted count differs from expected")
+	}
+	if reflect.ValueOf(lc.items).Pointer() == reflect.ValueOf(items).Pointer() {
+		t.Fatalf("items map should have been reallocated")
+	}
+	if !reflect.DeepEqual(lc.Keys(), []int{998, 999}) {
+		t.Fatalf("value differs from expected")
+	}
+	var bucketed int
+	for _, b := range lc.buckets {
+		bucketed += len(b.entries)
+	}
+	if bucketed != 2 {
+		t.Fatalf("expected 2 bucketed entries, got %d", bucketed)
+	}
+
+	// entries remain removable through their buckets
+	lc.Remove(998)
+	lc.Remove(999)
+	for _, b := range lc.buckets {
+		if len(b.entries) != 0 {
+			t.Fatalf("bucket should be empty")
+		}
+	}
+}
+
 func TestLRUEdgeCases(t *testing.T) {
 	lc := NewLRU[string, *string](2, nil, 0)
 
@@ -207,12 +256,85 @@ func TestLRU_Values(t *testing.T) {
 	}
 }
 
-// func TestExpirableMultipleClose(_ *testing.T) {
-//	lc := NewLRU[string, string](10, nil, 0)
-//	lc.Close()
-//	// should not panic
-//	lc.Close()
-// }
+func TestLRUBatchOperations(t *testing.T) {
+	var evicted []int
+	lc := NewLRU(3, func(k, v int) { evicted = append(evicted, k) }, time.Hour)
+
+	n := lc.AddMany([]simplelru.Entry[int, int]{{Key: 1, Value: 1}, {Key: 2, Value: 2}, {Key: 3, Value: 3}, {Key: 4, Value: 4}})
+	if n != 1 || !reflect.DeepEqual(evicted, []int{1}) {
+		t.Fatalf("bad evictions: %v, %v", n, evicted)
+	}
+	found := lc.GetMany([]int{1, 2, 3})
+	if !reflect.DeepEqual(found, map[int]int{2: 2, 3: 3}) {
+		t.Fatalf("bad found: %v", found)
+	}
+	if n := lc.RemoveMany([]int{4, 1, 2}); n != 2 || !reflect.DeepEqual(evicted, []int{1, 4, 2}) {
+		t.Fatalf("bad removals: %v, %v", n, evicted)
+	}
+	if !reflect.DeepEqual(lc.Keys(), []int{3}) {
+		t.Fatalf("bad keys: %v", lc.Keys())
+	}
+	if s := lc.Stats(); s.Adds != 4 || s.Hits != 2 || s.Misses != 1 {
+		t.Fatalf("bad stats: %+v", s)
+	}
+}
+
+func TestLRU_Range(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts[string, string](3, nil, time.Minute, WithClock[string, string](clock))
+
+	lc.Add("key1", "val1")
+	clock.Advance(30 * time.Second)
+	lc.Add("key2", "val2")
+	lc.Add("key3", "val3")
+	clock.Advance(31 * time.Second)
+
+	var values []string
+	lc.Range(func(k, v string) bool {
+		values = append(values, v)
+		return k != "key2"
+	})
+	if !reflect.DeepEqual(values, []string{"val2"}) {
+		t.Fatalf("bad values: %v", values)
+	}
+}
+
+// test that Range callbacks can use the cache
+func TestLRU_RangeReentrant(t *testing.T) {
+	size := 2*rangeChunkSize + 3
+	lc := NewLRU[int, int](size, nil, time.Hour)
+	for i := 0; i < size; i++ {
+		lc.Add(i, i)
+	}
+
+	next, visited := 0, 0
+	lc.Range(func(k, v int) bool {
+		if k != next || v != k {
+			t.Fatalf("bad entry: %d, %d, want %d", k, v, next)
+		}
+		next++
+		visited++
+		if _, ok := lc.Get(k); !ok {
+			t.Fatalf("%d should be contained", k)
+		}
+		if k == rangeChunkSize-1 {
+			lc.Remove(k + 1) // where Range resumes from
+			next++
+		}
+		lc.Remove(k)
+		return true
+	})
+	if visited != size-1 || lc.Len() != 0 {
+		t.Fatalf("bad visited count: %d, len %d", visited, lc.Len())
+	}
+}
+
+func TestExpirableMultipleClose(_ *testing.T) {
+	lc := NewLRU[string, string](10, nil, 0)
+	lc.Close()
+	// should not panic
+	lc.Close()
+}
 
 func TestLRUWithPurge(t *testing.T) {
 	var evicted []string
@@ -575,3 +697,404 @@ func TestCache_EvictionSameKey(t *testing.T) {
 		t.Errorf("evictedKeys got: %v want: %v", evictedKeys, want)
 	}
 }
+
+func TestLRUPeekOldestN(t *testing.T) {
+	lc := NewLRU[string, string](3, nil, time.Hour)
+	lc.Add("key1", "val1")
+	lc.Add("key2", "val2")
+	lc.Add("key3", "val3")
+
+	want := []simplelru.Entry[string, string]{{Key: "key1", Value: "val1"}, {Key: "key2", Value: "val2"}}
+	if got := lc.PeekOldestN(2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	lc.wantKeys(t, []string{"key1", "key2", "key3"})
+
+	// expired entries are skipped
+	lc.items["key1"].ExpiresAt = lc.now() - int64(time.Second)
+	want = []simplelru.Entry[string, string]{{Key: "key2", Value: "val2"}, {Key: "key3", Value: "val3"}}
+	if got := lc.PeekOldestN(3); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+}
+
+func TestLRUBucketReclaim(t *testing.T) {
+	lc := NewLRU[int, int](0, nil, time.Hour)
+	for i := 0; i < minBucketReclaimSize; i++ {
+		lc.Add(i, i)
+	}
+	b := &lc.buckets[lc.items[0].ExpireBucket]
+	if b.peak != minBucketReclaimSize {
+		t.Fatalf("bad bucket peak: %d", b.peak)
+	}
+	entries := b.entries
+
+	for i := 1; i < minBucketReclaimSize; i++ {
+		lc.Remove(i)
+	}
+	if reflect.ValueOf(b.entries).Pointer() != reflect.ValueOf(entries).Pointer() {
+		t.Fatalf("bucket map should be kept while not empty")
+	}
+
+	lc.Remove(0)
+	if reflect.ValueOf(b.entries).Pointer() == reflect.ValueOf(entries).Pointer() {
+		t.Fatalf("bucket map should have been reallocated")
+	}
+	if b.peak != 0 || len(b.entries) != 0 {
+		t.Fatalf("bucket should be reset")
+	}
+
+	// the reallocated bucket keeps working
+	lc.Add(1, 1)
+	if len(b.entries) != 1 || !lc.Remove(1) {
+		t.Fatalf("bucket should track new entries")
+	}
+}
+
+func TestLRUExpiryForecast(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts[int, int](0, nil, 4*time.Minute, WithClock[int, int](clock))
+	if got := lc.ExpiryForecast(0); got != nil {
+		t.Fatalf("bad forecast: %v", got)
+	}
+
+	lc.Add(1, 1)
+	lc.Add(2, 2)
+	clock.Advance(100 * time.Second)
+	lc.Add(3, 3)
+	clock.Advance(100 * time.Second)
+	lc.Add(4, 4)
+
+	// 1 and 2 expire in 40s, 3 in 140s and 4 in 240s
+	want := []int{2, 0, 1, 1}
+	if got := lc.ExpiryForecast(4); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+
+	// expired entries not cleaned up yet are due right away
+	clock.Advance(2 * time.Minute)
+	want = []int{3, 1}
+	if got := lc.ExpiryForecast(2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+}
+
+func TestLRUTouchUpdateTTL(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts[int, int](0, nil, 4*time.Minute, WithClock[int, int](clock))
+	for i := 1; i <= 4; i++ {
+		lc.Add(i, i)
+	}
+	clock.Advance(3 * time.Minute)
+
+	if !lc.Touch(1) || !lc.UpdateTTL(2, 30*time.Second) || !lc.UpdateTTL(3, time.Hour) {
+		t.Fatalf("entries should have been updated")
+	}
+	if !lc.UpdateTTL(4, 0) || lc.Touch(4) {
+		t.Fatalf("4 should have expired right away")
+	}
+	if lc.Touch(5) {
+		t.Fatalf("missing keys should not be touched")
+	}
+	// the recent-ness is unchanged
+	if keys := lc.Keys(); !reflect.DeepEqual(keys, []int{1, 2, 3}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	// 1 and 3 expire in 4m, as longer TTLs are capped, and 2 in 30s
+	if got, want := lc.ExpiryForecast(4), []int{2, 0, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	clock.Advance(time.Minute)
+	if _, ok := lc.Get(2); ok {
+		t.Fatalf("2 should have expired")
+	}
+	if lc.Touch(2) {
+		t.Fatalf("expired entries should not be touched")
+	}
+	if v, ok := lc.Get(1); !ok || v != 1 {
+		t.Fatalf("1 should not have expired")
+	}
+}
+
+func TestLRUPop(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	var evicted []int
+	lc := NewLRUWithOpts(0, func(k, v int) { evicted = append(evicted, k) }, time.Minute, WithClock[int, int](clock))
+
+	lc.Add(1, 10)
+	lc.Add(2, 20)
+	if v, ok := lc.Pop(1); !ok || v != 10 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if lc.Contains(1) || len(evicted) != 0 {
+		t.Fatalf("1 should have been removed without eviction")
+	}
+
+	clock.Advance(2 * time.Minute)
+	if _, ok := lc.Pop(2); ok {
+		t.Fatalf("expired entries should not be returned")
+	}
+}
+
+func TestLRUSnapshotRestore(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts[string, int](0, nil, 10*time.Minute, WithClock[string, int](clock))
+	lc.Add("a", 1)
+	clock.Advance(5 * time.Minute)
+	lc.Add("b", 2)
+	clock.Advance(6 * time.Minute)
+	lc.Add("c", 3)
+
+	// the snapshot skips a, which has expired, and survives encoding
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(lc.Snapshot()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var entries []SnapshotEntry[string, int]
+	if err := gob.NewDecoder(&buf).Decode(&entries); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Key != "b" || entries[1].Key != "c" {
+		t.Fatalf("bad snapshot: %v", entries)
+	}
+
+	restoredAt := clock.Now()
+	clock2 := simplelru.NewFakeClock(restoredAt.Add(time.Minute))
+	lc2 := NewLRUWithOpts[string, int](0, nil, 10*time.Minute, WithClock[string, int](clock2))
+	if n := lc2.Restore(entries); n != 2 {
+		t.Fatalf("bad restored count: %v", n)
+	}
+	if !reflect.DeepEqual(lc2.Keys(), []string{"b", "c"}) {
+		t.Fatalf("bad keys: %v", lc2.Keys())
+	}
+	// b keeps its original expiry instead of starting a new TTL
+	if got := lc2.base.Add(time.Duration(lc2.items["b"].ExpiresAt)); !got.Equal(entries[0].ExpiresAt) {
+		t.Fatalf("bad expiry: %v, want %v", got, entries[0].ExpiresAt)
+	}
+	clock2.Advance(4 * time.Minute)
+	if _, ok := lc2.Get("b"); ok {
+		t.Fatalf("b should have expired")
+	}
+	if v, ok := lc2.Get("c"); !ok || v != 3 {
+		t.Fatalf("c should not have expired")
+	}
+
+	// b's bucket is cleaned up before c's
+	dist := func(key string) int {
+		return (int(lc2.items[key].ExpireBucket) - int(lc2.nextCleanupBucket) + numBuckets) % numBuckets
+	}
+	if dist("b") >= dist("c") {
+		t.Fatalf("bad buckets: %v, %v", dist("b"), dist("c"))
+	}
+
+	// entries outliving the TTL of the restoring cache are shortened to it
+	lc3 := NewLRUWithOpts[string, int](0, nil, time.Minute, WithClock[string, int](clock2))
+	lc3.Restore(entries)
+	if got := lc3.base.Add(time.Duration(lc3.items["c"].ExpiresAt)); !got.Equal(clock2.Now().Add(time.Minute)) {
+		t.Fatalf("bad expiry: %v", got)
+	}
+}
+
+func TestLRUEvictCallbackReentrant(t *testing.T) {
+	var lc *LRU[int, int]
+	var evicted []int
+	lc = NewLRU(2, func(k, v int) {
+		evicted = append(evicted, k)
+		// re-adding from the callback must not deadlock
+		if k < 10 {
+			lc.Add(k+10, v)
+		}
+	}, time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lc.Add(1, 1)
+		lc.Add(2, 2)
+		lc.Add(3, 3)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("calling back into the cache from the evict callback deadlocked")
+	}
+
+	// evicting 1 re-adds 11, evicting 2 which re-adds 12, and so on
+	if !reflect.DeepEqual(evicted, []int{1, 2, 3, 11}) {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+	if keys := lc.Keys(); !reflect.DeepEqual(keys, []int{12, 13}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
+
+func TestLRUShrink(t *testing.T) {
+	var evicted []int
+	lc := NewLRU(0, func(k, v int) { evicted = append(evicted, k) }, time.Hour)
+	for i := 0; i < 10; i++ {
+		lc.Add(i, i)
+	}
+	if n := lc.Shrink(0.3); n != 3 {
+		t.Fatalf("bad: %d", n)
+	}
+	if !reflect.DeepEqual(evicted, []int{0, 1, 2}) {
+		t.Fatalf("bad: %v", evicted)
+	}
+	if n := lc.Shrink(0); n != 0 || lc.Len() != 7 {
+		t.Fatalf("bad: %d, %d left", n, lc.Len())
+	}
+}
+
+func TestLRUStats(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts[int, int](2, nil, time.Minute, WithClock[int, int](clock))
+	lc.Add(1, 1)
+	lc.Add(2, 2)
+	lc.Add(3, 3)
+	lc.Get(3)
+	lc.Get(1)
+
+	clock.Advance(2 * time.Minute)
+	lc.Get(3)
+	for i := 0; i < numBuckets; i++ {
+		lc.deleteExpired()
+	}
+
+	want := simplelru.Stats{Hits: 1, Misses: 2, Adds: 3, Evictions: 1, Expirations: 2}
+	if s := lc.Stats(); s != want {
+		t.Fatalf("got %+v, want %+v", s, want)
+	}
+	lc.ResetStats()
+	if s := lc.Stats(); s != (simplelru.Stats{}) {
+		t.Fatalf("bad: %+v", s)
+	}
+}
+
+func TestLRUExpiryCallbackOrder(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	var evicted []int
+	lc := NewLRUWithOpts(0, func(k, v int) { evicted = append(evicted, k) }, time.Hour, WithClock[int, int](clock))
+	// all in one bucket, added in the reverse order of their keys
+	var want []int
+	for i := 20; i > 0; i-- {
+		lc.Add(i, i)
+		want = append(want, i)
+		clock.Advance(time.Millisecond)
+	}
+	clock.Advance(2 * time.Hour)
+	for i := 0; i < numBuckets; i++ {
+		lc.deleteExpired()
+	}
+	if !reflect.DeepEqual(evicted, want) {
+		t.Fatalf("got %v, want %v", evicted, want)
+	}
+}
+
+func TestLRUCallbacksSerialized(t *testing.T) {
+	var active, overlaps int32
+	var lc *LRU[int, int]
+	lc = NewLRU(100, func(k, v int) {
+		if atomic.AddInt32(&active, 1) != 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		runtime.Gosched()
+		// calling back into the cache is delivered afterwards, in order
+		if k%10 == 0 {
+			lc.Remove(k + 1)
+		}
+		atomic.AddInt32(&active, -1)
+	}, 10*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				switch k := (i * (w + 1)) % 200; {
+				case i%97 == 0:
+					lc.Purge()
+				case i%3 == 0:
+					lc.Remove(k)
+				default:
+					lc.Add(k, i)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	time.Sleep(30 * time.Millisecond)
+	if n := atomic.LoadInt32(&overlaps); n != 0 {
+		t.Fatalf("callbacks overlapped %d times", n)
+	}
+}
+
+func TestLRUCallbackReentrant(t *testing.T) {
+	var evicted []int
+	var lc *LRU[int, int]
+	lc = NewLRU(0, func(k, v int) {
+		evicted = append(evicted, k)
+		if k == 1 {
+			lc.Remove(2)
+		}
+	}, 0)
+	lc.Add(1, 1)
+	lc.Add(2, 2)
+	lc.Add(3, 3)
+	lc.Remove(1)
+	if !reflect.DeepEqual(evicted, []int{1, 2}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+}
+
+func TestLRUCallbackPanic(t *testing.T) {
+	var evicted []int
+	var lc *LRU[int, int]
+	lc = NewLRU(0, func(k, v int) {
+		evicted = append(evicted, k)
+		if k == 1 {
+			lc.Remove(4)
+			lc.Remove(5)
+		}
+		if k == 2 || k == 4 {
+			panic(k)
+		}
+	}, 0)
+	for i := 1; i <= 6; i++ {
+		lc.Add(i, i)
+	}
+	mustPanic := func(fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("should have panicked")
+			}
+		}()
+		fn()
+	}
+
+	// the delivery of 5 queued behind the panicking one of 4 is kept
+	mustPanic(func() { lc.Remove(1) })
+	if !reflect.DeepEqual(evicted, []int{1, 4}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+	lc.Remove(6)
+	if !reflect.DeepEqual(evicted, []int{1, 4, 5, 6}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+
+	// the entries purged after the panicking 2, in any order, are still
+	// delivered
+	evicted = nil
+	mustPanic(lc.Purge)
+	sort.Ints(evicted)
+	if !reflect.DeepEqual(evicted, []int{2, 3}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+	lc.Add(7, 7)
+	lc.Remove(7)
+	if !reflect.DeepEqual(evicted, []int{2, 3, 7}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+}
@@ -0,0 +1,14 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build go1.23
+
+package expirable
+
+import "iter"
+
+// All returns an iterator over the unexpired entries from oldest to newest.
+// The cache is not locked while the loop body runs, see Range.
+func (c *LRU[K, V]) All() iter.Seq2[K, V] {
+	return c.Range
+}
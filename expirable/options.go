@@ -0,0 +1,98 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package expirable
+
+import (
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/internal"
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// Option configures an LRU constructed by NewLRUWithOpts.
+type Option[K comparable, V any] func(*LRU[K, V])
+
+// WithExpireBatchCallback sets a callback receiving all entries expired by one pass of
+// the cleanup goroutine in a single call, made without holding the lock,
+// instead of calling the evict callback once per expired entry. Entries
+// leaving the cache for any other reason are still passed to the evict
+// callback.
+func WithExpireBatchCallback[K comparable, V any](fn func(entries []simplelru.Entry[K, V])) Option[K, V] {
+	return func(c *LRU[K, V]) {
+		c.onExpireBatch = fn
+	}
+}
+
+// WithEvictReasonCallback sets a callback invoked without holding the lock
+// for every entry leaving the cache along with the reason, in addition to
+// the evict callback. Unlike the evict callback, it is also passed the old
+// values replaced by an update of their key. Entries passed to the
+// WithExpireBatchCallback callback are not passed to it.
+func WithEvictReasonCallback[K comparable, V any](fn func(key K, value V, reason EvictReason)) Option[K, V] {
+	return func(c *LRU[K, V]) {
+		c.onEvictReason = fn
+	}
+}
+
+// WithClock sets the clock entries expire by, simplelru.RealClock by default.
+// The cleanup goroutine still ticks in real time, and only removes buckets
+// of entries that have expired according to the clock.
+func WithClock[K comparable, V any](clock simplelru.Clock) Option[K, V] {
+	return func(c *LRU[K, V]) {
+		if clock != nil {
+			c.clock = clock
+		}
+	}
+}
+
+// WithCoarseClock makes the cache read its clock once every resolution from
+// a background goroutine instead of on every operation, for workloads where
+// the cost of reading the clock shows up. Entries then expire up to
+// resolution before or after their TTL elapses, so resolution should be
+// small compared with the TTL, e.g. a few milliseconds.
+func WithCoarseClock[K comparable, V any](resolution time.Duration) Option[K, V] {
+	return func(c *LRU[K, V]) {
+		if resolution > 0 {
+			c.coarse = resolution
+		}
+	}
+}
+
+// WithMaxExpirationsPerLock bounds the number of entries the cleanup
+// goroutine expires while holding the lock to n, releasing it between
+// batches so that operations such as Get do not stall behind a large
+// backlog of expirations. Lookups themselves never remove expired entries,
+// so this bounds the time any operation waits for the cleanup. With
+// WithExpireBatchCallback, each batch is delivered in its own call.
+func WithMaxExpirationsPerLock[K comparable, V any](n int) Option[K, V] {
+	return func(c *LRU[K, V]) {
+		if n > 0 {
+			c.maxExpirations = n
+		}
+	}
+}
+
+// WithAutoClose closes values implementing io.Closer once they leave the
+// cache, whether they expire, are evicted, removed, purged or replaced by an
+// update of their key, so that no evict callback is needed to release them.
+// Values handed back to the caller, such as by Pop, are not closed. Close is
+// called in a new goroutine, after the evict or expire batch callback if
+// any, and its errors are passed to onError unless it is nil.
+func WithAutoClose[K comparable, V any](onError func(key K, err error)) Option[K, V] {
+	return func(c *LRU[K, V]) {
+		c.autoClose = &internal.AutoCloser[K]{OnError: onError}
+	}
+}
+
+// WithCallbackExecutor makes the cache hand the callbacks for entries that
+// left it to execute instead of calling them directly, e.g. to run them on
+// a worker pool or with a tracing context. execute is called without
+// holding the lock, once per batch of entries removed together, and may run
+// fn asynchronously, in which case callbacks for different batches may run
+// concurrently or out of order.
+func WithCallbackExecutor[K comparable, V any](execute func(fn func())) Option[K, V] {
+	return func(c *LRU[K, V]) {
+		c.execute = execute
+	}
+}
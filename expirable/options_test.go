@@ -0,0 +1,355 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package expirable
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+func TestLRUExpireBatchCallback(t *testing.T) {
+	var evicted []string
+	var batches [][]simplelru.Entry[string, string]
+	lc := NewLRUWithOpts(2, func(k, v string) { evicted = append(evicted, k) }, time.Hour,
+		WithExpireBatchCallback(func(entries []simplelru.Entry[string, string]) {
+			batches = append(batches, entries)
+		}))
+
+	lc.Add("key1", "val1")
+	lc.Add("key2", "val2")
+	lc.Add("key3", "val3")
+	if !reflect.DeepEqual(evicted, []string{"key1"}) {
+		t.Fatalf("capacity evictions should use the evict callback: %v", evicted)
+	}
+
+	// make the bucket holding the entries due for cleanup
+	bucketIdx := lc.items["key2"].ExpireBucket
+	lc.nextCleanupBucket = bucketIdx
+	lc.buckets[bucketIdx].newestEntry = lc.now() - int64(time.Second)
+	lc.deleteExpired()
+
+	if len(batches) != 1 {
+		t.Fatalf("expected a single batch, got %d", len(batches))
+	}
+	batch := batches[0]
+	sort.Slice(batch, func(i, j int) bool { return batch[i].Key < batch[j].Key })
+	want := []simplelru.Entry[string, string]{{Key: "key2", Value: "val2"}, {Key: "key3", Value: "val3"}}
+	if !reflect.DeepEqual(batch, want) {
+		t.Fatalf("got: %v, want: %v", batch, want)
+	}
+	if len(evicted) != 1 || lc.Len() != 0 {
+		t.Fatalf("expired entries should only be passed to the batch callback")
+	}
+
+	// empty passes don't invoke the callback
+	lc.deleteExpired()
+	if len(batches) != 1 {
+		t.Fatalf("unexpected batch: %v", batches)
+	}
+}
+
+func TestLRUWithMaxExpirationsPerLock(t *testing.T) {
+	var batches []int
+	lc := NewLRUWithOpts[int, int](0, nil, time.Hour,
+		WithMaxExpirationsPerLock[int, int](2),
+		WithExpireBatchCallback(func(entries []simplelru.Entry[int, int]) {
+			batches = append(batches, len(entries))
+		}))
+	for i := 0; i < 5; i++ {
+		lc.Add(i, i)
+	}
+
+	bucketIdx := lc.items[0].ExpireBucket
+	lc.nextCleanupBucket = bucketIdx
+	lc.buckets[bucketIdx].newestEntry = lc.now() - int64(time.Second)
+	lc.deleteExpired()
+	if !reflect.DeepEqual(batches, []int{2, 2, 1}) {
+		t.Fatalf("bad batches: %v", batches)
+	}
+	if lc.Len() != 0 || lc.nextCleanupBucket == bucketIdx {
+		t.Fatalf("the bucket should have been cleaned up, got %v", lc.Keys())
+	}
+	if s := lc.Stats(); s.Expirations != 5 {
+		t.Fatalf("bad stats: %+v", s)
+	}
+}
+
+func TestLRUWithMaxExpirationsPerLock_Removed(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	var expired []int
+	var lc *LRU[int, int]
+	lc = NewLRUWithOpts[int, int](0, nil, time.Hour,
+		WithClock[int, int](clock),
+		WithMaxExpirationsPerLock[int, int](2),
+		WithExpireBatchCallback(func(entries []simplelru.Entry[int, int]) {
+			for _, e := range entries {
+				expired = append(expired, e.Key)
+			}
+			// removed between two batches of the sorted bucket
+			lc.Remove(3)
+		}))
+	for i := 0; i < 5; i++ {
+		lc.Add(i, i)
+		clock.Advance(time.Millisecond)
+	}
+
+	bucketIdx := lc.items[0].ExpireBucket
+	lc.nextCleanupBucket = bucketIdx
+	lc.buckets[bucketIdx].newestEntry = lc.now() - int64(time.Second)
+	lc.deleteExpired()
+	if !reflect.DeepEqual(expired, []int{0, 1, 2, 4}) {
+		t.Fatalf("bad expirations: %v", expired)
+	}
+	if lc.Len() != 0 {
+		t.Fatalf("the bucket should have been cleaned up, got %v", lc.Keys())
+	}
+	if s := lc.Stats(); s.Expirations != 4 {
+		t.Fatalf("bad stats: %+v", s)
+	}
+}
+
+func TestLRUWithClock(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts[string, string](0, nil, time.Minute, WithClock[string, string](clock))
+
+	lc.Add("key1", "val1")
+	clock.Advance(30 * time.Second)
+	lc.Add("key2", "val2")
+	if _, ok := lc.Get("key1"); !ok {
+		t.Fatalf("key1 should not have expired yet")
+	}
+
+	clock.Advance(31 * time.Second)
+	if _, ok := lc.Get("key1"); ok {
+		t.Fatalf("key1 should have expired")
+	}
+	if _, ok := lc.Peek("key2"); !ok {
+		t.Fatalf("key2 should not have expired yet")
+	}
+	if !reflect.DeepEqual(lc.Keys(), []string{"key2"}) {
+		t.Fatalf("bad keys: %v", lc.Keys())
+	}
+
+	// cleanup waits for the whole bucket to expire according to the clock
+	bucketIdx := lc.items["key1"].ExpireBucket
+	lc.nextCleanupBucket = bucketIdx
+	lc.deleteExpired()
+	if lc.Len() != 2 || lc.nextCleanupBucket != bucketIdx {
+		t.Fatalf("cleanup should have been retried later, got %v", lc.Keys())
+	}
+	clock.Advance(30 * time.Second)
+	lc.deleteExpired()
+	if lc.Len() != 0 {
+		t.Fatalf("all entries should have been removed, got %v", lc.Keys())
+	}
+}
+
+func TestLRUWithCoarseClock(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts[string, string](0, nil, time.Minute,
+		WithClock[string, string](clock), WithCoarseClock[string, string](time.Hour))
+
+	lc.Add("key1", "val1")
+	clock.Advance(2 * time.Minute)
+	if _, ok := lc.Get("key1"); !ok {
+		t.Fatalf("entries should only expire once the cached time is refreshed")
+	}
+
+	lc.tickCoarseClock()
+	if _, ok := lc.Get("key1"); ok {
+		t.Fatalf("key1 should have expired")
+	}
+
+	// expiry is relative to the cached time
+	lc.Add("key2", "val2")
+	clock.Advance(30 * time.Second)
+	lc.tickCoarseClock()
+	if _, ok := lc.Get("key2"); !ok {
+		t.Fatalf("key2 should not have expired yet")
+	}
+
+	// Close stops the refreshing goroutine, and the clock is read directly
+	lc.Close()
+	clock.Advance(time.Minute)
+	if _, ok := lc.Get("key2"); ok {
+		t.Fatalf("key2 should have expired")
+	}
+}
+
+type testCloser struct {
+	id     int
+	closed chan<- int
+}
+
+func (c *testCloser) Close() error {
+	c.closed <- c.id
+	return nil
+}
+
+func TestLRUWithAutoClose(t *testing.T) {
+	closed := make(chan int, 16)
+	wantClosed := func(want ...int) {
+		t.Helper()
+		var got []int
+		for len(got) < len(want) {
+			select {
+			case id := <-closed:
+				got = append(got, id)
+			case <-time.After(time.Second):
+				t.Fatalf("timed out with %v closed, want %v", got, want)
+			}
+		}
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("bad closed: %v, want %v", got, want)
+		}
+	}
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts[int, *testCloser](2, nil, time.Minute,
+		WithClock[int, *testCloser](clock), WithAutoClose[int, *testCloser](nil))
+
+	lc.Add(1, &testCloser{id: 1, closed: closed})
+	lc.Add(1, &testCloser{id: 2, closed: closed})
+	wantClosed(1)
+	lc.Add(2, &testCloser{id: 3, closed: closed})
+	lc.Add(3, &testCloser{id: 4, closed: closed})
+	wantClosed(2)
+
+	clock.Advance(2 * time.Minute)
+	for i := 0; i < numBuckets; i++ {
+		lc.deleteExpired()
+	}
+	wantClosed(3, 4)
+	if lc.Len() != 0 {
+		t.Fatalf("all entries should have expired, got %v", lc.Keys())
+	}
+}
+
+func TestLRUWithEvictReasonCallback(t *testing.T) {
+	var reasons []string
+	var evicted []int
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts(3, func(k, v int) { evicted = append(evicted, k) }, time.Minute,
+		WithClock[int, int](clock),
+		WithEvictReasonCallback(func(k, v int, reason EvictReason) {
+			reasons = append(reasons, fmt.Sprintf("%d=%d %v", k, v, reason))
+		}))
+
+	lc.Add(1, 1)
+	lc.Add(1, 10)
+	lc.Add(2, 2)
+	lc.Add(3, 3)
+	lc.Add(4, 4)
+	lc.Remove(2)
+	lc.Resize(1)
+	lc.Purge()
+	lc.Add(5, 5)
+	clock.Advance(2 * time.Minute)
+	for i := 0; i < numBuckets; i++ {
+		lc.deleteExpired()
+	}
+
+	want := []string{"1=1 replaced", "1=10 evicted", "2=2 removed", "3=3 resized", "4=4 purged", "5=5 expired"}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Fatalf("bad reasons: %v", reasons)
+	}
+	if !reflect.DeepEqual(evicted, []int{1, 2, 3, 4, 5}) {
+		t.Fatalf("replaced values should not be passed to the evict callback: %v", evicted)
+	}
+}
+
+func TestLRUSetTTL(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts[int, int](0, nil, time.Hour, WithClock[int, int](clock))
+	lc.Add(1, 1)
+	clock.Advance(20 * time.Minute)
+	lc.Add(2, 2)
+
+	// entries keep the time they were added at
+	lc.SetTTL(30 * time.Minute)
+	clock.Advance(11 * time.Minute)
+	if _, ok := lc.Get(1); ok {
+		t.Fatalf("1 should have expired")
+	}
+	if _, ok := lc.Get(2); !ok {
+		t.Fatalf("2 should not have expired yet")
+	}
+	for i := 0; i < numBuckets; i++ {
+		lc.deleteExpired()
+	}
+	if !reflect.DeepEqual(lc.Keys(), []int{2}) {
+		t.Fatalf("1 should have been cleaned up, got %v", lc.Keys())
+	}
+
+	// turning expiry off applies to cached entries too
+	lc.SetTTL(0)
+	clock.Advance(time.Hour)
+	if _, ok := lc.Get(2); !ok {
+		t.Fatalf("2 should not expire anymore")
+	}
+
+	// and starts the cleanup of caches created without a TTL
+	lc = NewLRU[int, int](0, nil, 0)
+	lc.SetTTL(time.Minute)
+	lc.mu.Lock()
+	running := lc.retick != nil
+	lc.mu.Unlock()
+	if !running {
+		t.Fatalf("the cleanup should have been started")
+	}
+}
+
+func TestLRUSetTTL_Tiny(t *testing.T) {
+	clock := simplelru.NewFakeClock(time.Now())
+	lc := NewLRUWithOpts[int, int](0, nil, time.Hour, WithClock[int, int](clock))
+	lc.Add(1, 1)
+
+	// TTLs under 100ns used to make a zero cleanup interval
+	lc.SetTTL(50 * time.Nanosecond)
+	lc.Add(2, 2)
+	clock.Advance(time.Microsecond)
+	if _, ok := lc.Get(2); ok {
+		t.Fatalf("2 should have expired")
+	}
+	lc.SetTTL(time.Hour)
+
+	lc = NewLRUWithOpts[int, int](0, nil, 50*time.Nanosecond, WithClock[int, int](clock))
+	lc.Add(1, 1)
+	lc.SetTTL(time.Hour)
+	if _, ok := lc.Peek(1); !ok {
+		t.Fatalf("1 should not have expired")
+	}
+}
+
+func TestLRUWithCallbackExecutor(t *testing.T) {
+	var queue []func()
+	var evicted []string
+	var reasons []EvictReason
+	lc := NewLRUWithOpts(2, func(k, v string) { evicted = append(evicted, k) }, 0,
+		WithEvictReasonCallback(func(k, v string, reason EvictReason) { reasons = append(reasons, reason) }),
+		WithCallbackExecutor[string, string](func(fn func()) { queue = append(queue, fn) }))
+	lc.Add("a", "1")
+	lc.Add("b", "2")
+	lc.Add("c", "3")
+	lc.Add("c", "4")
+	lc.Purge()
+	if len(evicted) != 0 || len(queue) != 3 {
+		t.Fatalf("callbacks should have been queued: %v, %d", evicted, len(queue))
+	}
+	for _, fn := range queue {
+		fn()
+	}
+	sort.Strings(evicted[1:])
+	if !reflect.DeepEqual(evicted, []string{"a", "b", "c"}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+	if !reflect.DeepEqual(reasons, []EvictReason{Evicted, Replaced, Purged, Purged}) {
+		t.Fatalf("bad reasons: %v", reasons)
+	}
+}
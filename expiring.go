@@ -0,0 +1,341 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// ExpiringPolicy is a thread-safe cache an Expiring layers TTLs over, such as
+// TwoQueueCache or arc.ARCCache, which picks the entries to evict for
+// capacity.
+type ExpiringPolicy[K comparable, V any] interface {
+	Add(key K, value V)
+	Get(key K) (value V, ok bool)
+	Peek(key K) (value V, ok bool)
+	Remove(key K)
+	Purge()
+	Keys() []K
+	Values() []V
+	Len() int
+	Cap() int
+	Resize(size int) (evicted int)
+}
+
+// ExpiringValue is a value stored by an Expiring in its policy.
+type ExpiringValue[V any] struct {
+	Value     V
+	ExpiresAt time.Time
+}
+
+// Expiring is a thread-safe fixed size cache whose entries expire a fixed
+// TTL after they were last added, like those of expirable.LRU, while its
+// policy picks those to evict for capacity. Expired entries are no longer
+// returned, and are removed by a background goroutine until Close is
+// called, invoking the evict callback. It has the methods of expirable.LRU,
+// with GetOldest and RemoveOldest acting on the next entry to expire.
+// Expirable2Q is an Expiring over a TwoQueueCache.
+type Expiring[K comparable, V any] struct {
+	policy  ExpiringPolicy[K, ExpiringValue[V]]
+	onEvict func(key K, value V)
+	ttl     time.Duration
+	clock   Clock
+
+	// expiry holds the cached keys from the first to the last to expire,
+	// since they all expire ttl after they were added
+	expiry *simplelru.LRU[K, time.Time]
+
+	// evicted entries are buffered while mu is held and passed to onEvict
+	// by unlock, so that the callback may call back into the cache
+	evictedKeys []K
+	evictedVals []V
+
+	mu        sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// ExpiryOption configures the expiry of the entries of an Expiring, or of
+// the inner caches of a Nested.
+type ExpiryOption func(*expiryOptions)
+
+type expiryOptions struct {
+	clock Clock
+}
+
+// WithExpiryClock sets the clock entries expire by, RealClock by default.
+func WithExpiryClock(clock Clock) ExpiryOption {
+	return func(o *expiryOptions) {
+		if clock != nil {
+			o.clock = clock
+		}
+	}
+}
+
+// expiringCleanups is how many times per TTL the cleanup goroutine of an
+// Expiring looks for expired entries.
+const expiringCleanups = 100
+
+// NewExpiring creates an Expiring of the given size over the policy created
+// by newPolicy, which must pass every value leaving it to the given evict
+// callback. onEvict, optional, is called after the lock is released for
+// every value that leaves the cache, including expired ones.
+//
+// Providing 0 TTL turns expiring off. Otherwise expired entries are deleted
+// every 1/100th of ttl by a goroutine which runs until Close is called.
+func NewExpiring[K comparable, V any](size int, onEvict func(key K, value V), ttl time.Duration,
+	newPolicy func(onEvict func(key K, value ExpiringValue[V])) (ExpiringPolicy[K, ExpiringValue[V]], error),
+	opts ...ExpiryOption,
+) (*Expiring[K, V], error) {
+	if newPolicy == nil {
+		return nil, errors.New("must provide a policy")
+	}
+	o := expiryOptions{clock: RealClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := &Expiring[K, V]{onEvict: onEvict, ttl: ttl, clock: o.clock, done: make(chan struct{})}
+	var err error
+	c.policy, err = newPolicy(c.onEvicted)
+	if err != nil {
+		return nil, err
+	}
+	c.expiry, err = simplelru.NewLRU[K, time.Time](size, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		interval := ttl / expiringCleanups
+		if interval <= 0 {
+			interval = 1
+		}
+		go func(done <-chan struct{}) {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					c.deleteExpired()
+				}
+			}
+		}(c.done)
+	}
+	return c, nil
+}
+
+// onEvicted is called by the policy, with mu held, for values leaving it.
+func (c *Expiring[K, V]) onEvicted(k K, v ExpiringValue[V]) {
+	c.expiry.Remove(k)
+	if c.onEvict != nil {
+		c.evictedKeys = append(c.evictedKeys, k)
+		c.evictedVals = append(c.evictedVals, v.Value)
+	}
+}
+
+// unlock releases mu and invokes the evict callback for the values dropped
+// while it was held, outside of the critical section.
+func (c *Expiring[K, V]) unlock() {
+	ks, vs := c.evictedKeys, c.evictedVals
+	c.evictedKeys, c.evictedVals = nil, nil
+	c.mu.Unlock()
+	for i := 0; i < len(ks); i++ {
+		c.onEvict(ks[i], vs[i])
+	}
+}
+
+// Add adds a value to the cache, resetting its expiration time. Returns
+// true if an eviction occurred.
+func (c *Expiring[K, V]) Add(key K, value V) (evicted bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.clock.Now().Add(c.ttl)
+	}
+	// evictions remove other keys from expiry before key is added to it
+	n := c.expiry.Len()
+	c.policy.Add(key, ExpiringValue[V]{Value: value, ExpiresAt: expiresAt})
+	evicted = c.expiry.Len() < n
+	c.expiry.Add(key, expiresAt)
+	return evicted
+}
+
+// Get looks up a key's value from the cache, updating its recency and
+// frequency unless it expired.
+func (c *Expiring[K, V]) Get(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	if v, ok := c.policy.Peek(key); ok && c.expired(v) {
+		c.policy.Remove(key)
+		return value, false
+	}
+	v, ok := c.policy.Get(key)
+	return v.Value, ok
+}
+
+// Contains checks if a key is in the cache and has not expired, without
+// updating recency or frequency.
+func (c *Expiring[K, V]) Contains(key K) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Peek returns the value of a key that has not expired, without updating
+// recency or frequency.
+func (c *Expiring[K, V]) Peek(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.policy.Peek(key)
+	if !ok || c.expired(v) {
+		return value, false
+	}
+	return v.Value, true
+}
+
+// Remove removes the provided key from the cache, returning if the key
+// was contained.
+func (c *Expiring[K, V]) Remove(key K) (present bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	present = c.expiry.Contains(key)
+	c.policy.Remove(key)
+	return present
+}
+
+// Purge clears the cache completely, invoking the evict callback for every
+// value.
+func (c *Expiring[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.unlock()
+	c.policy.Purge()
+}
+
+// Keys returns the keys of the entries that have not expired, in the order
+// of the Keys of the policy.
+func (c *Expiring[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := c.policy.Keys()
+	live := keys[:0]
+	for _, k := range keys {
+		if v, _ := c.policy.Peek(k); !c.expired(v) {
+			live = append(live, k)
+		}
+	}
+	return live
+}
+
+// Values returns the values of the entries that have not expired, in the
+// order of Keys.
+func (c *Expiring[K, V]) Values() []V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	vals := c.policy.Values()
+	live := make([]V, 0, len(vals))
+	for _, v := range vals {
+		if !c.expired(v) {
+			live = append(live, v.Value)
+		}
+	}
+	return live
+}
+
+// Len returns the number of cached entries, including expired ones that
+// were not removed yet.
+func (c *Expiring[K, V]) Len() int {
+	return c.policy.Len()
+}
+
+// Cap returns the capacity of the cache.
+func (c *Expiring[K, V]) Cap() int {
+	return c.policy.Cap()
+}
+
+// Close stops the goroutine deleting expired entries. Expired entries are
+// still not returned afterwards, but are only removed by lookups.
+func (c *Expiring[K, V]) Close() {
+	// under lock, so that no cleanup pass runs once Close returned
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// expired reports whether v expired. Has to be called with lock!
+func (c *Expiring[K, V]) expired(v ExpiringValue[V]) bool {
+	return c.ttl > 0 && c.clock.Now().After(v.ExpiresAt)
+}
+
+// GetOldest returns the entry that has not expired and was added the longest
+// ago, which is the next to expire, removing the expired ones before it.
+func (c *Expiring[K, V]) GetOldest() (key K, value V, ok bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.removeExpired()
+	key, _, ok = c.expiry.GetOldest()
+	if !ok {
+		return key, value, false
+	}
+	v, _ := c.policy.Peek(key)
+	return key, v.Value, true
+}
+
+// RemoveOldest removes the entry GetOldest returns, invoking the evict
+// callback.
+func (c *Expiring[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.removeExpired()
+	key, _, ok = c.expiry.GetOldest()
+	if !ok {
+		return key, value, false
+	}
+	v, _ := c.policy.Peek(key)
+	c.policy.Remove(key)
+	return key, v.Value, true
+}
+
+// Resize changes the cache size, evicting entries as the policy picks them
+// if it shrinks. Returns the number of evictions.
+func (c *Expiring[K, V]) Resize(size int) (evicted int) {
+	c.mu.Lock()
+	defer c.unlock()
+	evicted = c.policy.Resize(size)
+	c.expiry.Resize(size)
+	return evicted
+}
+
+// deleteExpired removes the entries that expired, first to last.
+func (c *Expiring[K, V]) deleteExpired() {
+	c.mu.Lock()
+	defer c.unlock()
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	c.removeExpired()
+}
+
+// removeExpired removes the entries that expired, first to last. Has to be
+// called with lock!
+func (c *Expiring[K, V]) removeExpired() {
+	if c.ttl <= 0 {
+		return
+	}
+	now := c.clock.Now()
+	for {
+		key, expiresAt, ok := c.expiry.GetOldest()
+		if !ok || expiresAt.After(now) {
+			return
+		}
+		c.policy.Remove(key)
+		c.expiry.Remove(key)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpiring_Policy(t *testing.T) {
+	if _, err := NewExpiring[int, int](2, nil, time.Second, nil); err == nil {
+		t.Fatalf("should fail without a policy")
+	}
+	policyErr := errors.New("policy")
+	_, err := NewExpiring[int, int](2, nil, time.Second, func(func(int, ExpiringValue[int])) (ExpiringPolicy[int, ExpiringValue[int]], error) {
+		return nil, policyErr
+	})
+	if err != policyErr {
+		t.Fatalf("bad err: %v", err)
+	}
+
+	var evicted []int
+	clock := NewFakeClock(time.Unix(0, 0))
+	l, err := NewExpiring(2, func(k, v int) { evicted = append(evicted, k) }, time.Second,
+		func(onEvict func(int, ExpiringValue[int])) (ExpiringPolicy[int, ExpiringValue[int]], error) {
+			return New2QParamsWithEvict(2, Default2QRecentRatio, Default2QGhostEntries, onEvict)
+		}, WithExpiryClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+	l.Add(1, 1)
+	clock.Advance(time.Second / 2)
+	l.Add(2, 2)
+	clock.Advance(time.Second / 2)
+	l.deleteExpired()
+	if l.Contains(1) || !l.Contains(2) || len(evicted) != 1 || evicted[0] != 1 {
+		t.Fatalf("bad expiry: %v, %v", l.Keys(), evicted)
+	}
+}
+
+func TestExpiring_Oldest(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	var evicted []int
+	l, err := NewExpirable2Q(4, func(k, v int) { evicted = append(evicted, k) }, time.Hour, WithExpiryClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+	if _, _, ok := l.GetOldest(); ok {
+		t.Fatalf("empty cache should have no oldest entry")
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i*10)
+		clock.Advance(time.Minute)
+	}
+	// re-adding renews an entry, hits don't
+	l.Add(0, 0)
+	l.Get(1)
+	if k, v, ok := l.GetOldest(); !ok || k != 1 || v != 10 {
+		t.Fatalf("bad oldest: %v, %v, %v", k, v, ok)
+	}
+	if k, _, ok := l.RemoveOldest(); !ok || k != 1 || l.Contains(1) {
+		t.Fatalf("bad removed oldest: %v, %v", k, ok)
+	}
+
+	// expired entries are removed on the way
+	clock.Advance(time.Hour - time.Minute)
+	if k, _, ok := l.GetOldest(); !ok || k != 0 {
+		t.Fatalf("bad oldest: %v, %v", k, ok)
+	}
+	if !reflect.DeepEqual(evicted, []int{1, 2, 3}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+
+	evicted = nil
+	l.Add(5, 5)
+	l.Add(6, 6)
+	if n := l.Resize(1); n != 2 || l.Len() != 1 || l.Cap() != 1 || len(evicted) != 2 {
+		t.Fatalf("bad resize: %d, %d, %v", n, l.Len(), evicted)
+	}
+	l.Add(7, 7)
+	if l.Len() != 1 {
+		t.Fatalf("bad len: %d", l.Len())
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/v2/internal"
+)
+
+// IntCache is a thread-safe fixed size LRU cache specialized for int64
+// keys. It stores entries in a preallocated open addressing hash table
+// instead of a Go map, which makes lookups cheaper than with Cache.
+type IntCache[V any] struct {
+	fastCache[int64, V]
+}
+
+// NewIntCache creates an IntCache of the given size.
+func NewIntCache[V any](size int) (*IntCache[V], error) {
+	return NewIntCacheWithEvict[V](size, nil)
+}
+
+// NewIntCacheWithEvict constructs a fixed size IntCache with the given
+// eviction callback.
+func NewIntCacheWithEvict[V any](size int, onEvicted func(key int64, value V)) (*IntCache[V], error) {
+	c := &IntCache[V]{}
+	if err := c.init(size, internal.HashInt64, onEvicted); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// StringCache is a thread-safe fixed size LRU cache specialized for string
+// keys. It stores entries in a preallocated open addressing hash table
+// instead of a Go map, which makes lookups cheaper than with Cache.
+//
+// The index only holds a 4 byte slot per entry at a load factor of at most
+// 1/2, with the 64-bit hash of each key kept in its entry to resolve
+// collisions without comparing long keys. Full keys are retained, so
+// lookups are exact and callbacks receive the original keys; where false
+// hits are acceptable, an IntCache keyed by a hash of the strings avoids
+// storing them altogether.
+type StringCache[V any] struct {
+	fastCache[string, V]
+
+	// prefixes indexes the keys for KeysWithPrefix, optional
+	prefixes *internal.Trie
+}
+
+// NewStringCache creates a StringCache of the given size.
+func NewStringCache[V any](size int) (*StringCache[V], error) {
+	return NewStringCacheWithEvict[V](size, nil)
+}
+
+// NewStringCacheWithEvict constructs a fixed size StringCache with the
+// given eviction callback.
+func NewStringCacheWithEvict[V any](size int, onEvicted func(key string, value V)) (*StringCache[V], error) {
+	c := &StringCache[V]{}
+	if err := c.init(size, internal.HashString, onEvicted); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewStringCacheWithPrefixIndex is like NewStringCacheWithEvict, and also
+// maintains a trie of the keys on every addition and removal so that
+// KeysWithPrefix only visits the matching keys.
+func NewStringCacheWithPrefixIndex[V any](size int, onEvicted func(key string, value V)) (*StringCache[V], error) {
+	c := &StringCache[V]{prefixes: new(internal.Trie)}
+	c.index = c.prefixes
+	if err := c.init(size, internal.HashString, onEvicted); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// KeysWithPrefix returns the keys starting with prefix in lexicographic
+// order, at most limit of them unless limit is not positive. Without a
+// prefix index, it scans all the keys.
+func (c *StringCache[V]) KeysWithPrefix(prefix string, limit int) []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.prefixes != nil {
+		return c.prefixes.WithPrefix(prefix, limit)
+	}
+	var keys []string
+	for _, k := range c.lru.Keys() {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+// keyIndex is kept up to date with the keys of a fastCache.
+type keyIndex[K any] interface {
+	Insert(key K)
+	Delete(key K)
+}
+
+// fastCache holds the methods shared by IntCache and StringCache.
+type fastCache[K comparable, V any] struct {
+	lru         *internal.OpenLRU[K, V]
+	evictedKeys []K
+	evictedVals []V
+	onEvictedCB func(k K, v V)
+	index       keyIndex[K] // optional
+	lock        sync.Mutex
+}
+
+func (c *fastCache[K, V]) init(size int, hash func(K) uint64, onEvicted func(key K, value V)) error {
+	if size <= 0 {
+		return errors.New("must provide a positive size")
+	}
+	c.onEvictedCB = onEvicted
+	if onEvicted != nil || c.index != nil {
+		c.initEvictBuffers()
+		c.lru = internal.NewOpenLRU(size, hash, c.onEvicted)
+	} else {
+		c.lru = internal.NewOpenLRU[K, V](size, hash, nil)
+	}
+	return nil
+}
+
+func (c *fastCache[K, V]) initEvictBuffers() {
+	c.evictedKeys = make([]K, 0, DefaultEvictedBufferSize)
+	c.evictedVals = make([]V, 0, DefaultEvictedBufferSize)
+}
+
+// onEvicted save evicted key/val and sent in externally registered callback
+// outside of critical section
+func (c *fastCache[K, V]) onEvicted(k K, v V) {
+	if c.index != nil {
+		c.index.Delete(k)
+	}
+	if c.onEvictedCB != nil {
+		c.evictedKeys = append(c.evictedKeys, k)
+		c.evictedVals = append(c.evictedVals, v)
+	}
+}
+
+// Purge is used to completely clear the cache.
+func (c *fastCache[K, V]) Purge() {
+	var ks []K
+	var vs []V
+	c.lock.Lock()
+	c.lru.Purge()
+	if c.onEvictedCB != nil && len(c.evictedKeys) > 0 {
+		ks, vs = c.evictedKeys, c.evictedVals
+		c.initEvictBuffers()
+	}
+	c.lock.Unlock()
+	// invoke callback outside of critical section
+	if c.onEvictedCB != nil {
+		for i := 0; i < len(ks); i++ {
+			c.onEvictedCB(ks[i], vs[i])
+		}
+	}
+}
+
+// Add adds a value to the cache. Returns true if an eviction occurred.
+func (c *fastCache[K, V]) Add(key K, value V) (evicted bool) {
+	var k K
+	var v V
+	c.lock.Lock()
+	evicted = c.lru.Add(key, value)
+	if c.index != nil {
+		c.index.Insert(key)
+	}
+	if c.onEvictedCB != nil && evicted {
+		k, v = c.evictedKeys[0], c.evictedVals[0]
+		c.evictedKeys, c.evictedVals = c.evictedKeys[:0], c.evictedVals[:0]
+	}
+	c.lock.Unlock()
+	if c.onEvictedCB != nil && evicted {
+		c.onEvictedCB(k, v)
+	}
+	return
+}
+
+// Get looks up a key's value from the cache.
+func (c *fastCache[K, V]) Get(key K) (value V, ok bool) {
+	c.lock.Lock()
+	value, ok = c.lru.Get(key)
+	c.lock.Unlock()
+	return value, ok
+}
+
+// Contains checks if a key is in the cache, without updating the
+// recent-ness or deleting it for being stale.
+func (c *fastCache[K, V]) Contains(key K) bool {
+	c.lock.Lock()
+	_, ok := c.lru.Peek(key)
+	c.lock.Unlock()
+	return ok
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the "recently used"-ness of the key.
+func (c *fastCache[K, V]) Peek(key K) (value V, ok bool) {
+	c.lock.Lock()
+	value, ok = c.lru.Peek(key)
+	c.lock.Unlock()
+	return value, ok
+}
+
+// Remove removes the provided key from the cache.
+func (c *fastCache[K, V]) Remove(key K) (present bool) {
+	var k K
+	var v V
+	c.lock.Lock()
+	present = c.lru.Remove(key)
+	if c.onEvictedCB != nil && present {
+		k, v = c.evictedKeys[0], c.evictedVals[0]
+		c.evictedKeys, c.evictedVals = c.evictedKeys[:0], c.evictedVals[:0]
+	}
+	c.lock.Unlock()
+	if c.onEvictedCB != nil && present {
+		c.onEvictedCB(k, v)
+	}
+	return
+}
+
+// RemoveOldest removes the oldest item from the cache.
+func (c *fastCache[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	var k K
+	var v V
+	c.lock.Lock()
+	key, value, ok = c.lru.RemoveOldest()
+	if c.onEvictedCB != nil && ok {
+		k, v = c.evictedKeys[0], c.evictedVals[0]
+		c.evictedKeys, c.evictedVals = c.evictedKeys[:0], c.evictedVals[:0]
+	}
+	c.lock.Unlock()
+	if c.onEvictedCB != nil && ok {
+		c.onEvictedCB(k, v)
+	}
+	return
+}
+
+// GetOldest returns the oldest entry
+func (c *fastCache[K, V]) GetOldest() (key K, value V, ok bool) {
+	c.lock.Lock()
+	key, value, ok = c.lru.GetOldest()
+	c.lock.Unlock()
+	return
+}
+
+// Keys returns a slice of the keys in the cache, from oldest to newest.
+func (c *fastCache[K, V]) Keys() []K {
+	c.lock.Lock()
+	keys := c.lru.Keys()
+	c.lock.Unlock()
+	return keys
+}
+
+// Values returns a slice of the values in the cache, from oldest to newest.
+func (c *fastCache[K, V]) Values() []V {
+	c.lock.Lock()
+	values := c.lru.Values()
+	c.lock.Unlock()
+	return values
+}
+
+// Len returns the number of items in the cache.
+func (c *fastCache[K, V]) Len() int {
+	c.lock.Lock()
+	length := c.lru.Len()
+	c.lock.Unlock()
+	return length
+}
+
+// Cap returns the capacity of the cache
+func (c *fastCache[K, V]) Cap() int {
+	return c.lru.Cap()
+}
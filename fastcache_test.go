@@ -0,0 +1,229 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+func BenchmarkIntCache_Rand(b *testing.B) {
+	l, err := NewIntCache[int64](8192)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+
+	trace := make([]int64, b.N*2)
+	for i := 0; i < b.N*2; i++ {
+		trace[i] = getRand(b) % 32768
+	}
+
+	b.ResetTimer()
+
+	var hit, miss int
+	for i := 0; i < 2*b.N; i++ {
+		if i%2 == 0 {
+			l.Add(trace[i], trace[i])
+		} else {
+			if _, ok := l.Get(trace[i]); ok {
+				hit++
+			} else {
+				miss++
+			}
+		}
+	}
+	b.Logf("hit: %d miss: %d ratio: %f", hit, miss, float64(hit)/float64(hit+miss))
+}
+
+func BenchmarkStringCache_Rand(b *testing.B) {
+	l, err := NewStringCache[int64](8192)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+
+	trace := make([]string, b.N*2)
+	for i := 0; i < b.N*2; i++ {
+		trace[i] = strconv.FormatInt(getRand(b)%32768, 10)
+	}
+
+	b.ResetTimer()
+
+	var hit, miss int
+	for i := 0; i < 2*b.N; i++ {
+		if i%2 == 0 {
+			l.Add(trace[i], int64(i))
+		} else {
+			if _, ok := l.Get(trace[i]); ok {
+				hit++
+			} else {
+				miss++
+			}
+		}
+	}
+	b.Logf("hit: %d miss: %d ratio: %f", hit, miss, float64(hit)/float64(hit+miss))
+}
+
+func TestIntCache(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k int64, v int64) {
+		if k != v {
+			t.Fatalf("Evict values not equal (%v!=%v)", k, v)
+		}
+		evictCounter++
+	}
+	l, err := NewIntCacheWithEvict(128, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := int64(0); i < 256; i++ {
+		l.Add(i, i)
+	}
+	if l.Len() != 128 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if l.Cap() != 128 {
+		t.Fatalf("expect %d, but %d", 128, l.Cap())
+	}
+	if evictCounter != 128 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+
+	for i, k := range l.Keys() {
+		if v, ok := l.Get(k); !ok || v != k || v != int64(i+128) {
+			t.Fatalf("bad key: %v", k)
+		}
+	}
+	for i := int64(0); i < 128; i++ {
+		if _, ok := l.Get(i); ok {
+			t.Fatalf("should be evicted")
+		}
+	}
+	for i := int64(128); i < 192; i++ {
+		if !l.Remove(i) {
+			t.Fatalf("should be contained")
+		}
+		if l.Remove(i) {
+			t.Fatalf("should not be contained")
+		}
+	}
+	if evictCounter != 192 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+
+	l.Get(192) // expect 192 to be last key in l.Keys()
+	for i, k := range l.Keys() {
+		if (i < 63 && k != int64(i+193)) || (i == 63 && k != 192) {
+			t.Fatalf("out of order key: %v", k)
+		}
+	}
+
+	if k, _, ok := l.GetOldest(); !ok || k != 193 {
+		t.Fatalf("bad oldest: %v", k)
+	}
+	if k, _, ok := l.RemoveOldest(); !ok || k != 193 {
+		t.Fatalf("bad oldest: %v", k)
+	}
+
+	l.Purge()
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if evictCounter != 256 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+	if _, ok := l.Get(200); ok {
+		t.Fatalf("should contain nothing")
+	}
+	l.Add(1, 1)
+	if v, ok := l.Peek(1); !ok || v != 1 {
+		t.Fatalf("bad: %v", v)
+	}
+}
+
+// Test that StringCache matches Cache under a random mix of operations,
+// exercising collisions and deletions in the hash table
+func TestStringCache_MatchesLRU(t *testing.T) {
+	want, err := simplelru.NewLRU[string, int](64, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l, err := NewStringCache[int](64)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		k := strconv.Itoa(r.Intn(128))
+		switch r.Intn(4) {
+		case 0, 1:
+			if got, exp := l.Add(k, i), want.Add(k, i); got != exp {
+				t.Fatalf("Add(%s) evicted %v, want %v", k, got, exp)
+			}
+		case 2:
+			v1, ok1 := l.Get(k)
+			v2, ok2 := want.Get(k)
+			if v1 != v2 || ok1 != ok2 {
+				t.Fatalf("Get(%s) = %v, %v want %v, %v", k, v1, ok1, v2, ok2)
+			}
+		case 3:
+			if got, exp := l.Remove(k), want.Remove(k); got != exp {
+				t.Fatalf("Remove(%s) = %v, want %v", k, got, exp)
+			}
+		}
+	}
+	if !reflect.DeepEqual(l.Keys(), want.Keys()) {
+		t.Fatalf("keys differ: %v, %v", l.Keys(), want.Keys())
+	}
+	if !reflect.DeepEqual(l.Values(), want.Values()) {
+		t.Fatalf("values differ: %v, %v", l.Values(), want.Values())
+	}
+}
+
+func TestIntCache_BadSize(t *testing.T) {
+	if _, err := NewIntCache[int](0); err == nil {
+		t.Fatalf("should have failed")
+	}
+	if _, err := NewStringCache[int](-1); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
+
+func TestStringCache_KeysWithPrefix(t *testing.T) {
+	plain, err := NewStringCache[int](4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	indexed, err := NewStringCacheWithPrefixIndex[int](4, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, l := range []*StringCache[int]{plain, indexed} {
+		for i, k := range []string{"/a/2", "/b/1", "/a/1", "/a", "/a/3"} {
+			l.Add(k, i)
+		}
+		l.Remove("/a/1")
+
+		// "/a/2" was evicted and "/a/1" removed
+		if keys := l.KeysWithPrefix("/a", 0); !reflect.DeepEqual(keys, []string{"/a", "/a/3"}) {
+			t.Fatalf("bad: %v", keys)
+		}
+		if keys := l.KeysWithPrefix("", 2); !reflect.DeepEqual(keys, []string{"/a", "/a/3"}) {
+			t.Fatalf("bad: %v", keys)
+		}
+		if keys := l.KeysWithPrefix("/c", 0); len(keys) != 0 {
+			t.Fatalf("bad: %v", keys)
+		}
+
+		l.Purge()
+		if keys := l.KeysWithPrefix("", 0); len(keys) != 0 {
+			t.Fatalf("bad: %v", keys)
+		}
+	}
+}
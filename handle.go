@@ -0,0 +1,81 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import "github.com/hashicorp/golang-lru/v2/simplelru"
+
+// Handle references a single Cache entry, allowing repeated access to it
+// without hashing the key again. It is safe for concurrent use and stays
+// valid until its entry leaves the cache.
+type Handle[K comparable, V any] struct {
+	c *Cache[K, V]
+	h simplelru.Handle[K, V]
+}
+
+// GetHandle looks up a key and returns a Handle to its entry, updating the
+// "recently used"-ness of the key.
+func (c *Cache[K, V]) GetHandle(key K) (h Handle[K, V], ok bool) {
+	if c.alarm != nil {
+		c.checkHitRatio()
+	}
+	c.lock.Lock()
+	h.h, ok = c.lru.GetHandle(key)
+	c.stats.Lookup(ok)
+	c.observeLookup(key)
+	c.lock.Unlock()
+	if ok {
+		h.c = c
+	}
+	return h, ok
+}
+
+// Key returns the key of the referenced entry.
+func (h Handle[K, V]) Key() K {
+	return h.h.Key()
+}
+
+// Value returns the entry's value without updating the "recently used"-ness
+// of the key.
+func (h Handle[K, V]) Value() (value V, ok bool) {
+	if h.c == nil {
+		return
+	}
+	h.c.lock.RLock()
+	value, ok = h.h.Value()
+	h.c.lock.RUnlock()
+	return h.c.decode(value, ok)
+}
+
+// Touch updates the "recently used"-ness of the entry. Returns false if the
+// entry is no longer in the cache.
+func (h Handle[K, V]) Touch() (ok bool) {
+	if h.c == nil {
+		return false
+	}
+	h.c.lock.Lock()
+	ok = h.h.Touch()
+	h.c.lock.Unlock()
+	return ok
+}
+
+// Remove removes the entry from the cache, returning if it was contained.
+func (h Handle[K, V]) Remove() (present bool) {
+	if h.c == nil {
+		return false
+	}
+	c := h.c
+	var k K
+	var v V
+	var m any
+	c.lock.Lock()
+	present = h.h.Remove()
+	if c.onEvictedCB != nil && present {
+		k, v, m = c.takeFirstEvicted()
+	}
+	c.lock.Unlock()
+	if c.onEvictedCB != nil && present {
+		c.notifyEvicted(k, v, m)
+	}
+	return
+}
@@ -0,0 +1,65 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import "testing"
+
+func TestCacheHandle(t *testing.T) {
+	var evicted []int
+	l, err := NewWithEvict(2, func(k int, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	h, ok := l.GetHandle(1)
+	if !ok {
+		t.Fatalf("missing handle")
+	}
+	if v, ok := h.Value(); !ok || v != 1 {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+
+	l.Add(3, 3)
+	if _, ok := h.Value(); !ok {
+		t.Fatalf("1 should not have been evicted")
+	}
+	if !h.Touch() {
+		t.Fatalf("touch should succeed")
+	}
+	l.wantKeys(t, []int{3, 1})
+
+	if !h.Remove() {
+		t.Fatalf("remove should succeed")
+	}
+	if h.Remove() || h.Touch() {
+		t.Fatalf("handle should be invalid after removal")
+	}
+	if len(evicted) != 2 || evicted[1] != 1 {
+		t.Fatalf("bad evicted keys: %v", evicted)
+	}
+
+	if _, ok := l.GetHandle(1); ok {
+		t.Fatalf("should not find removed key")
+	}
+	var zero Handle[int, int]
+	if _, ok := zero.Value(); ok || zero.Touch() || zero.Remove() {
+		t.Fatalf("zero handle should be invalid")
+	}
+}
+
+func BenchmarkCacheHandle_Touch(b *testing.B) {
+	l, err := New[int64, int64](8192)
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	h, _ := l.GetHandle(1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Touch()
+	}
+}
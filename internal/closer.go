@@ -0,0 +1,46 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package internal
+
+import (
+	"io"
+	"reflect"
+)
+
+// AutoCloser closes the values leaving a cache that implement io.Closer.
+type AutoCloser[K comparable] struct {
+	// OnError receives the errors returned by Close, optional
+	OnError func(key K, err error)
+
+	// Sync closes values in the calling goroutine instead of a new one
+	Sync bool
+}
+
+// Close closes v, which left the cache under key, in a new goroutine unless
+// Sync is set, if it implements io.Closer.
+func (a *AutoCloser[K]) Close(key K, v any) {
+	closer, ok := v.(io.Closer)
+	if !ok {
+		return
+	}
+	do := func() {
+		if err := closer.Close(); err != nil && a.OnError != nil {
+			a.OnError(key, err)
+		}
+	}
+	if a.Sync {
+		do()
+		return
+	}
+	go do()
+}
+
+// CloseReplaced closes old, which was replaced by new under key, like
+// Close unless it is the same value.
+func (a *AutoCloser[K]) CloseReplaced(key K, old, new any) {
+	if old == nil || (reflect.TypeOf(old).Comparable() && old == new) {
+		return
+	}
+	a.Close(key, old)
+}
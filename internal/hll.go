@@ -0,0 +1,65 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package internal
+
+import (
+	"math"
+	"math/bits"
+)
+
+// hllPrecision is the number of hash bits selecting a register, giving
+// 4096 registers and a standard error of about 1.6%.
+const hllPrecision = 12
+
+// HyperLogLog estimates the number of distinct hashes added to it in
+// constant memory.
+type HyperLogLog struct {
+	registers [1 << hllPrecision]uint8
+}
+
+// Add records a hash. Hashes are remixed, so weak hash functions such as
+// the identity of an integer key are fine.
+func (h *HyperLogLog) Add(hash uint64) {
+	x := Mix64(hash)
+	idx := x >> (64 - hllPrecision)
+	// the guard bit bounds the rank when the remaining bits are all zero
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// Count returns the estimated number of distinct hashes added.
+func (h *HyperLogLog) Count() uint64 {
+	const m = float64(1 << hllPrecision)
+	var sum float64
+	zeros := 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// linear counting is more accurate for small cardinalities
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// Reset forgets all added hashes.
+func (h *HyperLogLog) Reset() {
+	h.registers = [1 << hllPrecision]uint8{}
+}
+
+// Mix64 is the splitmix64 finalizer, spreading the bits of weak hashes.
+func Mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -24,11 +24,33 @@ type Entry[K comparable, V any] struct {
 	// The Value stored with this element.
 	Value V
 
-	// The time this element would be cleaned up, optional
-	ExpiresAt time.Time
+	// The time this element would be cleaned up in nanoseconds since a base
+	// time chosen by the owner of the list, optional. Lists not expiring
+	// their elements may keep the tick the element was last used at instead.
+	ExpiresAt int64
 
 	// The expiry bucket item was put in, optional
 	ExpireBucket uint8
+
+	// The position of the element in an index kept next to the list,
+	// optional
+	Slot int32
+
+	// Access bookkeeping, only allocated if the list tracks it
+	Meta *EntryMeta
+}
+
+// EntryMeta holds the access bookkeeping of an Entry, shared by the
+// features needing more than the recency order.
+type EntryMeta struct {
+	// The time the element was inserted
+	AddedAt time.Time
+
+	// The time the element was last moved to the front
+	AccessedAt time.Time
+
+	// The number of times the element was moved to the front
+	Hits uint64
 }
 
 // PrevEntry returns the previous list element or nil.
@@ -44,6 +66,10 @@ func (e *Entry[K, V]) PrevEntry() *Entry[K, V] {
 type LruList[K comparable, V any] struct {
 	root Entry[K, V] // sentinel list element, only &root, root.prev, and root.next are used
 	len  int         // current list Length excluding (this) sentinel element
+
+	// Now enables tracking EntryMeta for the elements inserted afterwards
+	// and provides the current time for it, optional
+	Now func() time.Time
 }
 
 // Init initializes or clears list l.
@@ -61,6 +87,17 @@ func NewList[K comparable, V any]() *LruList[K, V] { return new(LruList[K, V]).I
 // The complexity is O(1).
 func (l *LruList[K, V]) Length() int { return l.len }
 
+// Contains reports whether e is an element of list l.
+func (l *LruList[K, V]) Contains(e *Entry[K, V]) bool { return e.list == l }
+
+// Front returns the first element of list l or nil if the list is empty.
+func (l *LruList[K, V]) Front() *Entry[K, V] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.next
+}
+
 // Back returns the last element of list l or nil if the list is empty.
 func (l *LruList[K, V]) Back() *Entry[K, V] {
 	if l.len == 0 {
@@ -84,11 +121,15 @@ func (l *LruList[K, V]) insert(e, at *Entry[K, V]) *Entry[K, V] {
 	e.next.prev = e
 	e.list = l
 	l.len++
+	if l.Now != nil {
+		now := l.Now()
+		e.Meta = &EntryMeta{AddedAt: now, AccessedAt: now}
+	}
 	return e
 }
 
 // insertValue is a convenience wrapper for insert(&Entry{Value: v, ExpiresAt: ExpiresAt}, at).
-func (l *LruList[K, V]) insertValue(k K, v V, expiresAt time.Time, at *Entry[K, V]) *Entry[K, V] {
+func (l *LruList[K, V]) insertValue(k K, v V, expiresAt int64, at *Entry[K, V]) *Entry[K, V] {
 	return l.insert(&Entry[K, V]{Value: v, Key: k, ExpiresAt: expiresAt}, at)
 }
 
@@ -121,20 +162,45 @@ func (l *LruList[K, V]) move(e, at *Entry[K, V]) {
 // PushFront inserts a new element e with value v at the front of list l and returns e.
 func (l *LruList[K, V]) PushFront(k K, v V) *Entry[K, V] {
 	l.lazyInit()
-	return l.insertValue(k, v, time.Time{}, &l.root)
+	return l.insertValue(k, v, 0, &l.root)
+}
+
+// PushBack inserts a new element e with value v at the back of list l and returns e.
+func (l *LruList[K, V]) PushBack(k K, v V) *Entry[K, V] {
+	l.lazyInit()
+	return l.insertValue(k, v, 0, l.root.prev)
+}
+
+// InsertAfter inserts a new element e with value v immediately after mark and returns e.
+// The mark must be an element of l.
+func (l *LruList[K, V]) InsertAfter(k K, v V, mark *Entry[K, V]) *Entry[K, V] {
+	return l.insertValue(k, v, 0, mark)
 }
 
 // PushFrontExpirable inserts a new expirable element e with Value v at the front of list l and returns e.
-func (l *LruList[K, V]) PushFrontExpirable(k K, v V, expiresAt time.Time) *Entry[K, V] {
+func (l *LruList[K, V]) PushFrontExpirable(k K, v V, expiresAt int64) *Entry[K, V] {
 	l.lazyInit()
 	return l.insertValue(k, v, expiresAt, &l.root)
 }
 
+// Touch records a use of element e in its EntryMeta, as MoveToFront does,
+// without moving it.
+func (l *LruList[K, V]) Touch(e *Entry[K, V]) {
+	if e.Meta != nil && l.Now != nil {
+		e.Meta.AccessedAt = l.Now()
+		e.Meta.Hits++
+	}
+}
+
 // MoveToFront moves element e to the front of list l.
 // If e is not an element of l, the list is not modified.
 // The element must not be nil.
 func (l *LruList[K, V]) MoveToFront(e *Entry[K, V]) {
-	if e.list != l || l.root.next == e {
+	if e.list != l {
+		return
+	}
+	l.Touch(e)
+	if l.root.next == e {
 		return
 	}
 	// see comment in List.Remove about initialization of l
@@ -0,0 +1,265 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package internal
+
+import "hash/maphash"
+
+// OpenLRU is a non-thread safe fixed size LRU cache backed by an open
+// addressing hash table with linear probing, indexing a preallocated slab
+// of entries linked by position. Compared to a Go map and a pointer based
+// list it avoids per-entry allocations and keeps lookups cache friendly.
+type OpenLRU[K comparable, V any] struct {
+	hash    func(K) uint64
+	slots   []int32 // index into entries plus one, 0 if empty
+	mask    uint64
+	entries []openEntry[K, V]
+	free    []int32 // unused indexes into entries
+	head    int32   // most recently used entry, -1 if empty
+	tail    int32   // least recently used entry, -1 if empty
+	onEvict func(key K, value V)
+}
+
+type openEntry[K comparable, V any] struct {
+	key        K
+	value      V
+	hash       uint64
+	prev, next int32 // towards head and tail, -1 at the ends
+}
+
+// NewOpenLRU returns an OpenLRU holding up to size entries, using hash to
+// place keys. size must be positive.
+func NewOpenLRU[K comparable, V any](size int, hash func(K) uint64, onEvict func(key K, value V)) *OpenLRU[K, V] {
+	// keep the load factor at or below 1/2 so probe sequences stay short
+	n := 2
+	for n < 2*size {
+		n <<= 1
+	}
+	c := &OpenLRU[K, V]{
+		hash:    hash,
+		slots:   make([]int32, n),
+		mask:    uint64(n - 1),
+		entries: make([]openEntry[K, V], size),
+		free:    make([]int32, size),
+		head:    -1,
+		tail:    -1,
+		onEvict: onEvict,
+	}
+	for i := range c.free {
+		c.free[i] = int32(size - 1 - i)
+	}
+	return c
+}
+
+// find returns the slot holding key, or the empty slot ending its probe
+// sequence and false.
+func (c *OpenLRU[K, V]) find(key K, h uint64) (slot uint64, ok bool) {
+	for slot = h & c.mask; ; slot = (slot + 1) & c.mask {
+		idx := c.slots[slot]
+		if idx == 0 {
+			return slot, false
+		}
+		if e := &c.entries[idx-1]; e.hash == h && e.key == key {
+			return slot, true
+		}
+	}
+}
+
+// Add adds a value to the cache, making it the most recently used one.
+// Returns true if an eviction occurred.
+func (c *OpenLRU[K, V]) Add(key K, value V) (evicted bool) {
+	h := c.hash(key)
+	slot, ok := c.find(key, h)
+	if ok {
+		idx := c.slots[slot] - 1
+		c.entries[idx].value = value
+		c.moveToFront(idx)
+		return false
+	}
+
+	if len(c.free) == 0 {
+		c.removeIndex(c.tail, true)
+		evicted = true
+		// the probe sequence may have shifted
+		slot, _ = c.find(key, h)
+	}
+	idx := c.free[len(c.free)-1]
+	c.free = c.free[:len(c.free)-1]
+	c.entries[idx] = openEntry[K, V]{key: key, value: value, hash: h, prev: -1, next: c.head}
+	if c.head >= 0 {
+		c.entries[c.head].prev = idx
+	}
+	c.head = idx
+	if c.tail < 0 {
+		c.tail = idx
+	}
+	c.slots[slot] = idx + 1
+	return evicted
+}
+
+// Get looks up a key's value, making it the most recently used one.
+func (c *OpenLRU[K, V]) Get(key K) (value V, ok bool) {
+	slot, ok := c.find(key, c.hash(key))
+	if !ok {
+		return
+	}
+	idx := c.slots[slot] - 1
+	c.moveToFront(idx)
+	return c.entries[idx].value, true
+}
+
+// Peek looks up a key's value without updating its recent-ness.
+func (c *OpenLRU[K, V]) Peek(key K) (value V, ok bool) {
+	slot, ok := c.find(key, c.hash(key))
+	if !ok {
+		return
+	}
+	return c.entries[c.slots[slot]-1].value, true
+}
+
+// Remove removes the provided key, returning if it was contained.
+func (c *OpenLRU[K, V]) Remove(key K) bool {
+	slot, ok := c.find(key, c.hash(key))
+	if !ok {
+		return false
+	}
+	c.removeIndex(c.slots[slot]-1, true)
+	return true
+}
+
+// RemoveOldest removes the least recently used entry.
+func (c *OpenLRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	if c.tail < 0 {
+		return
+	}
+	e := c.entries[c.tail]
+	c.removeIndex(c.tail, true)
+	return e.key, e.value, true
+}
+
+// GetOldest returns the least recently used entry.
+func (c *OpenLRU[K, V]) GetOldest() (key K, value V, ok bool) {
+	if c.tail < 0 {
+		return
+	}
+	e := &c.entries[c.tail]
+	return e.key, e.value, true
+}
+
+// Keys returns the keys from oldest to newest.
+func (c *OpenLRU[K, V]) Keys() []K {
+	keys := make([]K, 0, c.Len())
+	for idx := c.tail; idx >= 0; idx = c.entries[idx].prev {
+		keys = append(keys, c.entries[idx].key)
+	}
+	return keys
+}
+
+// Values returns the values from oldest to newest.
+func (c *OpenLRU[K, V]) Values() []V {
+	values := make([]V, 0, c.Len())
+	for idx := c.tail; idx >= 0; idx = c.entries[idx].prev {
+		values = append(values, c.entries[idx].value)
+	}
+	return values
+}
+
+// Len returns the number of entries.
+func (c *OpenLRU[K, V]) Len() int {
+	return len(c.entries) - len(c.free)
+}
+
+// Cap returns the capacity.
+func (c *OpenLRU[K, V]) Cap() int {
+	return len(c.entries)
+}
+
+// Purge removes all entries, calling onEvict for each of them.
+func (c *OpenLRU[K, V]) Purge() {
+	for c.tail >= 0 {
+		c.removeIndex(c.tail, false)
+	}
+}
+
+// moveToFront makes the entry at idx the most recently used one.
+func (c *OpenLRU[K, V]) moveToFront(idx int32) {
+	if idx == c.head {
+		return
+	}
+	c.unlink(idx)
+	e := &c.entries[idx]
+	e.prev, e.next = -1, c.head
+	c.entries[c.head].prev = idx
+	c.head = idx
+}
+
+// unlink removes the entry at idx from the recency list.
+func (c *OpenLRU[K, V]) unlink(idx int32) {
+	e := &c.entries[idx]
+	if e.prev >= 0 {
+		c.entries[e.prev].next = e.next
+	} else {
+		c.head = e.next
+	}
+	if e.next >= 0 {
+		c.entries[e.next].prev = e.prev
+	} else {
+		c.tail = e.prev
+	}
+}
+
+// removeIndex removes the entry at idx, optionally keeping the hash table
+// intact when the whole cache is being cleared anyway.
+func (c *OpenLRU[K, V]) removeIndex(idx int32, deleteSlot bool) {
+	e := c.entries[idx]
+	c.unlink(idx)
+	if deleteSlot {
+		slot, _ := c.find(e.key, e.hash)
+		c.deleteSlot(slot)
+	}
+	c.entries[idx] = openEntry[K, V]{}
+	c.free = append(c.free, idx)
+	if c.tail < 0 && !deleteSlot {
+		for i := range c.slots {
+			c.slots[i] = 0
+		}
+	}
+	if c.onEvict != nil {
+		c.onEvict(e.key, e.value)
+	}
+}
+
+// deleteSlot empties slot, shifting back the entries after it in the
+// probe sequence so that no tombstones are needed.
+func (c *OpenLRU[K, V]) deleteSlot(slot uint64) {
+	i := slot
+	for j := (i + 1) & c.mask; c.slots[j] != 0; j = (j + 1) & c.mask {
+		home := c.entries[c.slots[j]-1].hash & c.mask
+		// the entry at j can't move before its home slot
+		if i <= j {
+			if i < home && home <= j {
+				continue
+			}
+		} else if i < home || home <= j {
+			continue
+		}
+		c.slots[i] = c.slots[j]
+		i = j
+	}
+	c.slots[i] = 0
+}
+
+// HashInt64 spreads the bits of an integer key over the whole hash.
+func HashInt64(key int64) uint64 {
+	return Mix64(uint64(key))
+}
+
+var stringSeed = maphash.MakeSeed()
+
+// HashString hashes a string key with a per-process random seed.
+func HashString(key string) uint64 {
+	var h maphash.Hash
+	h.SetSeed(stringSeed)
+	h.WriteString(key)
+	return h.Sum64()
+}
@@ -0,0 +1,135 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package internal
+
+import "math"
+
+// ReuseMaxKeys is the number of sampled keys a ReuseDistance tracks. Reuse
+// distances beyond it, once scaled by the sampling rate, count as misses.
+const ReuseMaxKeys = 4096
+
+// ReuseDistance estimates the distribution of the reuse distances of a
+// stream of hashes, the number of distinct hashes seen between two
+// occurrences of the same one, which is the smallest LRU cache that would
+// hit. Only hashes falling in a fixed 1/every of the hash space are tracked,
+// and their distances are scaled by every, so that memory and time stay
+// bounded whatever the number of distinct hashes.
+type ReuseDistance struct {
+	every     int
+	threshold uint64 // hashes mixed above it are not sampled
+
+	// the tracked hashes are ordered by their last occurrence, each at a
+	// position of a Fenwick tree counting the positions in use
+	last   map[uint64]int
+	hashes []uint64
+	tree   []int32
+	next   int
+
+	hist  [ReuseMaxKeys]uint64 // counts of the unscaled distances
+	total uint64               // sampled occurrences, including first ones
+}
+
+// NewReuseDistance returns an estimator sampling one in every hashes.
+func NewReuseDistance(every int) *ReuseDistance {
+	return &ReuseDistance{
+		every:     every,
+		threshold: math.MaxUint64 / uint64(every),
+		last:      make(map[uint64]int),
+		hashes:    make([]uint64, 2*ReuseMaxKeys),
+		tree:      make([]int32, 2*ReuseMaxKeys+1),
+	}
+}
+
+// Add records an occurrence of hash. Hashes are remixed, so weak hash
+// functions such as the identity of an integer key are fine.
+func (r *ReuseDistance) Add(hash uint64) {
+	x := Mix64(hash)
+	if x > r.threshold {
+		return
+	}
+	r.total++
+	if pos, ok := r.last[x]; ok {
+		r.hist[r.sum(r.next-1)-r.sum(pos)]++
+		r.update(pos, -1)
+	} else if len(r.last) == ReuseMaxKeys {
+		oldest := r.search(1)
+		delete(r.last, r.hashes[oldest])
+		r.update(oldest, -1)
+	}
+	if r.next == len(r.hashes) {
+		r.compact()
+	}
+	r.last[x] = r.next
+	r.hashes[r.next] = x
+	r.update(r.next, 1)
+	r.next++
+}
+
+// Percentile returns the size of the smallest LRU cache in which a fraction
+// p of the sampled occurrences would hit, one more than their reuse
+// distance, scaled by the sampling rate. It returns false if first
+// occurrences and distances too long to track make up more than 1-p of them.
+func (r *ReuseDistance) Percentile(p float64) (size int, ok bool) {
+	if r.total == 0 {
+		return 0, false
+	}
+	need := uint64(math.Ceil(p * float64(r.total)))
+	var n uint64
+	for d, c := range r.hist {
+		if n += c; n >= need {
+			return (d + 1) * r.every, true
+		}
+	}
+	return 0, false
+}
+
+// compact moves the tracked hashes to the first positions, keeping their
+// order.
+func (r *ReuseDistance) compact() {
+	n := 0
+	for pos := 0; pos < r.next; pos++ {
+		if r.sum(pos)-r.sum(pos-1) == 1 {
+			x := r.hashes[pos]
+			r.hashes[n] = x
+			r.last[x] = n
+			n++
+		}
+	}
+	for i := range r.tree {
+		r.tree[i] = 0
+	}
+	for pos := 0; pos < n; pos++ {
+		r.update(pos, 1)
+	}
+	r.next = n
+}
+
+// update adds delta to the count of pos.
+func (r *ReuseDistance) update(pos int, delta int32) {
+	for i := pos + 1; i < len(r.tree); i += i & -i {
+		r.tree[i] += delta
+	}
+}
+
+// sum returns the number of positions in use up to pos included.
+func (r *ReuseDistance) sum(pos int) int {
+	var s int32
+	for i := pos + 1; i > 0; i -= i & -i {
+		s += r.tree[i]
+	}
+	return int(s)
+}
+
+// search returns the position of the k-th position in use.
+func (r *ReuseDistance) search(k int) int {
+	pos := 0
+	rem := int32(k)
+	for step := len(r.tree) / 2; step > 0; step /= 2 {
+		if pos+step < len(r.tree) && r.tree[pos+step] < rem {
+			pos += step
+			rem -= r.tree[pos]
+		}
+	}
+	return pos
+}
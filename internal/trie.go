@@ -0,0 +1,97 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package internal
+
+import "sort"
+
+// Trie is a set of strings supporting lookups by prefix.
+type Trie struct {
+	root trieNode
+}
+
+// trieNode holds the strings sharing the prefix leading to it, with its
+// children sorted by label so that walks are in lexicographic order.
+type trieNode struct {
+	labels   []byte
+	children []*trieNode
+	terminal bool // the prefix leading to the node is in the set
+}
+
+// child returns the position of the child labelled b, and if it exists.
+func (n *trieNode) child(b byte) (int, bool) {
+	i := sort.Search(len(n.labels), func(i int) bool { return n.labels[i] >= b })
+	return i, i < len(n.labels) && n.labels[i] == b
+}
+
+// Insert adds s to the set.
+func (t *Trie) Insert(s string) {
+	n := &t.root
+	for i := 0; i < len(s); i++ {
+		j, ok := n.child(s[i])
+		if !ok {
+			n.labels = append(n.labels, 0)
+			copy(n.labels[j+1:], n.labels[j:])
+			n.labels[j] = s[i]
+			n.children = append(n.children, nil)
+			copy(n.children[j+1:], n.children[j:])
+			n.children[j] = &trieNode{}
+		}
+		n = n.children[j]
+	}
+	n.terminal = true
+}
+
+// Delete removes s from the set, pruning the nodes left empty.
+func (t *Trie) Delete(s string) {
+	t.root.delete(s)
+}
+
+// delete removes s from the strings below n, and reports whether n is left
+// empty.
+func (n *trieNode) delete(s string) bool {
+	if len(s) == 0 {
+		n.terminal = false
+	} else if j, ok := n.child(s[0]); ok && n.children[j].delete(s[1:]) {
+		n.labels = append(n.labels[:j], n.labels[j+1:]...)
+		copy(n.children[j:], n.children[j+1:])
+		n.children[len(n.children)-1] = nil
+		n.children = n.children[:len(n.children)-1]
+	}
+	return !n.terminal && len(n.children) == 0
+}
+
+// WithPrefix returns the strings starting with prefix in lexicographic
+// order, at most limit of them unless limit is not positive.
+func (t *Trie) WithPrefix(prefix string, limit int) []string {
+	n := &t.root
+	for i := 0; i < len(prefix); i++ {
+		j, ok := n.child(prefix[i])
+		if !ok {
+			return nil
+		}
+		n = n.children[j]
+	}
+	var out []string
+	buf := []byte(prefix)
+	var walk func(n *trieNode) bool
+	walk = func(n *trieNode) bool {
+		if n.terminal {
+			out = append(out, string(buf))
+			if len(out) == limit {
+				return false
+			}
+		}
+		for i, c := range n.children {
+			buf = append(buf, n.labels[i])
+			more := walk(c)
+			buf = buf[:len(buf)-1]
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+	walk(n)
+	return out
+}
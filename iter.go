@@ -0,0 +1,14 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build go1.23
+
+package lru
+
+import "iter"
+
+// All returns an iterator over the entries from oldest to newest, with the
+// semantics of Range.
+func (c *Cache[K, V]) All() iter.Seq2[K, V] {
+	return c.Range
+}
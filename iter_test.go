@@ -0,0 +1,34 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build go1.23
+
+package lru
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCacheAll(t *testing.T) {
+	l, err := New[int, int](4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i*10)
+	}
+
+	// the cache is not locked while iterating
+	var keys []int
+	for k, v := range l.All() {
+		if v != k*10 {
+			t.Fatalf("bad value for %d: %d", k, v)
+		}
+		keys = append(keys, k)
+		l.Remove(k + 1)
+	}
+	if !reflect.DeepEqual(keys, []int{0, 2}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Op identifies a cache operation reported to a LatencyObserver.
+type Op int
+
+const (
+	// OpGet is a call to Get.
+	OpGet Op = iota
+	// OpAdd is a call to Add.
+	OpAdd
+)
+
+// String returns the lowercase name of the operation, suitable as a metric
+// label.
+func (op Op) String() string {
+	switch op {
+	case OpGet:
+		return "get"
+	case OpAdd:
+		return "add"
+	}
+	return "unknown"
+}
+
+// LatencyObserver receives the duration of a sampled operation, such as the
+// Observe method of a histogram labelled by op.
+type LatencyObserver func(op Op, d time.Duration)
+
+// latencySampler times one in every calls of each operation.
+type latencySampler struct {
+	counts  [2]uint64 // first for 64-bit alignment of the atomic counters
+	every   uint64
+	observe LatencyObserver
+}
+
+// WithLatencyObserver reports the latency of one in every calls to Get and
+// Add to observe, measured in wall time from the call until it returns. It
+// includes the time spent waiting for the lock, which is what grows under
+// contention, and for Add the evict callback. Unsampled calls only pay for
+// an atomic increment. observe is called outside of the lock.
+func WithLatencyObserver[K comparable, V any](every int, observe LatencyObserver) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if every <= 0 {
+			return errors.New("must provide a positive sampling interval")
+		}
+		if observe == nil {
+			return errors.New("must provide a latency observer")
+		}
+		c.latency = &latencySampler{every: uint64(every), observe: observe}
+		return nil
+	}
+}
+
+// sample reports whether this call of op should be timed.
+func (s *latencySampler) sample(op Op) bool {
+	return atomic.AddUint64(&s.counts[op], 1)%s.every == 0
+}
+
+// done reports the latency of a sampled call of op started at start.
+func (s *latencySampler) done(op Op, start time.Time) {
+	s.observe(op, time.Since(start))
+}
@@ -0,0 +1,40 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyObserver(t *testing.T) {
+	counts := make(map[Op]int)
+	l, err := NewWithOpts(8, WithLatencyObserver[int, int](3, func(op Op, d time.Duration) {
+		if d < 0 {
+			t.Fatalf("negative latency for %v: %v", op, d)
+		}
+		counts[op]++
+	}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 9; i++ {
+		l.Add(i, i)
+	}
+	for i := 0; i < 6; i++ {
+		l.Get(i)
+	}
+	l.Peek(0)
+	if counts[OpAdd] != 3 || counts[OpGet] != 2 || len(counts) != 2 {
+		t.Fatalf("bad: %v", counts)
+	}
+
+	if _, err := NewWithOpts(8, WithLatencyObserver[int, int](0, func(Op, time.Duration) {})); err == nil {
+		t.Fatalf("should reject a zero sampling interval")
+	}
+	if _, err := NewWithOpts(8, WithLatencyObserver[int, int](1, nil)); err == nil {
+		t.Fatalf("should reject a nil observer")
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import "github.com/hashicorp/golang-lru/v2/simplelru"
+
+// lifecycleCallbacks are called as entries are added, updated and hit.
+type lifecycleCallbacks[K comparable, V any] struct {
+	onAdd    func(key K, value V)
+	onUpdate func(key K, value V)
+	onHit    func(key K, value V)
+
+	// updated holds the keys whose value was just replaced, until they are
+	// reported to onUpdate
+	updated map[K]struct{}
+
+	// pending holds the additions and updates made while the lock is held,
+	// until unlockAdded reports them
+	pending []lifecycleEvent[K, V]
+}
+
+// lifecycleEvent is an addition, or an update if update is set, waiting to
+// be reported.
+type lifecycleEvent[K comparable, V any] struct {
+	key    K
+	value  V
+	update bool
+}
+
+// WithAddCallback sets a callback invoked whenever a key that is not
+// cached is added, by any of the Add methods or a prefetch. It is called
+// after the lock is released, so it may use the cache.
+func WithAddCallback[K comparable, V any](onAdd func(key K, value V)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.initLifecycle().onAdd = onAdd
+		return nil
+	}
+}
+
+// WithUpdateCallback sets a callback invoked with the new value whenever an
+// addition replaces the value of a cached key. It is called after the lock
+// is released, so it may use the cache.
+func WithUpdateCallback[K comparable, V any](onUpdate func(key K, value V)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.initLifecycle().onUpdate = onUpdate
+		return nil
+	}
+}
+
+// WithHitCallback sets a callback invoked for every key found by Get and
+// GetMany, after the lock is released, so it may use the cache.
+func WithHitCallback[K comparable, V any](onHit func(key K, value V)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.initLifecycle().onHit = onHit
+		return nil
+	}
+}
+
+func (c *Cache[K, V]) initLifecycle() *lifecycleCallbacks[K, V] {
+	if c.lifecycle == nil {
+		c.lifecycle = &lifecycleCallbacks[K, V]{updated: make(map[K]struct{})}
+	}
+	return c.lifecycle
+}
+
+// onReplaced is passed values replaced in the underlying LRU.
+func (c *Cache[K, V]) onReplaced(key K, old, new V) {
+	if c.lifecycle != nil && c.lifecycle.onUpdate != nil {
+		c.lifecycle.updated[key] = struct{}{}
+	}
+	if c.autoClose != nil {
+		c.autoClose.CloseReplaced(key, old, new)
+	}
+}
+
+// replaceCallback returns the replace callback of the underlying LRU, if
+// any is needed.
+func (c *Cache[K, V]) replaceCallback() []simplelru.Option[K, V] {
+	if c.autoClose == nil && (c.lifecycle == nil || c.lifecycle.onUpdate == nil) {
+		return nil
+	}
+	return []simplelru.Option[K, V]{simplelru.WithReplaceCallback(c.onReplaced)}
+}
+
+// reportAdded queues the add or update callback for a key that was just
+// added or updated to value, for unlockAdded. Has to be called with lock!
+func (c *Cache[K, V]) reportAdded(key K, value V) {
+	l := c.lifecycle
+	if l == nil {
+		return
+	}
+	if _, ok := l.updated[key]; ok {
+		delete(l.updated, key)
+		l.pending = append(l.pending, lifecycleEvent[K, V]{key: key, value: value, update: true})
+		return
+	}
+	if l.onAdd != nil {
+		l.pending = append(l.pending, lifecycleEvent[K, V]{key: key, value: value})
+	}
+}
+
+// unlockAdded releases the lock, then calls the add and update callbacks
+// queued by reportAdded while it was held.
+func (c *Cache[K, V]) unlockAdded() {
+	l := c.lifecycle
+	if l == nil || len(l.pending) == 0 {
+		c.lock.Unlock()
+		return
+	}
+	pending := l.pending
+	l.pending = nil
+	c.lock.Unlock()
+	for _, e := range pending {
+		if e.update {
+			l.onUpdate(e.key, c.decodeStored(e.value))
+		} else {
+			l.onAdd(e.key, c.decodeStored(e.value))
+		}
+	}
+}
+
+// reportHit calls the hit callback for a key that was found.
+func (c *Cache[K, V]) reportHit(key K, value V) {
+	if c.lifecycle != nil && c.lifecycle.onHit != nil {
+		c.lifecycle.onHit(key, value)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+func TestLifecycleCallbacks(t *testing.T) {
+	var events []string
+	event := func(name string) func(k, v int) {
+		return func(k, v int) {
+			events = append(events, fmt.Sprintf("%s:%d=%d", name, k, v))
+		}
+	}
+	l, err := NewWithOpts(2,
+		WithAddCallback(event("add")),
+		WithUpdateCallback(event("update")),
+		WithHitCallback(event("hit")))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(1, 2)
+	l.ContainsOrAdd(1, 3)
+	l.AddMany([]simplelru.Entry[int, int]{{Key: 2, Value: 2}, {Key: 1, Value: 4}})
+	l.Get(1)
+	l.Get(3)
+	l.GetMany([]int{2, 3})
+	want := []string{"add:1=1", "update:1=2", "add:2=2", "update:1=4", "hit:1=4", "hit:2=2"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("bad events: %v", events)
+	}
+}
+
+func TestLifecycleCallbacksReentrant(t *testing.T) {
+	var l *Cache[int, int]
+	var events []string
+	l, err := NewWithOpts(2,
+		WithAddCallback(func(k, v int) {
+			// the lock is released, so the cache may be used
+			_, ok := l.Peek(k)
+			events = append(events, fmt.Sprintf("add:%d=%v", k, ok))
+		}),
+		WithUpdateCallback(func(k, v int) {
+			events = append(events, fmt.Sprintf("update:%d=%d", k, l.Len()))
+		}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.AddWithMeta(1, 2, nil)
+	l.AddMany([]simplelru.Entry[int, int]{{Key: 2, Value: 2}, {Key: 3, Value: 3}})
+	want := []string{"add:1=true", "update:1=1", "add:2=true", "add:3=true"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("bad events: %v", events)
+	}
+}
@@ -4,14 +4,21 @@
 package lru
 
 import (
+	"context"
 	"sync"
+	"time"
 
+	"github.com/hashicorp/golang-lru/v2/internal"
 	"github.com/hashicorp/golang-lru/v2/simplelru"
 )
 
 const (
 	// DefaultEvictedBufferSize defines the default buffer size to store evicted key/val
 	DefaultEvictedBufferSize = 16
+
+	// copyChunkSize is the number of entries Keys and Values copy before
+	// briefly releasing the lock to let writers in.
+	copyChunkSize = 4096
 )
 
 // Cache is a thread-safe fixed size LRU cache.
@@ -21,6 +28,78 @@ type Cache[K comparable, V any] struct {
 	evictedVals []V
 	onEvictedCB func(k K, v V)
 	lock        sync.RWMutex
+
+	// evictedMetas buffers the metadata of the evicted entries alongside
+	// evictedKeys for onEvictedMetaCB, optional
+	evictedMetas    []any
+	onEvictedMetaCB func(k K, v V, meta any)
+
+	// frozen rejects additions and defers Resize until Unfreeze
+	frozen      bool
+	pendingSize int
+
+	// lruOpts are passed on to the underlying LRU by NewWithOpts
+	lruOpts []simplelru.Option[K, V]
+
+	// minResidency is passed on to the underlying LRU with the clock
+	minResidency time.Duration
+
+	// distinct estimates the number of distinct keys looked up, optional
+	distinct *internal.HyperLogLog
+	hashKey  func(K) uint64
+
+	// reuse estimates the reuse distances of the keys looked up, optional
+	reuse *internal.ReuseDistance
+
+	// prefetcher is run asynchronously on Get misses, optional
+	prefetcher Prefetcher[K, V]
+
+	// deterministic runs the work of background goroutines synchronously
+	deterministic bool
+
+	// errs holds negative results added by AddError, allocated on first use
+	errs  *simplelru.LRU[K, cachedError]
+	clock Clock
+
+	// codec compresses stored values, optional
+	codec *valueCodec[V]
+
+	// autoClose closes values leaving the cache, optional
+	autoClose *internal.AutoCloser[K]
+
+	// onPanic receives panics recovered from user callbacks, optional
+	onPanic func(recovered any)
+
+	// execute schedules the evict callback, optional
+	execute func(fn func())
+
+	// pool bounds the entries of this and other caches together, optional
+	pool *CapacityPool
+
+	// latency times a sample of Get and Add calls, optional
+	latency *latencySampler
+
+	// lifecycle calls back additions, updates and hits, optional
+	lifecycle *lifecycleCallbacks[K, V]
+
+	// alarm calls back windows of lookups with a low hit ratio, optional
+	alarm *hitRatioAlarm
+
+	// validation rejects additions of invalid entries, optional
+	validation *validation[K, V]
+
+	// demotion calls back entries reaching an age, optional
+	demotion *demotionQueue[K, V]
+
+	// reverse indexes the keys by the value keys of their values, optional
+	reverse *reverseIndex[K, V]
+
+	// stats counts the operations of the cache
+	stats *simplelru.StatsCounter
+
+	// computing holds the GetOrCompute calls in progress
+	computing   map[K]*computeCall[V]
+	computeLock sync.Mutex
 }
 
 // New creates an LRU of the given size.
@@ -31,21 +110,15 @@ func New[K comparable, V any](size int) (*Cache[K, V], error) {
 // NewWithEvict constructs a fixed size cache with the given eviction
 // callback.
 func NewWithEvict[K comparable, V any](size int, onEvicted func(key K, value V)) (c *Cache[K, V], err error) {
-	// create a cache with default settings
-	c = &Cache[K, V]{
-		onEvictedCB: onEvicted,
-	}
-	if onEvicted != nil {
-		c.initEvictBuffers()
-		onEvicted = c.onEvicted
-	}
-	c.lru, err = simplelru.NewLRU(size, onEvicted)
-	return
+	return NewWithOpts(size, WithEvictCallback(onEvicted))
 }
 
 func (c *Cache[K, V]) initEvictBuffers() {
 	c.evictedKeys = make([]K, 0, DefaultEvictedBufferSize)
 	c.evictedVals = make([]V, 0, DefaultEvictedBufferSize)
+	if c.onEvictedMetaCB != nil {
+		c.evictedMetas = make([]any, 0, DefaultEvictedBufferSize)
+	}
 }
 
 // onEvicted save evicted key/val and sent in externally registered callback
@@ -55,50 +128,518 @@ func (c *Cache[K, V]) onEvicted(k K, v V) {
 	c.evictedVals = append(c.evictedVals, v)
 }
 
+// onEvictedMeta is onEvicted for caches with an evict meta callback, also
+// saving the metadata of the entry.
+func (c *Cache[K, V]) onEvictedMeta(k K, v V, meta any) {
+	c.onEvicted(k, v)
+	c.evictedMetas = append(c.evictedMetas, meta)
+}
+
+// takeEvicted returns the saved evicted entries, replacing the buffers. Has
+// to be called with lock!
+func (c *Cache[K, V]) takeEvicted() (ks []K, vs []V, ms []any) {
+	ks, vs, ms = c.evictedKeys, c.evictedVals, c.evictedMetas
+	c.initEvictBuffers()
+	return ks, vs, ms
+}
+
+// takeFirstEvicted returns the single saved evicted entry, truncating the
+// buffers for reuse. Has to be called with lock!
+func (c *Cache[K, V]) takeFirstEvicted() (k K, v V, meta any) {
+	k, v = c.evictedKeys[0], c.evictedVals[0]
+	c.evictedKeys, c.evictedVals = c.evictedKeys[:0], c.evictedVals[:0]
+	if c.evictedMetas != nil {
+		meta = c.evictedMetas[0]
+		c.evictedMetas = c.evictedMetas[:0]
+	}
+	return k, v, meta
+}
+
+// notifyEvicted invokes the evict callbacks for an entry taken from the
+// buffers, outside of critical section.
+func (c *Cache[K, V]) notifyEvicted(k K, v V, meta any) {
+	c.onEvictedCB(k, v)
+	if c.onEvictedMetaCB != nil {
+		c.onEvictedMetaCB(k, v, meta)
+	}
+}
+
+// notifyAllEvicted invokes the evict callbacks for the entries taken with
+// takeEvicted, outside of critical section.
+func (c *Cache[K, V]) notifyAllEvicted(ks []K, vs []V, ms []any) {
+	for i := 0; i < len(ks); i++ {
+		var meta any
+		if ms != nil {
+			meta = ms[i]
+		}
+		c.notifyEvicted(ks[i], vs[i], meta)
+	}
+}
+
+// added records the addition or update of key to value. Has to be called
+// with lock!
+func (c *Cache[K, V]) added(key K, value V) {
+	c.reindex(key, value)
+	c.trackDemotion(key)
+	c.reportAdded(key, value)
+}
+
+// add adds value under key with add, one of the addition methods of the
+// underlying LRU, and records it. Returns the number of entries it evicted,
+// several if WithMaxWeight is set. Has to be called with lock!
+func (c *Cache[K, V]) add(key K, value V, add func(key K, value V) bool) (evicted int) {
+	n := c.lru.Len()
+	if !c.lru.Contains(key) {
+		n++
+	}
+	if add(key, value) {
+		evicted = n - c.lru.Len()
+	}
+	c.added(key, value)
+	c.countAdd(evicted)
+	return evicted
+}
+
 // Purge is used to completely clear the cache.
 func (c *Cache[K, V]) Purge() {
 	var ks []K
 	var vs []V
+	var ms []any
 	c.lock.Lock()
 	c.lru.Purge()
+	if c.errs != nil {
+		c.errs.Purge()
+	}
 	if c.onEvictedCB != nil && len(c.evictedKeys) > 0 {
-		ks, vs = c.evictedKeys, c.evictedVals
-		c.initEvictBuffers()
+		ks, vs, ms = c.takeEvicted()
 	}
 	c.lock.Unlock()
 	// invoke callback outside of critical section
-	if c.onEvictedCB != nil {
-		for i := 0; i < len(ks); i++ {
-			c.onEvictedCB(ks[i], vs[i])
+	c.notifyAllEvicted(ks, vs, ms)
+}
+
+// PurgeChunked clears the cache like Purge, but removes copyChunkSize
+// entries at a time, releasing the lock in between so that other callers
+// don't stall on large caches. After each chunk, progress is called with the
+// number of entries removed so far and the number there were at the start,
+// if not nil. Purging stops early with ctx's error if ctx is done, leaving
+// the remaining entries in the cache. Entries added concurrently may be
+// removed as well.
+func (c *Cache[K, V]) PurgeChunked(ctx context.Context, progress func(removed, total int)) error {
+	total := c.Len()
+	removed := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var ks []K
+		var vs []V
+		var ms []any
+		c.lock.Lock()
+		n := 0
+		for ; n < copyChunkSize; n++ {
+			if _, _, ok := c.lru.RemoveOldest(); !ok {
+				break
+			}
+		}
+		done := c.lru.Len() == 0
+		if done {
+			// invalidate outstanding handles as Purge does
+			c.lru.Purge()
+			if c.errs != nil {
+				c.errs.Purge()
+			}
+		}
+		if c.onEvictedCB != nil && len(c.evictedKeys) > 0 {
+			ks, vs, ms = c.takeEvicted()
+		}
+		c.lock.Unlock()
+		// invoke callback outside of critical section
+		c.notifyAllEvicted(ks, vs, ms)
+		removed += n
+		if progress != nil {
+			progress(removed, total)
+		}
+		if done {
+			return nil
 		}
 	}
 }
 
 // Add adds a value to the cache. Returns true if an eviction occurred.
 func (c *Cache[K, V]) Add(key K, value V) (evicted bool) {
-	var k K
-	var v V
+	if c.rejects(key, value) {
+		return
+	}
+	if c.latency != nil && c.latency.sample(OpAdd) {
+		defer c.latency.done(OpAdd, time.Now())
+	}
+	if c.demotion != nil {
+		c.demote()
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	value = c.encode(value)
 	c.lock.Lock()
-	evicted = c.lru.Add(key, value)
+	if c.frozen {
+		c.lock.Unlock()
+		return false
+	}
+	c.replaceError(key)
+	evicted = c.add(key, value, c.lru.Add) > 0
 	if c.onEvictedCB != nil && evicted {
-		k, v = c.evictedKeys[0], c.evictedVals[0]
-		c.evictedKeys, c.evictedVals = c.evictedKeys[:0], c.evictedVals[:0]
+		ks, vs, ms = c.takeEvicted()
 	}
-	c.lock.Unlock()
+	c.unlockAdded()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	if c.pool != nil {
+		c.pool.enforce()
+	}
+	return
+}
+
+// AddWithContext adds a value to the cache like Add, but gives up with
+// ctx's error if the lock cannot be acquired before ctx is done, so that
+// callers with a deadline don't pile up behind a busy cache. The lock is
+// polled with a growing backoff of up to a millisecond, so under heavy
+// contention AddWithContext yields to callers of the other methods.
+func (c *Cache[K, V]) AddWithContext(ctx context.Context, key K, value V) (evicted bool, err error) {
+	if c.rejects(key, value) {
+		return false, nil
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	value = c.encode(value)
+	if err := c.lockContext(ctx); err != nil {
+		return false, err
+	}
+	if c.frozen {
+		c.lock.Unlock()
+		return false, nil
+	}
+	c.replaceError(key)
+	evicted = c.add(key, value, c.lru.Add) > 0
 	if c.onEvictedCB != nil && evicted {
-		c.onEvictedCB(k, v)
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.unlockAdded()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	if c.pool != nil {
+		c.pool.enforce()
+	}
+	return evicted, nil
+}
+
+// lockContext acquires the write lock unless ctx is done first.
+func (c *Cache[K, V]) lockContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.lock.TryLock() {
+		return nil
+	}
+	wait := 10 * time.Microsecond
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if c.lock.TryLock() {
+			return nil
+		}
+		if wait < time.Millisecond {
+			wait *= 2
+		}
+		timer.Reset(wait)
+	}
+}
+
+// AddEx adds a value to the cache like Add, and also returns the value it
+// replaced if the key was already cached, and the entry evicted to make room
+// if any, see simplelru.LRU.AddEx.
+func (c *Cache[K, V]) AddEx(key K, value V) (oldValue V, replaced bool, evictedKey K, evictedValue V, evicted bool) {
+	if c.rejects(key, value) {
+		return
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	value = c.encode(value)
+	c.lock.Lock()
+	if c.frozen {
+		c.lock.Unlock()
+		return
+	}
+	c.replaceError(key)
+	n := c.lru.Len()
+	oldValue, replaced, evictedKey, evictedValue, evicted = c.lru.AddEx(key, value)
+	if !replaced {
+		n++
+	}
+	c.added(key, value)
+	c.countAdd(n - c.lru.Len())
+	if c.onEvictedCB != nil && evicted {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.unlockAdded()
+	c.notifyAllEvicted(ks, vs, ms)
+	if c.pool != nil {
+		c.pool.enforce()
+	}
+	oldValue, _ = c.decode(oldValue, replaced)
+	evictedValue, _ = c.decode(evictedValue, evicted)
+	return
+}
+
+// AddMany adds the entries in order under a single lock acquisition, as if
+// by Add, evicting the overflow in one pass once all of them are added. The
+// evict callback is invoked in eviction order after the lock is released.
+// Returns the number of evictions.
+func (c *Cache[K, V]) AddMany(entries []simplelru.Entry[K, V]) (evicted int) {
+	entries = c.validEntries(entries)
+	if c.codec != nil {
+		encoded := make([]simplelru.Entry[K, V], len(entries))
+		for i, e := range entries {
+			encoded[i] = simplelru.Entry[K, V]{Key: e.Key, Value: c.codec.encode(e.Value)}
+		}
+		entries = encoded
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	c.lock.Lock()
+	if c.frozen {
+		c.lock.Unlock()
+		return 0
+	}
+	for _, e := range entries {
+		c.dropError(e.Key)
+	}
+	evicted = c.lru.AddMany(entries)
+	c.trimErrors(0)
+	c.stats.Add(len(entries))
+	c.stats.Evict(evicted)
+	for _, e := range entries {
+		c.added(e.Key, e.Value)
+	}
+	if c.onEvictedCB != nil && evicted > 0 {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.unlockAdded()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	if c.pool != nil {
+		c.pool.enforce()
+	}
+	return evicted
+}
+
+// AddAsOldest adds a value to the cache as its least recently used entry,
+// so that it does not displace recently used ones. If the key is already
+// contained, only its value is updated. Returns true if an eviction occurred.
+func (c *Cache[K, V]) AddAsOldest(key K, value V) (evicted bool) {
+	if c.rejects(key, value) {
+		return
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	value = c.encode(value)
+	c.lock.Lock()
+	if c.frozen {
+		c.lock.Unlock()
+		return false
+	}
+	c.replaceError(key)
+	evicted = c.add(key, value, c.lru.AddAsOldest) > 0
+	if c.onEvictedCB != nil && evicted {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.unlockAdded()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	if c.pool != nil {
+		c.pool.enforce()
+	}
+	return
+}
+
+// AddTransient adds a value expected to be used only once, such as data read
+// by a batch scan, at about the middle of the recency order instead of the
+// front, so that scans only ever turn over the colder half of the cache.
+// If the key is already contained, only its value is updated. Returns true
+// if an eviction occurred.
+func (c *Cache[K, V]) AddTransient(key K, value V) (evicted bool) {
+	if c.rejects(key, value) {
+		return
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	value = c.encode(value)
+	c.lock.Lock()
+	if c.frozen {
+		c.lock.Unlock()
+		return false
+	}
+	c.replaceError(key)
+	evicted = c.add(key, value, c.lru.AddTransient) > 0
+	if c.onEvictedCB != nil && evicted {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.unlockAdded()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	if c.pool != nil {
+		c.pool.enforce()
 	}
 	return
 }
 
 // Get looks up a key's value from the cache.
 func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	if c.latency != nil && c.latency.sample(OpGet) {
+		defer c.latency.done(OpGet, time.Now())
+	}
+	if c.demotion != nil {
+		c.demote()
+	}
+	if c.alarm != nil {
+		c.checkHitRatio()
+	}
 	c.lock.Lock()
 	value, ok = c.lru.Get(key)
+	c.stats.Lookup(ok)
+	c.observeLookup(key)
 	c.lock.Unlock()
+	if !ok && c.prefetcher != nil {
+		c.startPrefetch(key)
+	}
+	value, ok = c.decode(value, ok)
+	if ok {
+		c.reportHit(key, value)
+	}
 	return value, ok
 }
 
+// GetMany looks up the values of keys as if by Get in order, under a
+// single lock acquisition, and returns those found.
+func (c *Cache[K, V]) GetMany(keys []K) map[K]V {
+	if c.demotion != nil {
+		c.demote()
+	}
+	if c.alarm != nil {
+		c.checkHitRatio()
+	}
+	found := make(map[K]V, len(keys))
+	var missed []K
+	c.lock.Lock()
+	for _, key := range keys {
+		value, ok := c.lru.Get(key)
+		c.stats.Lookup(ok)
+		c.observeLookup(key)
+		if ok {
+			found[key] = value
+		} else if c.prefetcher != nil {
+			missed = append(missed, key)
+		}
+	}
+	c.lock.Unlock()
+	for _, key := range missed {
+		c.startPrefetch(key)
+	}
+	if c.codec != nil {
+		for key, value := range found {
+			found[key], _ = c.codec.decode(value)
+		}
+	}
+	for _, key := range keys {
+		if value, ok := found[key]; ok {
+			c.reportHit(key, value)
+		}
+	}
+	return found
+}
+
+// startPrefetch runs the prefetcher for a missed key in a new goroutine,
+// or right away in a deterministic cache.
+func (c *Cache[K, V]) startPrefetch(key K) {
+	if c.deterministic {
+		c.prefetch(key)
+		return
+	}
+	go c.prefetch(key)
+}
+
+// prefetch adds the entries the prefetcher returns for a missed key as the
+// oldest ones, leaving keys that are already cached untouched.
+func (c *Cache[K, V]) prefetch(key K) {
+	entries := c.validEntries(c.prefetcher(key))
+	if len(entries) == 0 {
+		return
+	}
+	for i := range entries {
+		entries[i].Value = c.encode(entries[i].Value)
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	c.lock.Lock()
+	if !c.frozen {
+		for _, ent := range entries {
+			if !c.lru.Contains(ent.Key) {
+				c.replaceError(ent.Key)
+				c.add(ent.Key, ent.Value, c.lru.AddAsOldest)
+			}
+		}
+	}
+	if c.onEvictedCB != nil && len(c.evictedKeys) > 0 {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.unlockAdded()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	if c.pool != nil {
+		c.pool.enforce()
+	}
+}
+
+// observeLookup feeds a looked up key to the estimators enabled by
+// WithDistinctKeys and WithReuseDistance. Has to be called with lock!
+func (c *Cache[K, V]) observeLookup(key K) {
+	if c.distinct == nil && c.reuse == nil {
+		return
+	}
+	h := c.hashKey(key)
+	if c.distinct != nil {
+		c.distinct.Add(h)
+	}
+	if c.reuse != nil {
+		c.reuse.Add(h)
+	}
+}
+
+// DistinctKeys returns the approximate number of distinct keys looked up
+// with Get or GetHandle since the cache was created, or 0 unless
+// WithDistinctKeys was given. Compared with Cap, it tells whether the
+// working set fits the cache.
+func (c *Cache[K, V]) DistinctKeys() uint64 {
+	if c.distinct == nil {
+		return 0
+	}
+	c.lock.RLock()
+	n := c.distinct.Count()
+	c.lock.RUnlock()
+	return n
+}
+
 // Contains checks if a key is in the cache, without updating the
 // recent-ness or deleting it for being stale.
 func (c *Cache[K, V]) Contains(key K) bool {
@@ -114,28 +655,39 @@ func (c *Cache[K, V]) Peek(key K) (value V, ok bool) {
 	c.lock.RLock()
 	value, ok = c.lru.Peek(key)
 	c.lock.RUnlock()
-	return value, ok
+	return c.decode(value, ok)
 }
 
 // ContainsOrAdd checks if a key is in the cache without updating the
 // recent-ness or deleting it for being stale, and if not, adds the value.
 // Returns whether found and whether an eviction occurred.
 func (c *Cache[K, V]) ContainsOrAdd(key K, value V) (ok, evicted bool) {
-	var k K
-	var v V
+	if c.rejects(key, value) {
+		return false, false
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	value = c.encode(value)
 	c.lock.Lock()
 	if c.lru.Contains(key) {
 		c.lock.Unlock()
 		return true, false
 	}
-	evicted = c.lru.Add(key, value)
-	if c.onEvictedCB != nil && evicted {
-		k, v = c.evictedKeys[0], c.evictedVals[0]
-		c.evictedKeys, c.evictedVals = c.evictedKeys[:0], c.evictedVals[:0]
+	if c.frozen {
+		c.lock.Unlock()
+		return false, false
 	}
-	c.lock.Unlock()
+	c.replaceError(key)
+	evicted = c.add(key, value, c.lru.Add) > 0
 	if c.onEvictedCB != nil && evicted {
-		c.onEvictedCB(k, v)
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.unlockAdded()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	if c.pool != nil {
+		c.pool.enforce()
 	}
 	return false, evicted
 }
@@ -144,22 +696,30 @@ func (c *Cache[K, V]) ContainsOrAdd(key K, value V) (ok, evicted bool) {
 // recent-ness or deleting it for being stale, and if not, adds the value.
 // Returns whether found and whether an eviction occurred.
 func (c *Cache[K, V]) PeekOrAdd(key K, value V) (previous V, ok, evicted bool) {
-	var k K
-	var v V
+	if c.rejects(key, value) {
+		return
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	value = c.encode(value)
 	c.lock.Lock()
 	previous, ok = c.lru.Peek(key)
-	if ok {
+	if ok || c.frozen {
 		c.lock.Unlock()
-		return previous, true, false
+		previous, _ = c.decode(previous, ok)
+		return previous, ok, false
 	}
-	evicted = c.lru.Add(key, value)
+	c.replaceError(key)
+	evicted = c.add(key, value, c.lru.Add) > 0
 	if c.onEvictedCB != nil && evicted {
-		k, v = c.evictedKeys[0], c.evictedVals[0]
-		c.evictedKeys, c.evictedVals = c.evictedKeys[:0], c.evictedVals[:0]
+		ks, vs, ms = c.takeEvicted()
 	}
-	c.lock.Unlock()
-	if c.onEvictedCB != nil && evicted {
-		c.onEvictedCB(k, v)
+	c.unlockAdded()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	if c.pool != nil {
+		c.pool.enforce()
 	}
 	return
 }
@@ -168,52 +728,199 @@ func (c *Cache[K, V]) PeekOrAdd(key K, value V) (previous V, ok, evicted bool) {
 func (c *Cache[K, V]) Remove(key K) (present bool) {
 	var k K
 	var v V
+	var m any
 	c.lock.Lock()
+	c.dropError(key)
 	present = c.lru.Remove(key)
 	if c.onEvictedCB != nil && present {
-		k, v = c.evictedKeys[0], c.evictedVals[0]
-		c.evictedKeys, c.evictedVals = c.evictedKeys[:0], c.evictedVals[:0]
+		k, v, m = c.takeFirstEvicted()
 	}
 	c.lock.Unlock()
 	if c.onEvictedCB != nil && present {
-		c.onEvictedCB(k, v)
+		c.notifyEvicted(k, v, m)
 	}
 	return
 }
 
-// Resize changes the cache size.
+// RemoveMany removes the provided keys from the cache under a single lock
+// acquisition, returning how many were present.
+func (c *Cache[K, V]) RemoveMany(keys []K) (removed int) {
+	var ks []K
+	var vs []V
+	var ms []any
+	c.lock.Lock()
+	for _, key := range keys {
+		c.dropError(key)
+	}
+	removed = c.lru.RemoveMany(keys)
+	if c.onEvictedCB != nil && removed > 0 {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.lock.Unlock()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	return removed
+}
+
+// Pop removes the provided key from the cache and returns its value,
+// without invoking the evict callback.
+func (c *Cache[K, V]) Pop(key K) (value V, ok bool) {
+	c.lock.Lock()
+	c.dropError(key)
+	value, ok = c.lru.Pop(key)
+	c.lock.Unlock()
+	return c.decode(value, ok)
+}
+
+// Pin exempts a cached key from capacity eviction until Unpin is called or
+// the key is removed explicitly, see simplelru.LRU.Pin. Returns false if the
+// key is not cached, or if the cache has as many pinned entries as it can.
+func (c *Cache[K, V]) Pin(key K) (ok bool) {
+	c.lock.Lock()
+	ok = c.lru.Pin(key)
+	c.lock.Unlock()
+	return ok
+}
+
+// Unpin makes a key pinned by Pin evictable again, returning if it was
+// pinned.
+func (c *Cache[K, V]) Unpin(key K) (ok bool) {
+	c.lock.Lock()
+	ok = c.lru.Unpin(key)
+	c.lock.Unlock()
+	return ok
+}
+
+// Resize changes the cache size. While the cache is frozen the new size
+// is only recorded, and applied by Unfreeze.
 func (c *Cache[K, V]) Resize(size int) (evicted int) {
 	var ks []K
 	var vs []V
+	var ms []any
 	c.lock.Lock()
+	if c.frozen {
+		c.pendingSize = size
+		c.lock.Unlock()
+		return 0
+	}
 	evicted = c.lru.Resize(size)
+	c.stats.Evict(evicted)
+	if c.errs != nil {
+		c.errs.Resize(size)
+		c.trimErrors(0)
+	}
 	if c.onEvictedCB != nil && evicted > 0 {
-		ks, vs = c.evictedKeys, c.evictedVals
-		c.initEvictBuffers()
+		ks, vs, ms = c.takeEvicted()
 	}
 	c.lock.Unlock()
 	if c.onEvictedCB != nil && evicted > 0 {
-		for i := 0; i < len(ks); i++ {
-			c.onEvictedCB(ks[i], vs[i])
+		c.notifyAllEvicted(ks, vs, ms)
+	}
+	return evicted
+}
+
+// Shrink evicts the given fraction of the entries, between 0 and 1, oldest
+// first, e.g. to release memory when the process nears its memory limit.
+// Like Resize, it skips the pinned entries and those kept by the eviction
+// filter. A frozen cache is left untouched, since it does not evict.
+// Returns the number of evictions.
+func (c *Cache[K, V]) Shrink(fraction float64) (evicted int) {
+	var ks []K
+	var vs []V
+	var ms []any
+	c.lock.Lock()
+	if c.frozen {
+		c.lock.Unlock()
+		return 0
+	}
+	n := shrinkCount(c.lru.Len(), fraction)
+	for ; evicted < n; evicted++ {
+		if !c.lru.EvictOldest() {
+			break
+		}
+	}
+	c.stats.Evict(evicted)
+	if c.onEvictedCB != nil && evicted > 0 {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.lock.Unlock()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	return evicted
+}
+
+// shrinkCount returns how many of n entries Shrink evicts for fraction.
+func shrinkCount(n int, fraction float64) int {
+	if fraction <= 0 {
+		return 0
+	}
+	if fraction >= 1 {
+		return n
+	}
+	return int(float64(n) * fraction)
+}
+
+// Freeze makes the cache read-only for additions: while frozen, Add,
+// ContainsOrAdd and PeekOrAdd leave the cache untouched and no capacity
+// evictions happen, so the set of keys only shrinks through explicit
+// removals. This allows taking consistent snapshots with Keys, Values or
+// PeekOldestN without holding the lock for the entire duration.
+func (c *Cache[K, V]) Freeze() {
+	c.lock.Lock()
+	c.frozen = true
+	c.lock.Unlock()
+}
+
+// Unfreeze re-enables additions, applying any Resize requested while the
+// cache was frozen. Returns the number of entries evicted by that Resize.
+func (c *Cache[K, V]) Unfreeze() (evicted int) {
+	var ks []K
+	var vs []V
+	var ms []any
+	c.lock.Lock()
+	c.frozen = false
+	if c.pendingSize != 0 {
+		evicted = c.lru.Resize(c.pendingSize)
+		c.stats.Evict(evicted)
+		if c.errs != nil {
+			c.errs.Resize(c.pendingSize)
+			c.trimErrors(0)
 		}
+		c.pendingSize = 0
+	}
+	if c.onEvictedCB != nil && evicted > 0 {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.lock.Unlock()
+	if c.onEvictedCB != nil && evicted > 0 {
+		c.notifyAllEvicted(ks, vs, ms)
 	}
 	return evicted
 }
 
+// Frozen reports whether the cache is frozen.
+func (c *Cache[K, V]) Frozen() bool {
+	c.lock.RLock()
+	frozen := c.frozen
+	c.lock.RUnlock()
+	return frozen
+}
+
 // RemoveOldest removes the oldest item from the cache.
 func (c *Cache[K, V]) RemoveOldest() (key K, value V, ok bool) {
 	var k K
 	var v V
+	var m any
 	c.lock.Lock()
 	key, value, ok = c.lru.RemoveOldest()
 	if c.onEvictedCB != nil && ok {
-		k, v = c.evictedKeys[0], c.evictedVals[0]
-		c.evictedKeys, c.evictedVals = c.evictedKeys[:0], c.evictedVals[:0]
+		k, v, m = c.takeFirstEvicted()
 	}
 	c.lock.Unlock()
 	if c.onEvictedCB != nil && ok {
-		c.onEvictedCB(k, v)
+		c.notifyEvicted(k, v, m)
 	}
+	value, _ = c.decode(value, ok)
 	return
 }
 
@@ -222,23 +929,133 @@ func (c *Cache[K, V]) GetOldest() (key K, value V, ok bool) {
 	c.lock.RLock()
 	key, value, ok = c.lru.GetOldest()
 	c.lock.RUnlock()
+	value, _ = c.decode(value, ok)
 	return
 }
 
-// Keys returns a slice of the keys in the cache, from oldest to newest.
-func (c *Cache[K, V]) Keys() []K {
+// PeekOldestN returns up to n of the oldest entries, from oldest to newest,
+// without updating their "recently used"-ness.
+func (c *Cache[K, V]) PeekOldestN(n int) []simplelru.Entry[K, V] {
 	c.lock.RLock()
-	keys := c.lru.Keys()
+	entries := c.lru.PeekOldestN(n)
 	c.lock.RUnlock()
-	return keys
+	return c.decodeEntries(entries)
+}
+
+// PopOldestN removes up to n of the oldest entries under a single lock and
+// returns them, from oldest to newest, without invoking the evict callback.
+func (c *Cache[K, V]) PopOldestN(n int) []simplelru.Entry[K, V] {
+	c.lock.Lock()
+	entries := c.lru.PopOldestN(n)
+	c.lock.Unlock()
+	return c.decodeEntries(entries)
+}
+
+// Keys returns a slice of the keys in the cache, from oldest to newest.
+// Large caches are copied in chunks, releasing the lock in between, so the
+// result is only a consistent snapshot if there are no concurrent writes.
+func (c *Cache[K, V]) Keys() []K {
+	return copyChunked(c, c.lru.Keys, simplelru.Handle[K, V].Key)
 }
 
 // Values returns a slice of the values in the cache, from oldest to newest.
+// Large caches are copied in chunks, releasing the lock in between, so the
+// result is only a consistent snapshot if there are no concurrent writes.
 func (c *Cache[K, V]) Values() []V {
+	values := copyChunked(c, c.lru.Values, func(h simplelru.Handle[K, V]) V {
+		v, _ := h.Value()
+		return v
+	})
+	if c.codec != nil {
+		for i := range values {
+			values[i], _ = c.codec.decode(values[i])
+		}
+	}
+	return values
+}
+
+// Range calls fn for each entry from oldest to newest, until fn returns false.
+// fn is called without holding the lock, so it may use the cache, e.g. to
+// Remove entries while scanning for ones to invalidate. The entries are
+// walked copyChunkSize at a time rather than copied all at once, so like
+// Keys, Range only sees a consistent snapshot if there are no concurrent
+// writes: entries removed before fn reached them are skipped, and those
+// moved or added meanwhile may be visited again or not at all. At most as
+// many entries are visited as the cache held when Range started.
+func (c *Cache[K, V]) Range(fn func(key K, value V) bool) {
 	c.lock.RLock()
-	values := c.lru.Values()
+	left := c.lru.Len()
+	next, ok := c.lru.OldestHandle()
 	c.lock.RUnlock()
-	return values
+	size := left
+	if size > copyChunkSize {
+		size = copyChunkSize
+	}
+	chunk := make([]simplelru.Handle[K, V], 0, size)
+	for ok && left > 0 {
+		c.lock.RLock()
+		if !next.Valid() {
+			// the entry to resume from was removed, resume from the
+			// newest entry left of the previous chunk
+			next, ok = resumeHandle(c, chunk)
+		}
+		chunk = chunk[:0]
+		for ; ok && len(chunk) < cap(chunk) && len(chunk) < left; next, ok = next.Newer() {
+			chunk = append(chunk, next)
+		}
+		c.lock.RUnlock()
+		left -= len(chunk)
+
+		for _, h := range chunk {
+			c.lock.RLock()
+			value, valid := h.Value()
+			c.lock.RUnlock()
+			value, valid = c.decode(value, valid)
+			if valid && !fn(h.Key(), value) {
+				return
+			}
+		}
+	}
+}
+
+// resumeHandle returns the entry after the newest one of chunk that is still
+// cached, or the oldest entry if there is none. Has to be called with lock!
+func resumeHandle[K comparable, V any](c *Cache[K, V], chunk []simplelru.Handle[K, V]) (simplelru.Handle[K, V], bool) {
+	for i := len(chunk) - 1; i >= 0; i-- {
+		if chunk[i].Valid() {
+			return chunk[i].Newer()
+		}
+	}
+	return c.lru.OldestHandle()
+}
+
+// copyChunked walks the cache from oldest to newest collecting get for
+// every entry, yielding the lock every copyChunkSize entries to bound writer
+// stalls. If the entry to resume from was removed in the meantime, it falls
+// back to copying everything with all under a single lock acquisition.
+func copyChunked[K comparable, V any, T any](c *Cache[K, V], all func() []T, get func(simplelru.Handle[K, V]) T) []T {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.lru.Len() <= copyChunkSize {
+		return all()
+	}
+	res := make([]T, 0, c.lru.Len())
+	h, ok := c.lru.OldestHandle()
+	for ok {
+		for i := 0; ok && i < copyChunkSize; i++ {
+			res = append(res, get(h))
+			h, ok = h.Newer()
+		}
+		if !ok {
+			break
+		}
+		c.lock.RUnlock()
+		c.lock.RLock()
+		if !h.Valid() {
+			return all()
+		}
+	}
+	return res
 }
 
 // Len returns the number of items in the cache.
@@ -249,6 +1066,15 @@ func (c *Cache[K, V]) Len() int {
 	return length
 }
 
+// Weight returns the total weight of the entries, or 0 unless WithWeigher
+// was given.
+func (c *Cache[K, V]) Weight() int64 {
+	c.lock.RLock()
+	weight := c.lru.Weight()
+	c.lock.RUnlock()
+	return weight
+}
+
 // Cap returns the capacity of the cache
 func (c *Cache[K, V]) Cap() int {
 	return c.lru.Cap()
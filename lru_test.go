@@ -4,8 +4,12 @@
 package lru
 
 import (
+	"context"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
 )
 
 func BenchmarkLRU_Rand(b *testing.B) {
@@ -444,3 +448,483 @@ func TestCache_EvictionSameKey(t *testing.T) {
 		}
 	})
 }
+
+// test that PeekOldestN doesn't update recent-ness
+func TestLRUPeekOldestN(t *testing.T) {
+	l, err := New[int, int](3)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	entries := l.PeekOldestN(2)
+	if len(entries) != 2 || entries[0].Key != 1 || entries[1].Key != 2 {
+		t.Errorf("bad entries: %v", entries)
+	}
+
+	l.Add(4, 4)
+	if l.Contains(1) {
+		t.Errorf("should not have updated recent-ness of 1")
+	}
+}
+
+// test that a frozen cache rejects additions and defers evictions
+func TestLRUFreeze(t *testing.T) {
+	var evicted []int
+	l, err := NewWithEvict(3, func(k int, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Freeze()
+	if !l.Frozen() {
+		t.Fatalf("cache should be frozen")
+	}
+
+	if l.Add(3, 3) || l.Contains(3) {
+		t.Errorf("Add should be rejected while frozen")
+	}
+	l.Add(1, 10)
+	if v, _ := l.Peek(1); v != 1 {
+		t.Errorf("update should be rejected while frozen: %v", v)
+	}
+	if contains, _ := l.ContainsOrAdd(4, 4); contains || l.Contains(4) {
+		t.Errorf("ContainsOrAdd should be rejected while frozen")
+	}
+	if _, contains, _ := l.PeekOrAdd(4, 4); contains || l.Contains(4) {
+		t.Errorf("PeekOrAdd should be rejected while frozen")
+	}
+	if previous, contains, _ := l.PeekOrAdd(2, 20); !contains || previous != 2 {
+		t.Errorf("PeekOrAdd should return existing value while frozen")
+	}
+
+	if l.Resize(1) != 0 || l.Len() != 2 || len(evicted) != 0 {
+		t.Errorf("Resize should be deferred while frozen")
+	}
+	l.wantKeys(t, []int{1, 2})
+
+	if n := l.Unfreeze(); n != 1 {
+		t.Errorf("1 element should have been evicted: %v", n)
+	}
+	if l.Frozen() || l.Cap() != 1 {
+		t.Errorf("deferred resize should have been applied")
+	}
+	l.wantKeys(t, []int{2})
+	if len(evicted) != 1 || evicted[0] != 1 {
+		t.Errorf("bad evicted keys: %v", evicted)
+	}
+
+	l.Add(3, 3)
+	l.wantKeys(t, []int{3})
+}
+
+// test that Keys and Values copied in chunks match the cache contents
+func TestLRUKeysValuesChunked(t *testing.T) {
+	size := 3*copyChunkSize + 7
+	l, err := New[int, int](size)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < size; i++ {
+		l.Add(i, -i)
+	}
+
+	keys, values := l.Keys(), l.Values()
+	if len(keys) != size || len(values) != size {
+		t.Fatalf("bad len: %v, %v", len(keys), len(values))
+	}
+	for i := range keys {
+		if keys[i] != i || values[i] != -i {
+			t.Fatalf("bad entry %d: %v, %v", i, keys[i], values[i])
+		}
+	}
+
+	// concurrent writers must not corrupt the walk
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < size; i++ {
+			l.Remove(i)
+			l.Add(size+i, 0)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		seen := make(map[int]struct{}, size)
+		for _, k := range l.Keys() {
+			if _, ok := seen[k]; ok {
+				t.Fatalf("duplicate key: %v", k)
+			}
+			seen[k] = struct{}{}
+		}
+	}
+	<-done
+}
+
+// test that AddTransient doesn't displace recently used entries
+func TestLRUAddTransient(t *testing.T) {
+	l, err := New[int, int](4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+	for i := 10; i < 20; i++ {
+		l.AddTransient(i, i)
+	}
+	if !l.Contains(2) || !l.Contains(3) {
+		t.Errorf("recent entries should not have been evicted: %v", l.Keys())
+	}
+
+	l.Freeze()
+	if l.AddTransient(5, 5) || l.Contains(5) {
+		t.Errorf("AddTransient should be rejected while frozen")
+	}
+}
+
+// test that Range callbacks can use the cache
+func TestLRURange(t *testing.T) {
+	l, err := New[int, int](8)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		l.Add(i, i)
+	}
+
+	var visited []int
+	l.Range(func(k, v int) bool {
+		visited = append(visited, k)
+		if k%2 == 0 {
+			l.Remove(k + 1) // skipped when reached
+		}
+		l.Add(k, v*10) // moving visited entries doesn't revisit them
+		l.Add(100+k, 0)
+		return true
+	})
+	if !reflect.DeepEqual(visited, []int{0, 2, 4, 6}) {
+		t.Fatalf("bad visited keys: %v", visited)
+	}
+
+	visited = visited[:0]
+	l.Range(func(k, v int) bool {
+		visited = append(visited, k)
+		return len(visited) < 3
+	})
+	if len(visited) != 3 {
+		t.Fatalf("Range should stop when fn returns false: %v", visited)
+	}
+}
+
+// test that Range walks caches larger than a chunk without copying them
+func TestLRURangeChunked(t *testing.T) {
+	size := 3*copyChunkSize + 7
+	l, err := New[int, int](size)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < size; i++ {
+		l.Add(i, i)
+	}
+
+	next := 0
+	l.Range(func(k, v int) bool {
+		if k != next || v != k {
+			t.Fatalf("bad entry: %d, %d, want %d", k, v, next)
+		}
+		next++
+		return true
+	})
+	if next != size {
+		t.Fatalf("bad visited count: %d", next)
+	}
+
+	// removing the entries visited, and the one Range resumes from
+	visited := 0
+	l.Range(func(k, v int) bool {
+		visited++
+		l.Remove(k)
+		if k%copyChunkSize == copyChunkSize-1 {
+			l.Remove(k + 1)
+		}
+		return true
+	})
+	if visited != size-3 || l.Len() != 0 {
+		t.Fatalf("bad visited count: %d, len %d", visited, l.Len())
+	}
+
+	// entries moved while walking may be revisited, up to the starting count
+	for i := 0; i < size; i++ {
+		l.Add(i, i)
+	}
+	visited = 0
+	l.Range(func(k, v int) bool {
+		visited++
+		l.Get(k)
+		return true
+	})
+	if visited != size {
+		t.Fatalf("bad visited count: %d", visited)
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		l.Range(func(k, v int) bool { return true })
+	})
+	if allocs > 2 {
+		t.Fatalf("Range should only allocate a chunk: %v", allocs)
+	}
+}
+
+func TestLRUPop(t *testing.T) {
+	evictCounter := 0
+	l, err := NewWithEvict(2, func(k, v int) { evictCounter++ })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 10)
+	if v, ok := l.Pop(1); !ok || v != 10 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if _, ok := l.Pop(1); ok || l.Len() != 0 {
+		t.Fatalf("should not be contained")
+	}
+	if evictCounter != 0 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+}
+
+func TestLRUPopOldestN(t *testing.T) {
+	l, err := New[int, int](4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+	l.Get(0)
+
+	got := l.PopOldestN(2)
+	if len(got) != 2 || got[0].Key != 1 || got[1].Key != 2 {
+		t.Fatalf("bad: %v", got)
+	}
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []int{3, 0}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
+
+func TestLRUPurgeChunked(t *testing.T) {
+	evictCounter := 0
+	l, err := NewWithEvict(3*copyChunkSize, func(k, v int) { evictCounter++ })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 2*copyChunkSize+10; i++ {
+		l.Add(i, i)
+	}
+
+	// aborting leaves the remaining entries in place
+	ctx, cancel := context.WithCancel(context.Background())
+	err = l.PurgeChunked(ctx, func(removed, total int) {
+		if removed != copyChunkSize || total != 2*copyChunkSize+10 {
+			t.Fatalf("bad progress: %v/%v", removed, total)
+		}
+		cancel()
+	})
+	if err != context.Canceled {
+		t.Fatalf("err: %v", err)
+	}
+	if l.Len() != copyChunkSize+10 || evictCounter != copyChunkSize {
+		t.Fatalf("bad len: %v, evicted: %v", l.Len(), evictCounter)
+	}
+
+	var calls []int
+	err = l.PurgeChunked(context.Background(), func(removed, total int) {
+		calls = append(calls, removed)
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(calls, []int{copyChunkSize, copyChunkSize + 10}) {
+		t.Fatalf("bad progress: %v", calls)
+	}
+	if l.Len() != 0 || evictCounter != 2*copyChunkSize+10 {
+		t.Fatalf("bad len: %v, evicted: %v", l.Len(), evictCounter)
+	}
+}
+
+func TestLRUAddMany(t *testing.T) {
+	var evicted []int
+	l, err := NewWithEvict(2, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	n := l.AddMany([]simplelru.Entry[int, int]{{Key: 2, Value: 2}, {Key: 3, Value: 3}, {Key: 4, Value: 4}})
+	if n != 2 || !reflect.DeepEqual(evicted, []int{1, 2}) {
+		t.Fatalf("bad evictions: %v, %v", n, evicted)
+	}
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []int{3, 4}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	l.Freeze()
+	if n := l.AddMany([]simplelru.Entry[int, int]{{Key: 5, Value: 5}}); n != 0 || l.Contains(5) {
+		t.Fatalf("frozen caches should not be added to")
+	}
+}
+
+func TestLRUAddWithContext(t *testing.T) {
+	var evicted []int
+	l, err := NewWithEvict(1, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ev, err := l.AddWithContext(context.Background(), 1, 1); ev || err != nil {
+		t.Fatalf("bad: %v, %v", ev, err)
+	}
+	if ev, err := l.AddWithContext(context.Background(), 2, 2); !ev || err != nil {
+		t.Fatalf("bad: %v, %v", ev, err)
+	}
+	if !reflect.DeepEqual(evicted, []int{1}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+
+	// a stuck writer holds the lock past the deadline
+	l.lock.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := l.AddWithContext(ctx, 3, 3); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	l.lock.Unlock()
+	if l.Contains(3) {
+		t.Fatalf("3 should not have been added")
+	}
+
+	// the lock is acquired once released
+	l.lock.Lock()
+	time.AfterFunc(time.Millisecond, l.lock.Unlock)
+	if _, err := l.AddWithContext(context.Background(), 3, 3); err != nil || !l.Contains(3) {
+		t.Fatalf("3 should have been added: %v", err)
+	}
+}
+
+func TestLRUPin(t *testing.T) {
+	var evicted []int
+	l, err := NewWithEvict(2, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	if !l.Pin(1) || l.Pin(2) {
+		t.Fatalf("bad pins")
+	}
+	l.Add(2, 2)
+	l.Add(3, 3)
+	if !reflect.DeepEqual(evicted, []int{2}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+	if !l.Unpin(1) {
+		t.Fatalf("1 should have been pinned")
+	}
+	l.Add(4, 4)
+	if !reflect.DeepEqual(evicted, []int{2, 1}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+}
+
+func TestLRUAddEx(t *testing.T) {
+	var evicted []int
+	l, err := NewWithEvict(2, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.AddEx(1, 1)
+	l.AddEx(2, 2)
+	if old, replaced, _, _, ev := l.AddEx(1, 10); !replaced || old != 1 || ev {
+		t.Fatalf("bad: %v, %v, %v", old, replaced, ev)
+	}
+	if _, replaced, k, v, ev := l.AddEx(3, 3); replaced || !ev || k != 2 || v != 2 {
+		t.Fatalf("bad: %v, %v=%v, %v", replaced, k, v, ev)
+	}
+	if !reflect.DeepEqual(evicted, []int{2}) {
+		t.Fatalf("the evict callback should still be invoked: %v", evicted)
+	}
+	if s := l.Stats(); s.Adds != 4 || s.Evictions != 1 {
+		t.Fatalf("bad stats: %+v", s)
+	}
+}
+
+func TestLRUGetManyRemoveMany(t *testing.T) {
+	var evicted []int
+	l, err := NewWithEvict(4, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i*10)
+	}
+
+	found := l.GetMany([]int{2, 0, 5})
+	if !reflect.DeepEqual(found, map[int]int{0: 0, 2: 20}) {
+		t.Fatalf("bad found: %v", found)
+	}
+	if s := l.Stats(); s.Hits != 2 || s.Misses != 1 {
+		t.Fatalf("bad stats: %+v", s)
+	}
+	if n := l.RemoveMany([]int{3, 5, 1}); n != 2 || !reflect.DeepEqual(evicted, []int{3, 1}) {
+		t.Fatalf("bad removals: %v, %v", n, evicted)
+	}
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []int{2, 0}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+}
+
+func TestCache_Shrink(t *testing.T) {
+	var evicted []int
+	l, err := NewWithEvict(8, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		l.Add(i, i)
+	}
+	l.Get(0)
+
+	if n := l.Shrink(0.25); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+	if !reflect.DeepEqual(evicted, []int{1, 2}) {
+		t.Fatalf("bad: %v", evicted)
+	}
+	if n := l.Shrink(-1); n != 0 || l.Len() != 6 {
+		t.Fatalf("bad: %d, %d left", n, l.Len())
+	}
+
+	// a frozen cache does not evict
+	l.Freeze()
+	if n := l.Shrink(1); n != 0 || l.Len() != 6 || len(evicted) != 2 {
+		t.Fatalf("bad: %d, %d left", n, l.Len())
+	}
+	l.Unfreeze()
+	if n := l.Shrink(2); n != 6 || l.Len() != 0 {
+		t.Fatalf("bad: %d, %d left", n, l.Len())
+	}
+
+	// pinned and filtered entries are kept
+	l, err = NewWithOpts(8, WithEvictionFilter(func(k, v int) bool { return k == 1 }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+	l.Pin(0)
+	if n := l.Shrink(1); n != 2 || !reflect.DeepEqual(l.Keys(), []int{0, 1}) {
+		t.Fatalf("bad: %d, %v", n, l.Keys())
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import "errors"
+
+// WithEvictMetaCallback sets a callback invoked for every entry leaving the
+// cache along with the metadata attached to it by AddWithMeta, nil if there
+// was none. Like the evict callback, it is called after the lock is
+// released, right after the evict callback for the same entry.
+func WithEvictMetaCallback[K comparable, V any](onEvict func(key K, value V, meta any)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if onEvict == nil {
+			return errors.New("must provide an evict callback")
+		}
+		c.onEvictedMetaCB = func(key K, value V, meta any) {
+			onEvict(key, c.decodeStored(value), meta)
+		}
+		return nil
+	}
+}
+
+// AddWithMeta adds a value to the cache like Add, attaching meta to the
+// entry, e.g. the provenance of the value or a trace ID, see
+// simplelru.LRU.AddWithMeta. Returns true if an eviction occurred.
+func (c *Cache[K, V]) AddWithMeta(key K, value V, meta any) (evicted bool) {
+	if c.rejects(key, value) {
+		return
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	value = c.encode(value)
+	c.lock.Lock()
+	if c.frozen {
+		c.lock.Unlock()
+		return false
+	}
+	c.replaceError(key)
+	evicted = c.add(key, value, func(key K, value V) bool {
+		return c.lru.AddWithMeta(key, value, meta)
+	}) > 0
+	if c.onEvictedCB != nil && evicted {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.unlockAdded()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	if c.pool != nil {
+		c.pool.enforce()
+	}
+	return
+}
+
+// GetMeta returns the metadata attached to a cached key by AddWithMeta,
+// without updating its "recently used"-ness.
+func (c *Cache[K, V]) GetMeta(key K) (meta any, ok bool) {
+	c.lock.RLock()
+	meta, ok = c.lru.GetMeta(key)
+	c.lock.RUnlock()
+	return meta, ok
+}
@@ -0,0 +1,66 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCacheAddWithMeta(t *testing.T) {
+	type traced struct {
+		key   int
+		value string
+		meta  any
+	}
+	var evicted []traced
+	l, err := NewWithOpts(2,
+		WithValueCompression[int, string](&flateCodec{}),
+		WithEvictMetaCallback(func(k int, v string, meta any) {
+			evicted = append(evicted, traced{k, v, meta})
+		}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.AddWithMeta(1, "a", "trace-1")
+	l.Add(2, "b")
+	if meta, ok := l.GetMeta(1); !ok || meta != "trace-1" {
+		t.Fatalf("bad meta: %v, %v", meta, ok)
+	}
+	l.AddWithMeta(3, "c", "trace-3")
+	l.Remove(3)
+	l.Purge()
+	want := []traced{{1, "a", "trace-1"}, {3, "c", "trace-3"}, {2, "b", nil}}
+	if !reflect.DeepEqual(evicted, want) {
+		t.Fatalf("got %v, want %v", evicted, want)
+	}
+	if _, err := NewWithOpts[int, int](2, WithEvictMetaCallback[int, int](nil)); err == nil {
+		t.Fatalf("should fail for a nil callback")
+	}
+}
+
+func TestCacheEvictMetaCallbackReentrant(t *testing.T) {
+	var l *Cache[int, int]
+	var evicted []int
+	var metas []any
+	l, err := NewWithOpts(2,
+		WithEvictCallback(func(k, v int) { evicted = append(evicted, k) }),
+		WithEvictMetaCallback(func(k, v int, meta any) {
+			// the lock is released, so the cache may be used
+			l.Contains(k)
+			metas = append(metas, meta)
+		}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.AddWithMeta(i, i, i*10)
+	}
+	l.Remove(2)
+	l.Resize(1)
+	l.Purge()
+	if !reflect.DeepEqual(evicted, []int{0, 1, 2, 3}) || !reflect.DeepEqual(metas, []any{0, 10, 20, 30}) {
+		t.Fatalf("bad: %v, %v", evicted, metas)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StatsSource is a cache reporting its Stats, such as Cache, TwoQueueCache,
+// arc.ARCCache and expirable.LRU.
+type StatsSource interface {
+	Stats() Stats
+	Len() int
+}
+
+// MetricsSink receives the metrics of the caches registered with a
+// MetricsReporter, e.g. to set the values of Prometheus metrics labelled by
+// name or of go-metrics gauges. The counts in stats are cumulative, so they
+// only go down when the cache's ResetStats is called.
+type MetricsSink interface {
+	ReportCache(name string, stats Stats, size int)
+}
+
+// MetricsSinkFunc adapts a function to a MetricsSink.
+type MetricsSinkFunc func(name string, stats Stats, size int)
+
+// ReportCache calls f(name, stats, size).
+func (f MetricsSinkFunc) ReportCache(name string, stats Stats, size int) {
+	f(name, stats, size)
+}
+
+// MetricsReporter reports the metrics of named caches to a sink, either on
+// demand, e.g. from the Collect method of a Prometheus collector, or
+// periodically. It is safe for concurrent use.
+type MetricsReporter struct {
+	sink MetricsSink
+
+	lock   sync.Mutex
+	caches map[string]StatsSource
+}
+
+// NewMetricsReporter creates a MetricsReporter reporting to sink.
+func NewMetricsReporter(sink MetricsSink) *MetricsReporter {
+	return &MetricsReporter{sink: sink, caches: make(map[string]StatsSource)}
+}
+
+// Register adds a cache to report under name, which must not be in use.
+func (r *MetricsReporter) Register(name string, cache StatsSource) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.caches[name]; ok {
+		return errors.New("cache already registered: " + name)
+	}
+	r.caches[name] = cache
+	return nil
+}
+
+// Unregister stops reporting the cache registered under name.
+func (r *MetricsReporter) Unregister(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.caches, name)
+}
+
+// Report passes the current metrics of every registered cache to the sink,
+// in the order of their names.
+func (r *MetricsReporter) Report() {
+	r.lock.Lock()
+	names := make([]string, 0, len(r.caches))
+	for name := range r.caches {
+		names = append(names, name)
+	}
+	caches := make([]StatsSource, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		caches[i] = r.caches[name]
+	}
+	r.lock.Unlock()
+
+	for i, cache := range caches {
+		r.sink.ReportCache(names[i], cache.Stats(), cache.Len())
+	}
+}
+
+// ReportEvery calls Report every interval from a new goroutine, until the
+// returned function is called.
+func (r *MetricsReporter) ReportEvery(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				r.Report()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetricsReporter(t *testing.T) {
+	type report struct {
+		name  string
+		stats Stats
+		size  int
+	}
+	var reports []report
+	r := NewMetricsReporter(MetricsSinkFunc(func(name string, stats Stats, size int) {
+		reports = append(reports, report{name, stats, size})
+	}))
+
+	l, _ := New[int, int](4)
+	q, _ := New2Q[int, int](4)
+	if err := r.Register("lru", l); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := r.Register("2q", q); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := r.Register("2q", l); err == nil {
+		t.Fatalf("expected an error registering a name twice")
+	}
+
+	l.Add(1, 1)
+	l.Get(1)
+	l.Get(2)
+	q.Add(1, 1)
+	r.Report()
+	want := []report{
+		{"2q", Stats{Adds: 1}, 1},
+		{"lru", Stats{Hits: 1, Misses: 1, Adds: 1}, 1},
+	}
+	if !reflect.DeepEqual(reports, want) {
+		t.Fatalf("bad reports: %+v", reports)
+	}
+
+	r.Unregister("2q")
+	reports = nil
+	r.Report()
+	if len(reports) != 1 || reports[0].name != "lru" {
+		t.Fatalf("bad reports: %+v", reports)
+	}
+}
+
+func TestMetricsReporterReportEvery(t *testing.T) {
+	reported := make(chan string, 1)
+	r := NewMetricsReporter(MetricsSinkFunc(func(name string, stats Stats, size int) {
+		select {
+		case reported <- name:
+		default:
+		}
+	}))
+	l, _ := New[int, int](4)
+	_ = r.Register("lru", l)
+
+	stop := r.ReportEvery(time.Millisecond)
+	defer stop()
+	select {
+	case name := <-reported:
+		if name != "lru" {
+			t.Fatalf("bad name: %q", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for a report")
+	}
+	stop()
+}
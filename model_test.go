@@ -0,0 +1,190 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// modelLRU is a reference LRU with exact TTL semantics, kept as simple as
+// possible to compare the caches against. Expired entries are only hidden
+// by lookups, and stay in the recency order until they are evicted.
+type modelLRU struct {
+	size    int
+	ttl     time.Duration // 0 if entries never expire
+	keys    []int         // oldest first
+	values  map[int]int
+	expires map[int]time.Time
+}
+
+func newModelLRU(size int, ttl time.Duration) *modelLRU {
+	return &modelLRU{size: size, ttl: ttl, values: make(map[int]int), expires: make(map[int]time.Time)}
+}
+
+func (m *modelLRU) index(key int) int {
+	for i, k := range m.keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *modelLRU) touch(i int) {
+	key := m.keys[i]
+	m.keys = append(append(m.keys[:i:i], m.keys[i+1:]...), key)
+}
+
+func (m *modelLRU) expired(now time.Time, key int) bool {
+	return m.ttl > 0 && now.After(m.expires[key])
+}
+
+func (m *modelLRU) add(now time.Time, key, value int) (evicted bool) {
+	m.values[key] = value
+	m.expires[key] = now.Add(m.ttl)
+	if i := m.index(key); i >= 0 {
+		m.touch(i)
+		return false
+	}
+	m.keys = append(m.keys, key)
+	if len(m.keys) > m.size {
+		m.remove(m.keys[0])
+		return true
+	}
+	return false
+}
+
+func (m *modelLRU) get(now time.Time, key int) (value int, ok bool) {
+	i := m.index(key)
+	if i < 0 || m.expired(now, key) {
+		return 0, false
+	}
+	m.touch(i)
+	return m.values[key], true
+}
+
+func (m *modelLRU) peek(now time.Time, key int) (value int, ok bool) {
+	if m.index(key) < 0 || m.expired(now, key) {
+		return 0, false
+	}
+	return m.values[key], true
+}
+
+func (m *modelLRU) contains(key int) bool {
+	return m.index(key) >= 0
+}
+
+func (m *modelLRU) remove(key int) bool {
+	i := m.index(key)
+	if i < 0 {
+		return false
+	}
+	m.keys = append(m.keys[:i], m.keys[i+1:]...)
+	delete(m.values, key)
+	delete(m.expires, key)
+	return true
+}
+
+func (m *modelLRU) liveKeys(now time.Time) []int {
+	keys := make([]int, 0, len(m.keys))
+	for _, k := range m.keys {
+		if !m.expired(now, k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// modelCache is the subset of the cache API compared against modelLRU.
+type modelCache interface {
+	Add(key, value int) bool
+	Get(key int) (int, bool)
+	Peek(key int) (int, bool)
+	Contains(key int) bool
+	Remove(key int) bool
+	Keys() []int
+	Len() int
+}
+
+// runModel applies a random sequence of operations to c and a modelLRU of
+// the same size and TTL, failing at the first observable difference.
+func runModel(t *testing.T, seed int64, c modelCache, m *modelLRU, clock *FakeClock) {
+	t.Helper()
+	r := rand.New(rand.NewSource(seed))
+	var ops []string
+	fail := func(format string, args ...any) {
+		t.Helper()
+		t.Fatalf("seed %d, after %v: %s", seed, ops, fmt.Sprintf(format, args...))
+	}
+	for i := 0; i < 200; i++ {
+		key := r.Intn(2 * m.size)
+		now := clock.Now()
+		switch op := r.Intn(7); op {
+		case 0, 1:
+			ops = append(ops, fmt.Sprintf("Add(%d)", key))
+			if got, want := c.Add(key, i), m.add(now, key, i); got != want {
+				fail("evicted %v, want %v", got, want)
+			}
+		case 2:
+			ops = append(ops, fmt.Sprintf("Get(%d)", key))
+			gv, gok := c.Get(key)
+			if wv, wok := m.get(now, key); gv != wv || gok != wok {
+				fail("got %v, %v, want %v, %v", gv, gok, wv, wok)
+			}
+		case 3:
+			ops = append(ops, fmt.Sprintf("Peek(%d)", key))
+			gv, gok := c.Peek(key)
+			if wv, wok := m.peek(now, key); gv != wv || gok != wok {
+				fail("got %v, %v, want %v, %v", gv, gok, wv, wok)
+			}
+		case 4:
+			ops = append(ops, fmt.Sprintf("Contains(%d)", key))
+			if got, want := c.Contains(key), m.contains(key); got != want {
+				fail("got %v, want %v", got, want)
+			}
+		case 5:
+			ops = append(ops, fmt.Sprintf("Remove(%d)", key))
+			if got, want := c.Remove(key), m.remove(key); got != want {
+				fail("got %v, want %v", got, want)
+			}
+		case 6:
+			d := time.Duration(r.Int63n(int64(time.Hour / 4)))
+			ops = append(ops, fmt.Sprintf("Advance(%v)", d))
+			clock.Advance(d)
+		}
+		if got, want := c.Len(), len(m.keys); got != want {
+			fail("Len() = %d, want %d", got, want)
+		}
+		if got, want := c.Keys(), m.liveKeys(clock.Now()); !reflect.DeepEqual(got, want) && (len(got) != 0 || len(want) != 0) {
+			fail("Keys() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCacheAgainstModel(t *testing.T) {
+	for seed := int64(0); seed < 1000; seed++ {
+		clock := NewFakeClock(time.Unix(0, 0))
+		c, err := New[int, int](8)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		runModel(t, seed, c, newModelLRU(8, 0), clock)
+	}
+}
+
+func TestExpirableLRUAgainstModel(t *testing.T) {
+	for seed := int64(0); seed < 1000; seed++ {
+		clock := NewFakeClock(time.Unix(0, 0))
+		// the cleanup goroutine ticks every ttl/100 of real time, so it
+		// doesn't run during the test and expired entries stay in place
+		c := expirable.NewLRUWithOpts[int, int](8, nil, time.Hour, expirable.WithClock[int, int](clock))
+		runModel(t, seed, c, newModelLRU(8, time.Hour), clock)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// cachedError is a negative result stored by AddError.
+type cachedError struct {
+	err       error
+	expiresAt time.Time
+}
+
+// AddError caches err as the result for key for the given ttl, replacing
+// any value cached for it. Negative results are only visible through
+// GetResult, and are dropped again by adding a value for the key. They are
+// not counted by Len, but take up capacity like values: adding one to a full
+// cache evicts the oldest value, and values added to a full cache push out
+// the oldest negative results first. Returns true if an entry had to be
+// evicted to make room.
+func (c *Cache[K, V]) AddError(key K, err error, ttl time.Duration) (evicted bool) {
+	var ks []K
+	var vs []V
+	var ms []any
+	c.lock.Lock()
+	if c.frozen {
+		c.lock.Unlock()
+		return false
+	}
+	if c.errs == nil {
+		// the size is valid, or the cache could not have been created
+		c.errs, _ = simplelru.NewLRU[K, cachedError](c.lru.Cap(), nil)
+	}
+	evicted = c.errs.Add(key, cachedError{err: err, expiresAt: c.clock.Now().Add(ttl)})
+	present := c.lru.Remove(key)
+	n := 0
+	for c.lru.Len()+c.errs.Len() > c.lru.Cap() {
+		if c.lru.EvictOldest() {
+			n++
+		} else {
+			// the values left can't be evicted
+			c.errs.RemoveOldest()
+		}
+		evicted = true
+	}
+	c.stats.Evict(n)
+	if c.onEvictedCB != nil && (present || n > 0) {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.lock.Unlock()
+	if c.onEvictedCB != nil {
+		c.notifyAllEvicted(ks, vs, ms)
+	}
+	return evicted
+}
+
+// GetResult looks up the result cached for key, which is either a value or
+// an unexpired error stored by AddError, counting a hit for either. Looking
+// up a value updates the "recently used"-ness of the key.
+func (c *Cache[K, V]) GetResult(key K) (value V, err error, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer func() {
+		c.stats.Lookup(ok)
+		c.observeLookup(key)
+	}()
+	if value, ok = c.lru.Get(key); ok || c.errs == nil {
+		value, ok = c.decode(value, ok)
+		return value, nil, ok
+	}
+	if ce, found := c.errs.Get(key); found {
+		if c.clock.Now().Before(ce.expiresAt) {
+			return value, ce.err, true
+		}
+		c.errs.Remove(key)
+	}
+	return value, nil, false
+}
+
+// dropError removes any negative result cached for key. Has to be called with lock!
+func (c *Cache[K, V]) dropError(key K) {
+	if c.errs != nil {
+		c.errs.Remove(key)
+	}
+}
+
+// replaceError removes any negative result cached for key, which a value is
+// about to be added for, and the oldest ones that would take the cache
+// beyond its capacity once it is. Has to be called with lock!
+func (c *Cache[K, V]) replaceError(key K) {
+	if c.errs == nil {
+		return
+	}
+	c.errs.Remove(key)
+	if !c.lru.Contains(key) {
+		c.trimErrors(1)
+	}
+}
+
+// trimErrors removes the oldest negative results until they fit in the
+// capacity left by the values, and extra values about to be added. Has to
+// be called with lock!
+func (c *Cache[K, V]) trimErrors(extra int) {
+	for c.errs != nil && c.errs.Len() > 0 && c.lru.Len()+c.errs.Len()+extra > c.lru.Cap() {
+		c.errs.RemoveOldest()
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+func TestCacheAddError(t *testing.T) {
+	var evicted []int
+	l, err := NewWithEvict(2, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err, ok := l.GetResult(1); ok || err != nil {
+		t.Fatalf("should not find missing key")
+	}
+
+	errNotFound := errors.New("not found")
+	l.Add(1, 1)
+	l.AddError(1, errNotFound, time.Hour)
+	if len(evicted) != 1 || evicted[0] != 1 {
+		t.Fatalf("value should have been replaced: %v", evicted)
+	}
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("Get should not return negative results")
+	}
+	if _, err, ok := l.GetResult(1); !ok || err != errNotFound {
+		t.Fatalf("bad result: %v, %v", err, ok)
+	}
+
+	// adding a value drops the negative result
+	l.Add(1, 10)
+	if v, err, ok := l.GetResult(1); !ok || err != nil || v != 10 {
+		t.Fatalf("bad result: %v, %v, %v", v, err, ok)
+	}
+	l.Remove(1)
+
+	// expired negative results are dropped on lookup
+	l.AddError(2, errNotFound, -time.Second)
+	if _, err, ok := l.GetResult(2); ok || err != nil {
+		t.Fatalf("negative result should have expired")
+	}
+
+	// negative results are bounded by the cache size
+	l.AddError(3, errNotFound, time.Hour)
+	l.AddError(4, errNotFound, time.Hour)
+	if !l.AddError(5, errNotFound, time.Hour) {
+		t.Fatalf("should have an eviction")
+	}
+	if _, _, ok := l.GetResult(3); ok {
+		t.Fatalf("3 should have been evicted")
+	}
+
+	l.Remove(4)
+	if _, _, ok := l.GetResult(4); ok {
+		t.Fatalf("4 should have been removed")
+	}
+	l.Purge()
+	if _, _, ok := l.GetResult(5); ok {
+		t.Fatalf("5 should have been purged")
+	}
+	if l.Len() != 0 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+}
+
+func TestCacheAddError_Capacity(t *testing.T) {
+	var evicted []int
+	l, err := NewWithEvict(3, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	errNotFound := errors.New("not found")
+
+	// negative results evict the oldest values
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	if !l.AddError(4, errNotFound, time.Hour) || !reflect.DeepEqual(evicted, []int{1}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+	if l.Len() != 2 || l.Cap() != 3 {
+		t.Fatalf("bad len: %d, cap %d", l.Len(), l.Cap())
+	}
+
+	// values push out the oldest negative results first
+	evicted = nil
+	l.Add(5, 5)
+	if len(evicted) != 0 || l.Len() != 3 {
+		t.Fatalf("bad evictions: %v, len %d", evicted, l.Len())
+	}
+	if _, _, ok := l.GetResult(4); ok {
+		t.Fatalf("4 should have been pushed out")
+	}
+
+	// the values and negative results never exceed the capacity
+	evicted = nil
+	l.AddError(8, errNotFound, time.Hour)
+	l.AddMany([]simplelru.Entry[int, int]{{Key: 9, Value: 9}, {Key: 10, Value: 10}})
+	if _, _, ok := l.GetResult(8); ok || l.Len() != 3 {
+		t.Fatalf("8 should have been pushed out: len %d", l.Len())
+	}
+	l.AddError(11, errNotFound, time.Hour)
+	if l.Resize(2) != 0 || l.Len() != 2 {
+		t.Fatalf("bad len after resize: %d", l.Len())
+	}
+	if _, _, ok := l.GetResult(11); ok {
+		t.Fatalf("11 should have been pushed out")
+	}
+}
+
+func TestCacheGetResult_Stats(t *testing.T) {
+	l, err := New[int, int](2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.AddError(2, errors.New("not found"), time.Hour)
+	l.GetResult(1)
+	l.GetResult(2)
+	l.GetResult(3)
+	if s := l.Stats(); s.Hits != 2 || s.Misses != 1 {
+		t.Fatalf("bad stats: %+v", s)
+	}
+}
+
+func TestCacheAddErrorWithClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	l, err := NewWithOpts(2, WithClock[int, int](clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := NewWithOpts(2, WithClock[int, int](nil)); err == nil {
+		t.Fatalf("should reject nil clock")
+	}
+
+	l.AddError(1, errors.New("unavailable"), time.Minute)
+	clock.Advance(59 * time.Second)
+	if _, err, ok := l.GetResult(1); !ok || err == nil {
+		t.Fatalf("negative result should not have expired yet")
+	}
+	clock.Advance(time.Second)
+	if _, _, ok := l.GetResult(1); ok {
+		t.Fatalf("negative result should have expired")
+	}
+}
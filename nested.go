@@ -0,0 +1,210 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// Nested is a thread-safe cache of caches, holding an inner Cache per outer
+// key, e.g. one per tenant. Inner caches are created by a factory on first
+// use, and dropped once they were not used for a fixed TTL, or to make room
+// for others. A dropped inner cache is purged, invoking its evict callback
+// for every value, so that options such as WithAutoClose release what it
+// holds.
+//
+// The inner caches are kept in an expirable.LRU, and operations on them
+// through Get and Add only hold their own lock, so that tenants don't wait
+// for each other. An inner cache dropped while such operations run is
+// purged once the last of them returns, so their values are not lost
+// without invoking its evict callback. The evict callbacks of inner caches
+// may call back into the Nested.
+type Nested[K1, K2 comparable, V any] struct {
+	factory func(key K1) (*Cache[K2, V], error)
+
+	// outer holds the inner caches from the least to the most recently used
+	outer *expirable.LRU[K1, *nestedCache[K2, V]]
+
+	// creating holds a channel per key whose inner cache is being created,
+	// closed once it is added to outer, so that it is only created once
+	mu       sync.Mutex
+	creating map[K1]chan struct{}
+}
+
+// nestedCache is an inner cache stored in a Nested.
+type nestedCache[K comparable, V any] struct {
+	cache *Cache[K, V]
+
+	// refs counts the operations running on cache, plus one while outer
+	// holds it; cache is purged once it drops to 0
+	refs int32
+}
+
+// acquire takes a reference to n, returning false if it was dropped.
+func (n *nestedCache[K, V]) acquire() bool {
+	for {
+		refs := atomic.LoadInt32(&n.refs)
+		if refs == 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&n.refs, refs, refs+1) {
+			return true
+		}
+	}
+}
+
+// release drops a reference to n, purging its cache with the last one.
+func (n *nestedCache[K, V]) release() {
+	if atomic.AddInt32(&n.refs, -1) == 0 {
+		n.cache.Purge()
+	}
+}
+
+// NewNested creates a Nested holding up to size inner caches, created by
+// factory, which is called at most once at a time per key, without holding
+// any lock.
+//
+// Providing 0 TTL turns expiring off. Otherwise expired inner caches are
+// dropped by the cleanup goroutine of the expirable.LRU holding them, which
+// runs until Close is called. The WithExpiryClock option sets the clock
+// inner caches expire by.
+func NewNested[K1, K2 comparable, V any](size int, ttl time.Duration, factory func(key K1) (*Cache[K2, V], error), opts ...ExpiryOption) (*Nested[K1, K2, V], error) {
+	if size <= 0 {
+		return nil, errors.New("must provide a positive size")
+	}
+	if factory == nil {
+		return nil, errors.New("must provide a factory")
+	}
+	o := expiryOptions{clock: RealClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := &Nested[K1, K2, V]{factory: factory, creating: make(map[K1]chan struct{})}
+	c.outer = expirable.NewLRUWithOpts[K1, *nestedCache[K2, V]](size, nil, ttl,
+		expirable.WithClock[K1, *nestedCache[K2, V]](o.clock),
+		expirable.WithEvictReasonCallback(c.onDropped))
+	return c, nil
+}
+
+// onDropped is called by outer, without holding any lock, for inner caches
+// leaving it, including those replaced after they expired.
+func (c *Nested[K1, K2, V]) onDropped(_ K1, n *nestedCache[K2, V], _ expirable.EvictReason) {
+	n.release()
+}
+
+// Inner returns the inner cache of key, creating it if needed, and resets
+// its expiration time. The inner cache may be dropped concurrently once
+// Inner returns, and values added to it afterwards are then lost without
+// invoking its evict callback; prefer Get and Add where this matters.
+func (c *Nested[K1, K2, V]) Inner(key K1) (*Cache[K2, V], error) {
+	n, err := c.acquire(key, true)
+	if err != nil {
+		return nil, err
+	}
+	defer n.release()
+	return n.cache, nil
+}
+
+// Get looks up key2 in the inner cache of key1, without creating it. A hit
+// or a miss in an existing inner cache resets its expiration time.
+func (c *Nested[K1, K2, V]) Get(key1 K1, key2 K2) (value V, ok bool) {
+	n, _ := c.acquire(key1, false)
+	if n == nil {
+		return value, false
+	}
+	defer n.release()
+	return n.cache.Get(key2)
+}
+
+// Add adds a value under key2 to the inner cache of key1, creating it if
+// needed, and resets the expiration time of the inner cache. Returns true if
+// the inner cache evicted a value, or the error of the factory.
+func (c *Nested[K1, K2, V]) Add(key1 K1, key2 K2, value V) (evicted bool, err error) {
+	n, err := c.acquire(key1, true)
+	if err != nil {
+		return false, err
+	}
+	defer n.release()
+	return n.cache.Add(key2, value), nil
+}
+
+// Remove drops the inner cache of key, returning if it was contained.
+func (c *Nested[K1, K2, V]) Remove(key K1) (present bool) {
+	return c.outer.Remove(key)
+}
+
+// Purge drops every inner cache.
+func (c *Nested[K1, K2, V]) Purge() {
+	c.outer.Purge()
+}
+
+// Len returns the number of inner caches, including expired ones that were
+// not dropped yet.
+func (c *Nested[K1, K2, V]) Len() int {
+	return c.outer.Len()
+}
+
+// Close stops the goroutine dropping expired inner caches, and drops every
+// inner cache.
+func (c *Nested[K1, K2, V]) Close() {
+	c.outer.Close()
+	c.outer.Purge()
+}
+
+// acquire returns the inner cache of key with a reference taken, resetting
+// its expiration time, or creates it if create is set. Returns nil if there
+// is none to return.
+func (c *Nested[K1, K2, V]) acquire(key K1, create bool) (*nestedCache[K2, V], error) {
+	for {
+		if n, ok := c.outer.Get(key); ok && n.acquire() {
+			c.outer.Touch(key)
+			return n, nil
+		}
+		if !create {
+			return nil, nil
+		}
+
+		c.mu.Lock()
+		if _, ok := c.outer.Peek(key); ok {
+			// created since the lookup
+			c.mu.Unlock()
+			continue
+		}
+		if done, ok := c.creating[key]; ok {
+			c.mu.Unlock()
+			<-done
+			continue
+		}
+		done := make(chan struct{})
+		c.creating[key] = done
+		c.mu.Unlock()
+
+		n, err := c.create(key)
+		c.mu.Lock()
+		delete(c.creating, key)
+		c.mu.Unlock()
+		close(done)
+		return n, err
+	}
+}
+
+// create creates the inner cache of key with the factory, and adds it to
+// outer with a reference taken for the caller.
+func (c *Nested[K1, K2, V]) create(key K1) (*nestedCache[K2, V], error) {
+	inner, err := c.factory(key)
+	if err != nil {
+		return nil, err
+	}
+	if inner == nil {
+		return nil, errors.New("factory returned no cache")
+	}
+	n := &nestedCache[K2, V]{cache: inner, refs: 2}
+	c.outer.Add(key, n)
+	return n, nil
+}
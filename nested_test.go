@@ -0,0 +1,160 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNested(t *testing.T) {
+	if _, err := NewNested[string, int, int](2, 0, nil); err == nil {
+		t.Fatalf("should reject nil factory")
+	}
+
+	var lock sync.Mutex
+	var evicted []string
+	clock := NewFakeClock(time.Unix(0, 0))
+	l, err := NewNested(2, 50*time.Millisecond, func(tenant string) (*Cache[int, int], error) {
+		if tenant == "" {
+			return nil, errors.New("no tenant")
+		}
+		return NewWithEvict(2, func(k, v int) {
+			lock.Lock()
+			defer lock.Unlock()
+			evicted = append(evicted, tenant)
+		})
+	}, WithExpiryClock(clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+	wantEvicted := func(want ...string) {
+		t.Helper()
+		lock.Lock()
+		defer lock.Unlock()
+		sort.Strings(evicted)
+		if !reflect.DeepEqual(evicted, want) && (len(evicted) != 0 || len(want) != 0) {
+			t.Fatalf("got evictions from %v, want %v", evicted, want)
+		}
+		evicted = nil
+	}
+
+	if _, err := l.Add("", 1, 1); err == nil {
+		t.Fatalf("should return the factory error")
+	}
+	if _, ok := l.Get("a", 1); ok || l.Len() != 0 {
+		t.Fatalf("Get should not create inner caches")
+	}
+	l.Add("a", 1, 1)
+	l.Add("a", 2, 2)
+	l.Add("b", 1, 1)
+	if v, ok := l.Get("a", 2); !ok || v != 2 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	inner, err := l.Inner("a")
+	if err != nil || inner.Len() != 2 {
+		t.Fatalf("bad inner cache: %v", err)
+	}
+	wantEvicted()
+
+	// b is the least recently used and makes room for c
+	l.Add("c", 1, 1)
+	if l.Len() != 2 {
+		t.Fatalf("bad len: %d", l.Len())
+	}
+	wantEvicted("b")
+	if !l.Remove("a") || l.Remove("a") {
+		t.Fatalf("a should have been removed once")
+	}
+	wantEvicted("a", "a")
+
+	// c expires unless used, and the cleanup drops it
+	waitLen := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for l.Len() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("bad len: %d, want %d", l.Len(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	clock.Advance(30 * time.Millisecond)
+	l.Get("c", 1)
+	clock.Advance(30 * time.Millisecond)
+	if _, ok := l.Get("c", 1); !ok {
+		t.Fatalf("c should have been renewed")
+	}
+	clock.Advance(30 * time.Millisecond)
+	time.Sleep(60 * time.Millisecond)
+	if l.Len() != 1 {
+		t.Fatalf("c should not have expired yet: %d", l.Len())
+	}
+	clock.Advance(30 * time.Millisecond)
+	waitLen(0)
+	wantEvicted("c")
+
+	l.Add("d", 1, 1)
+	l.Close()
+	if l.Len() != 0 {
+		t.Fatalf("Close should drop inner caches")
+	}
+	wantEvicted("d")
+}
+
+func TestNested_Concurrent(t *testing.T) {
+	block := make(chan struct{})
+	blocked := make(chan struct{})
+	var evicted []int
+	var l *Nested[string, int, int]
+	l, err := NewNested(4, 0, func(tenant string) (*Cache[int, int], error) {
+		return NewWithEvict(1, func(k, v int) {
+			if tenant != "slow" {
+				return
+			}
+			// callbacks may use the Nested
+			l.Len()
+			if k == 1 {
+				close(blocked)
+				<-block
+			}
+			evicted = append(evicted, k)
+		})
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+
+	l.Add("slow", 1, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Add("slow", 2, 2)
+	}()
+	<-blocked
+
+	// other tenants don't wait for the slow one
+	if _, err := l.Add("fast", 1, 1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if v, ok := l.Get("fast", 1); !ok || v != 1 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+
+	// the slow inner cache is only purged once the Add returns
+	if !l.Remove("slow") {
+		t.Fatalf("slow should have been removed")
+	}
+	close(block)
+	<-done
+	if !reflect.DeepEqual(evicted, []int{1, 2}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/internal"
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// Option configures a Cache constructed by NewWithOpts.
+type Option[K comparable, V any] func(*Cache[K, V]) error
+
+// NewWithOpts constructs a fixed size cache configured by opts.
+func NewWithOpts[K comparable, V any](size int, opts ...Option[K, V]) (*Cache[K, V], error) {
+	c := &Cache[K, V]{clock: RealClock{}, stats: new(simplelru.StatsCounter)}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.deterministic {
+		c.lruOpts = append(c.lruOpts, simplelru.WithSampleSeed[K, V](deterministicSeed))
+	}
+	if c.minResidency > 0 {
+		c.lruOpts = append(c.lruOpts, simplelru.WithMinResidency[K, V](c.minResidency, c.clock))
+	}
+	if c.demotion != nil {
+		c.lruOpts = append(c.lruOpts, simplelru.WithAccessTracking[K, V](c.clock))
+	}
+	if c.autoClose != nil {
+		c.autoClose.Sync = c.deterministic
+		c.initAutoClose()
+	}
+	c.lruOpts = append(c.lruOpts, c.replaceCallback()...)
+	if c.codec != nil && c.onEvictedCB != nil {
+		cb := c.onEvictedCB
+		c.onEvictedCB = func(k K, v V) {
+			v, _ = c.codec.decode(v)
+			cb(k, v)
+		}
+	}
+	if c.onPanic != nil {
+		if cb := c.onEvictedCB; cb != nil {
+			c.onEvictedCB = func(k K, v V) {
+				defer c.recoverPanic()
+				cb(k, v)
+			}
+		}
+		if cb := c.onEvictedMetaCB; cb != nil {
+			c.onEvictedMetaCB = func(k K, v V, meta any) {
+				defer c.recoverPanic()
+				cb(k, v, meta)
+			}
+		}
+		if prefetcher := c.prefetcher; prefetcher != nil {
+			c.prefetcher = func(key K) []simplelru.Entry[K, V] {
+				defer c.recoverPanic()
+				return prefetcher(key)
+			}
+		}
+	}
+	if cb := c.onEvictedCB; cb != nil && c.execute != nil {
+		c.onEvictedCB = func(k K, v V) {
+			c.execute(func() { cb(k, v) })
+		}
+	}
+	if cb := c.onEvictedMetaCB; cb != nil {
+		if c.execute != nil {
+			c.onEvictedMetaCB = func(k K, v V, meta any) {
+				c.execute(func() { cb(k, v, meta) })
+			}
+		}
+		if c.onEvictedCB == nil {
+			c.onEvictedCB = func(K, V) {}
+		}
+	}
+	var onEvicted simplelru.EvictCallback[K, V]
+	if c.onEvictedMetaCB != nil {
+		c.initEvictBuffers()
+		c.lruOpts = append(c.lruOpts, simplelru.WithMetaEvictCallback(c.onEvictedMeta))
+	} else if c.onEvictedCB != nil {
+		c.initEvictBuffers()
+		onEvicted = c.onEvicted
+	}
+	lru, err := simplelru.NewLRUWithOpts(size, onEvicted, c.lruOpts...)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = lru
+	c.lruOpts = nil
+	if c.pool != nil {
+		c.pool.attach(c)
+	}
+	return c, nil
+}
+
+// WithEvictCallback sets the callback invoked outside of the lock for every
+// entry leaving the cache.
+func WithEvictCallback[K comparable, V any](onEvicted func(key K, value V)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.onEvictedCB = onEvicted
+		return nil
+	}
+}
+
+// WithEvictionFilter sets a filter that can veto capacity evictions of
+// individual entries, see simplelru.WithEvictionFilter. The filter is called
+// while the cache is locked and must not call back into it.
+func WithEvictionFilter[K comparable, V any](filter simplelru.EvictionFilter[K, V]) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.lruOpts = append(c.lruOpts, simplelru.WithEvictionFilter(filter))
+		return nil
+	}
+}
+
+// WithUpdateInPlace makes Add keep the position of keys that are already
+// contained, see simplelru.WithUpdateInPlace.
+func WithUpdateInPlace[K comparable, V any]() Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.lruOpts = append(c.lruOpts, simplelru.WithUpdateInPlace[K, V]())
+		return nil
+	}
+}
+
+// WithWeigher sets the function weighing entries for WithMaxWeight, see
+// simplelru.WithWeigher. It is called with the lock held, and with
+// WithValueCompression it weighs the compressed values.
+func WithWeigher[K comparable, V any](weigher simplelru.Weigher[K, V]) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.lruOpts = append(c.lruOpts, simplelru.WithWeigher(weigher))
+		return nil
+	}
+}
+
+// WithMaxWeight bounds the total weight of the entries on top of their
+// number, evicting the oldest entries to stay within it, see
+// simplelru.WithMaxWeight.
+func WithMaxWeight[K comparable, V any](maxWeight int64) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.lruOpts = append(c.lruOpts, simplelru.WithMaxWeight[K, V](maxWeight))
+		return nil
+	}
+}
+
+// WithAdmission sets a policy deciding whether new keys are worth evicting
+// an entry for, such as a simplelru.TinyLFU, see simplelru.WithAdmission.
+// The policy is called while the cache is locked and must not call back
+// into it. Given to NewSharded, the policy is shared by the shards, so it
+// must be safe for concurrent use, as TinyLFU is.
+func WithAdmission[K comparable, V any](policy simplelru.AdmissionPolicy[K]) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.lruOpts = append(c.lruOpts, simplelru.WithAdmission[K, V](policy))
+		return nil
+	}
+}
+
+// WithApproximatedLRU makes hits stamp entries instead of reordering them,
+// and capacity evictions pick the least recently used of sampleSize randomly
+// sampled entries, see simplelru.WithApproximatedLRU. Entries are then listed
+// in insertion order. The samples are drawn with a random seed, except in
+// the caches constructed by NewDeterministic.
+func WithApproximatedLRU[K comparable, V any](sampleSize int) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.lruOpts = append(c.lruOpts, simplelru.WithApproximatedLRU[K, V](sampleSize))
+		return nil
+	}
+}
+
+// WithMinResidency protects entries added less than d ago according to the
+// cache's clock from capacity evictions, see simplelru.WithMinResidency.
+func WithMinResidency[K comparable, V any](d time.Duration) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.minResidency = d
+		return nil
+	}
+}
+
+// WithDistinctKeys enables estimating the number of distinct keys looked up,
+// reported by DistinctKeys, using a HyperLogLog sketch of about 4KB with a
+// standard error of about 1.6%. hash must map equal keys to equal values;
+// its distribution does not matter.
+func WithDistinctKeys[K comparable, V any](hash func(key K) uint64) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if hash == nil {
+			return errors.New("must provide a key hash function")
+		}
+		c.distinct = new(internal.HyperLogLog)
+		c.hashKey = hash
+		return nil
+	}
+}
+
+// WithReuseDistance enables estimating the distribution of the reuse
+// distances of the keys looked up, reported by ReuseDistance. Only the keys
+// whose hash falls in one in every of the hash space are tracked, up to
+// 4096 of them in about 250KB, so larger rates estimate longer distances,
+// more coarsely. hash must map equal keys to
+// equal values; its distribution does not matter. If WithDistinctKeys is
+// also given, the last hash function is used by both.
+func WithReuseDistance[K comparable, V any](every int, hash func(key K) uint64) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if every <= 0 {
+			return errors.New("must provide a positive sampling rate")
+		}
+		if hash == nil {
+			return errors.New("must provide a key hash function")
+		}
+		c.reuse = internal.NewReuseDistance(every)
+		c.hashKey = hash
+		return nil
+	}
+}
+
+// Prefetcher returns entries related to a key that missed, e.g. the next
+// pages of a paginated resource, to warm the cache with.
+type Prefetcher[K comparable, V any] func(missedKey K) []simplelru.Entry[K, V]
+
+// WithPrefetcher sets a prefetcher run in a new goroutine after every Get
+// miss. Its entries are added as the oldest ones so that they do not
+// displace the hot working set, and keys that are cached by then are left
+// untouched.
+func WithPrefetcher[K comparable, V any](prefetcher Prefetcher[K, V]) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.prefetcher = prefetcher
+		return nil
+	}
+}
+
+// WithPanicHandler recovers panics in the evict callback and the
+// prefetcher, passing the recovered values to handler instead of letting
+// them unwind through the cache or crash the process. A callback that
+// panicked is skipped, and the remaining ones are still invoked.
+func WithPanicHandler[K comparable, V any](handler func(recovered any)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if handler == nil {
+			return errors.New("must provide a panic handler")
+		}
+		c.onPanic = handler
+		return nil
+	}
+}
+
+// WithCallbackExecutor makes the cache hand every call of the evict
+// callback to execute instead of making it directly, e.g. to run it on a
+// worker pool or with a tracing context. execute is called without holding
+// the lock, and may run fn asynchronously, in which case evict callbacks may
+// run concurrently or out of order.
+func WithCallbackExecutor[K comparable, V any](execute func(fn func())) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if execute == nil {
+			return errors.New("must provide an executor")
+		}
+		c.execute = execute
+		return nil
+	}
+}
+
+// recoverPanic passes a panic in a user callback to the panic handler. Must
+// be deferred directly.
+func (c *Cache[K, V]) recoverPanic() {
+	if r := recover(); r != nil {
+		c.onPanic(r)
+	}
+}
+
+// WithClock sets the clock used to expire negative results added by AddError.
+func WithClock[K comparable, V any](clock Clock) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if clock == nil {
+			return errors.New("must provide a clock")
+		}
+		c.clock = clock
+		return nil
+	}
+}
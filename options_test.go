@@ -0,0 +1,338 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+func TestNewWithOpts(t *testing.T) {
+	if _, err := NewWithOpts[int, int](0); err == nil {
+		t.Fatalf("should reject invalid size")
+	}
+	fail := func(*Cache[int, int]) error { return errors.New("fail") }
+	if _, err := NewWithOpts[int, int](1, fail); err == nil {
+		t.Fatalf("should return option error")
+	}
+
+	evictCounter := 0
+	l, err := NewWithOpts(1, WithEvictCallback(func(k, v int) { evictCounter++ }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if evictCounter != 1 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+}
+
+func TestCacheEvictionFilter(t *testing.T) {
+	var evicted []int
+	l, err := NewWithOpts(2,
+		WithEvictCallback(func(k, v int) { evicted = append(evicted, k) }),
+		WithEvictionFilter(func(k, v int) bool { return k == 1 }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if !l.Add(3, 3) {
+		t.Fatalf("an eviction should have occurred")
+	}
+	l.wantKeys(t, []int{1, 3})
+	if len(evicted) != 1 || evicted[0] != 2 {
+		t.Fatalf("bad evicted keys: %v", evicted)
+	}
+}
+
+func TestCacheUpdateInPlace(t *testing.T) {
+	l, err := NewWithOpts(2, WithUpdateInPlace[int, int]())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(1, 10)
+	l.Add(3, 3)
+	l.wantKeys(t, []int{2, 3})
+}
+
+func TestCacheDistinctKeys(t *testing.T) {
+	if _, err := NewWithOpts[int, int](1, WithDistinctKeys[int, int](nil)); err == nil {
+		t.Fatalf("should reject nil hash")
+	}
+	l, err := New[int, int](10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Get(1)
+	if n := l.DistinctKeys(); n != 0 {
+		t.Fatalf("untracked cache should report 0: %v", n)
+	}
+
+	l, err = NewWithOpts(10, WithDistinctKeys[int, int](func(k int) uint64 { return uint64(k) }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		l.Get(i)
+		l.Get(i)
+	}
+	if n := l.DistinctKeys(); n < 97 || n > 103 {
+		t.Fatalf("bad estimate for 100 keys: %v", n)
+	}
+	for i := 0; i < 100000; i++ {
+		l.Get(i)
+	}
+	if n := l.DistinctKeys(); n < 95000 || n > 105000 {
+		t.Fatalf("bad estimate for 100000 keys: %v", n)
+	}
+}
+
+func TestCacheAddAsOldest(t *testing.T) {
+	l, err := New[int, int](2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	if !l.AddAsOldest(3, 3) {
+		t.Fatalf("should have an eviction")
+	}
+	l.wantKeys(t, []int{3, 2})
+
+	l.Freeze()
+	if l.AddAsOldest(4, 4) || l.Contains(4) {
+		t.Fatalf("AddAsOldest should be rejected while frozen")
+	}
+}
+
+func TestCachePrefetcher(t *testing.T) {
+	l, err := NewWithOpts(2, WithPrefetcher(func(k int) []simplelru.Entry[int, int] {
+		return []simplelru.Entry[int, int]{{Key: k + 1, Value: k + 1}, {Key: k + 2, Value: -1}}
+	}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(100, 100)
+	l.Add(3, 3)
+
+	if _, ok := l.Get(1); ok {
+		t.Fatalf("1 should not be cached")
+	}
+	deadline := time.Now().Add(time.Second)
+	for !l.Contains(2) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// 2 is prefetched as the oldest entry, evicting 100; 3 is left alone
+	l.wantKeys(t, []int{2, 3})
+	if v, _ := l.Peek(3); v != 3 {
+		t.Fatalf("cached value should not be replaced: %v", v)
+	}
+
+	if _, ok := l.Get(3); !ok {
+		t.Fatalf("3 should be cached")
+	}
+}
+
+func TestCacheMinResidency(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	l, err := NewWithOpts(2, WithMinResidency[int, int](time.Minute), WithClock[int, int](clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	clock.Advance(2 * time.Minute)
+	l.AddAsOldest(2, 2)
+	l.Add(3, 3)
+	l.wantKeys(t, []int{2, 3})
+}
+
+func TestCachePanicHandler(t *testing.T) {
+	recovered := make(chan any, 2)
+	var evicted []int
+	l, err := NewWithOpts(1,
+		WithEvictCallback(func(k, v int) {
+			if k == 1 {
+				panic("evict")
+			}
+			evicted = append(evicted, k)
+		}),
+		WithPrefetcher(func(k int) []simplelru.Entry[int, int] {
+			panic("prefetch")
+		}),
+		WithPanicHandler[int, int](func(r any) { recovered <- r }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Resize(2)
+	l.Add(3, 3)
+	l.RemoveOldest()
+	l.RemoveOldest()
+	if !reflect.DeepEqual(evicted, []int{2, 3}) {
+		t.Fatalf("bad evicted keys: %v", evicted)
+	}
+	if r := <-recovered; r != "evict" {
+		t.Fatalf("bad recovered: %v", r)
+	}
+
+	l.Get(4)
+	select {
+	case r := <-recovered:
+		if r != "prefetch" {
+			t.Fatalf("bad recovered: %v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("prefetcher panic should have been recovered")
+	}
+
+	if _, err := NewWithOpts(1, WithPanicHandler[int, int](nil)); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
+
+func TestCacheMaxWeight(t *testing.T) {
+	l, err := NewWithOpts(10,
+		WithWeigher(func(k int, v string) int64 { return int64(len(v)) }),
+		WithMaxWeight[int, string](6))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, "abc")
+	l.Add(2, "abc")
+	l.Add(3, "a")
+	if l.Contains(1) || l.Weight() != 4 {
+		t.Fatalf("bad: %d, %v", l.Weight(), l.Keys())
+	}
+}
+
+func TestCacheMaxWeight_SeveralEvictions(t *testing.T) {
+	adds := map[string]func(l *Cache[int, int], key, value int) bool{
+		"Add": (*Cache[int, int]).Add,
+		"AddWithContext": func(l *Cache[int, int], key, value int) bool {
+			evicted, _ := l.AddWithContext(context.Background(), key, value)
+			return evicted
+		},
+		"AddTransient": (*Cache[int, int]).AddTransient,
+		"ContainsOrAdd": func(l *Cache[int, int], key, value int) bool {
+			_, evicted := l.ContainsOrAdd(key, value)
+			return evicted
+		},
+		"PeekOrAdd": func(l *Cache[int, int], key, value int) bool {
+			_, _, evicted := l.PeekOrAdd(key, value)
+			return evicted
+		},
+		"AddWithMeta": func(l *Cache[int, int], key, value int) bool {
+			return l.AddWithMeta(key, value, nil)
+		},
+	}
+	// AddAsOldest is left out, as the new entry would be the one evicted
+	for name, add := range adds {
+		var evicted []int
+		l, err := NewWithOpts(10,
+			WithEvictCallback(func(k, v int) { evicted = append(evicted, k) }),
+			WithWeigher(func(k, v int) int64 { return int64(v) }),
+			WithMaxWeight[int, int](10))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		for i := 0; i < 5; i++ {
+			l.Add(i, 2)
+		}
+		if !add(l, 5, 9) {
+			t.Fatalf("%s: should have evicted", name)
+		}
+		if len(evicted) < 2 || l.Len()+len(evicted) != 6 {
+			t.Fatalf("%s: bad evicted: %v", name, evicted)
+		}
+		if s := l.Stats(); s.Evictions != uint64(len(evicted)) {
+			t.Fatalf("%s: bad evictions: %d", name, s.Evictions)
+		}
+	}
+}
+
+func TestCacheAdmission(t *testing.T) {
+	f, err := simplelru.NewTinyLFU[int](2, func(k int) uint64 { return uint64(k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var evicted []int
+	l, err := NewWithOpts(2, WithEvictCallback(func(k, v int) { evicted = append(evicted, k) }), WithAdmission[int, int](f))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Get(1)
+	l.Get(2)
+	if !l.Add(3, 3) || l.Contains(3) || !reflect.DeepEqual(evicted, []int{3}) {
+		t.Fatalf("3 should be rejected: %v", evicted)
+	}
+}
+
+func TestCacheApproximatedLRU(t *testing.T) {
+	if _, err := NewWithOpts(2, WithApproximatedLRU[int, int](0)); err == nil {
+		t.Fatalf("should reject invalid sample size")
+	}
+	var evicted []int
+	l, err := NewWithOpts(64, WithEvictCallback(func(k, v int) { evicted = append(evicted, k) }),
+		WithApproximatedLRU[int, int](simplelru.DefaultSampleSize))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 64; i++ {
+		l.Add(i, i)
+	}
+	// hits don't reorder the entries
+	l.Get(0)
+	if keys := l.Keys(); keys[0] != 0 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	for i := 64; i < 128; i++ {
+		l.Add(i, i)
+	}
+	if l.Len() != 64 || len(evicted) != 64 || !l.Contains(127) {
+		t.Fatalf("bad len: %d, evicted %d", l.Len(), len(evicted))
+	}
+}
+
+func TestWithCallbackExecutor(t *testing.T) {
+	var queue []func()
+	var evicted []int
+	l, err := NewWithOpts(2,
+		WithEvictCallback(func(k, v int) { evicted = append(evicted, k) }),
+		WithCallbackExecutor[int, int](func(fn func()) { queue = append(queue, fn) }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+	l.Remove(2)
+	if len(evicted) != 0 || len(queue) != 3 {
+		t.Fatalf("callbacks should have been queued: %v, %d", evicted, len(queue))
+	}
+	for _, fn := range queue {
+		fn()
+	}
+	if !reflect.DeepEqual(evicted, []int{0, 1, 2}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+	if _, err := NewWithOpts(2, WithCallbackExecutor[int, int](nil)); err == nil {
+		t.Fatalf("should fail for a nil executor")
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"sync"
+)
+
+// CapacityPool is an overall budget of entries shared by several caches,
+// on top of their own sizes. When an addition takes the caches attached to
+// the pool past its size, the oldest entries of the cache holding the most
+// entries are evicted as if to make room in that cache, skipping its pinned
+// entries and those its options keep, through the regular evict callback.
+// Frozen caches are not evicted from. Under concurrent additions the pool may
+// briefly exceed its size or evict slightly more than needed.
+type CapacityPool struct {
+	size    int
+	members []poolMember
+	lock    sync.Mutex
+}
+
+// poolMember is a cache attached to a CapacityPool.
+type poolMember interface {
+	Len() int
+	poolEvict() bool
+}
+
+// NewCapacityPool creates a CapacityPool of the given size.
+func NewCapacityPool(size int) (*CapacityPool, error) {
+	if size <= 0 {
+		return nil, errors.New("must provide a positive size")
+	}
+	return &CapacityPool{size: size}, nil
+}
+
+// WithCapacityPool attaches the cache to pool, until it is detached with
+// CapacityPool.Detach.
+func WithCapacityPool[K comparable, V any](pool *CapacityPool) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if pool == nil {
+			return errors.New("must provide a pool")
+		}
+		c.pool = pool
+		return nil
+	}
+}
+
+// Len returns the number of entries in the caches attached to the pool.
+func (p *CapacityPool) Len() int {
+	n := 0
+	for _, m := range p.snapshot() {
+		n += m.Len()
+	}
+	return n
+}
+
+// Cap returns the size of the pool.
+func (p *CapacityPool) Cap() int {
+	return p.size
+}
+
+// Detach detaches a cache attached with WithCapacityPool, e.g. once it is
+// no longer used, so that the pool neither evicts from it nor keeps it
+// alive. Its entries stop counting towards the size of the pool. Returns
+// false if cache was not attached.
+func (p *CapacityPool) Detach(cache any) (detached bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for i, m := range p.members {
+		if m == cache {
+			// copy, as snapshots may still be iterating the previous slice
+			members := make([]poolMember, 0, len(p.members)-1)
+			members = append(members, p.members[:i]...)
+			p.members = append(members, p.members[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CapacityPool) attach(m poolMember) {
+	p.lock.Lock()
+	p.members = append(p.members, m)
+	p.lock.Unlock()
+}
+
+func (p *CapacityPool) snapshot() []poolMember {
+	p.lock.Lock()
+	members := p.members
+	p.lock.Unlock()
+	return members
+}
+
+// enforce evicts entries until the pool is within its size. It must not be
+// called with the lock of any member held.
+func (p *CapacityPool) enforce() {
+	members := p.snapshot()
+	lens := make([]int, len(members))
+	total := 0
+	for i, m := range members {
+		lens[i] = m.Len()
+		total += lens[i]
+	}
+	for total > p.size {
+		victim := -1
+		for i, n := range lens {
+			if n > 0 && (victim < 0 || n > lens[victim]) {
+				victim = i
+			}
+		}
+		if victim < 0 {
+			return
+		}
+		if members[victim].poolEvict() {
+			total--
+			lens[victim]--
+		} else {
+			lens[victim] = 0
+		}
+	}
+}
+
+// poolEvict evicts the oldest evictable entry on behalf of the pool, unless
+// frozen.
+func (c *Cache[K, V]) poolEvict() bool {
+	var k K
+	var v V
+	var m any
+	c.lock.Lock()
+	if c.frozen {
+		c.lock.Unlock()
+		return false
+	}
+	ok := c.lru.EvictOldest()
+	if ok {
+		c.stats.Evict(1)
+	}
+	if c.onEvictedCB != nil && ok {
+		k, v, m = c.takeFirstEvicted()
+	}
+	c.lock.Unlock()
+	if c.onEvictedCB != nil && ok {
+		c.notifyEvicted(k, v, m)
+	}
+	return ok
+}
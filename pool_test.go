@@ -0,0 +1,108 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapacityPool(t *testing.T) {
+	pool, err := NewCapacityPool(10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var evicted []int
+	a, err := NewWithOpts(8,
+		WithEvictCallback(func(k, v int) { evicted = append(evicted, k) }),
+		WithCapacityPool[int, int](pool))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	b, err := NewWithOpts(8, WithCapacityPool[int, int](pool))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 8; i++ {
+		a.Add(i, i)
+	}
+	for i := 100; i < 104; i++ {
+		b.Add(i, i)
+	}
+
+	// the largest cache gives up its oldest entries
+	if pool.Len() != 10 || a.Len() != 6 || b.Len() != 4 {
+		t.Fatalf("bad lens: %v, %v, %v", pool.Len(), a.Len(), b.Len())
+	}
+	if len(evicted) != 2 || evicted[0] != 0 || evicted[1] != 1 {
+		t.Fatalf("bad evicted keys: %v", evicted)
+	}
+
+	// updates don't take more capacity
+	b.Add(100, 100)
+	if a.Len() != 6 || b.Len() != 4 {
+		t.Fatalf("bad lens: %v, %v", a.Len(), b.Len())
+	}
+
+	// frozen caches are left alone
+	a.Freeze()
+	for i := 104; i < 108; i++ {
+		b.Add(i, i)
+	}
+	if a.Len() != 6 || b.Len() != 4 {
+		t.Fatalf("bad lens: %v, %v", a.Len(), b.Len())
+	}
+	if b.Contains(100) || !b.Contains(107) {
+		t.Fatalf("b should have evicted its own oldest entries: %v", b.Keys())
+	}
+
+	// evictions are counted in the stats
+	if s := b.Stats(); s.Evictions != 4 {
+		t.Fatalf("bad evictions: %d", s.Evictions)
+	}
+
+	// detached caches no longer count, so b may grow to its own size
+	if !pool.Detach(a) || pool.Detach(a) {
+		t.Fatalf("a should have been detached once")
+	}
+	a.Unfreeze()
+	for i := 108; i < 112; i++ {
+		b.Add(i, i)
+	}
+	if pool.Len() != 8 || a.Len() != 6 || b.Len() != 8 {
+		t.Fatalf("bad lens: %v, %v, %v", pool.Len(), a.Len(), b.Len())
+	}
+
+	if _, err := NewCapacityPool(0); err == nil {
+		t.Fatalf("should have failed")
+	}
+	if _, err := NewWithOpts(1, WithCapacityPool[int, int](nil)); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
+
+func TestCapacityPoolPins(t *testing.T) {
+	pool, err := NewCapacityPool(4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l, err := NewWithOpts(8,
+		WithCapacityPool[int, int](pool),
+		WithEvictionFilter(func(k, v int) bool { return k == 1 }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+	l.Pin(0)
+	l.Add(4, 4)
+	l.Add(5, 5)
+
+	// the pinned and filtered entries are skipped
+	if !reflect.DeepEqual(l.Keys(), []int{0, 1, 4, 5}) {
+		t.Fatalf("bad keys: %v", l.Keys())
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import "errors"
+
+// reverseIndexSlack is the number of stale keys a reverse index may hold
+// on top of one per entry before it is rebuilt.
+const reverseIndexSlack = 64
+
+// reverseIndex maps the value keys of entries back to their keys. It is
+// only updated on additions, so it may hold keys that were removed or
+// whose value changed since; they are checked against the cache before use
+// and dropped when the index is rebuilt.
+type reverseIndex[K comparable, V any] struct {
+	valueKey func(value V) any
+	keys     map[any]map[K]struct{}
+	size     int // number of keys in keys, including stale ones
+}
+
+// WithReverseIndex maintains an index of the keys by valueKey(value),
+// enabling RemoveByValueKey to remove all the keys caching the same
+// underlying object, e.g. aliases of a record keyed by its ID. valueKey is
+// called with the lock held and must not call back into the cache.
+func WithReverseIndex[K comparable, V any, R comparable](valueKey func(value V) R) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if valueKey == nil {
+			return errors.New("must provide a value key function")
+		}
+		c.reverse = &reverseIndex[K, V]{
+			valueKey: func(value V) any { return valueKey(value) },
+			keys:     make(map[any]map[K]struct{}),
+		}
+		return nil
+	}
+}
+
+// RemoveByValueKey removes the entries of c whose value key, as computed
+// by the function given to WithReverseIndex, equals r, and returns how
+// many were removed. The evict callback is invoked for each of them. It is
+// a function rather than a method because methods cannot introduce the
+// type of r.
+func RemoveByValueKey[K comparable, V any, R comparable](c *Cache[K, V], r R) (removed int) {
+	if c.reverse == nil {
+		return 0
+	}
+	var ks []K
+	var vs []V
+	var ms []any
+	c.lock.Lock()
+	keys := c.reverse.keys[r]
+	delete(c.reverse.keys, r)
+	c.reverse.size -= len(keys)
+	for k := range keys {
+		v, ok := c.lru.Peek(k)
+		if v, ok = c.decode(v, ok); ok && c.reverse.valueKey(v) == any(r) {
+			c.dropError(k)
+			c.lru.Remove(k)
+			removed++
+		}
+	}
+	if c.onEvictedCB != nil && len(c.evictedKeys) > 0 {
+		ks, vs, ms = c.takeEvicted()
+	}
+	c.lock.Unlock()
+	// invoke callback outside of critical section
+	c.notifyAllEvicted(ks, vs, ms)
+	return removed
+}
+
+// reindex records the stored value of key in the reverse index, if any.
+// Has to be called with lock!
+func (c *Cache[K, V]) reindex(key K, value V) {
+	x := c.reverse
+	if x == nil {
+		return
+	}
+	if x.size >= 2*c.lru.Len()+reverseIndexSlack {
+		x.keys = make(map[any]map[K]struct{}, len(x.keys))
+		x.size = 0
+		keys, values := c.lru.Keys(), c.lru.Values()
+		for i := range keys {
+			x.add(keys[i], c.decodeStored(values[i]))
+		}
+	}
+	x.add(key, c.decodeStored(value))
+}
+
+// decodeStored returns the value stored as value.
+func (c *Cache[K, V]) decodeStored(value V) V {
+	value, _ = c.decode(value, true)
+	return value
+}
+
+func (x *reverseIndex[K, V]) add(key K, value V) {
+	r := x.valueKey(value)
+	keys := x.keys[r]
+	if keys == nil {
+		keys = make(map[K]struct{})
+		x.keys[r] = keys
+	}
+	if _, ok := keys[key]; !ok {
+		keys[key] = struct{}{}
+		x.size++
+	}
+}
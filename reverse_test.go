@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type aliasedRecord struct {
+	id   int
+	name string
+}
+
+func TestRemoveByValueKey(t *testing.T) {
+	var evicted []string
+	l, err := NewWithOpts(8,
+		WithEvictCallback(func(k string, v *aliasedRecord) { evicted = append(evicted, k) }),
+		WithReverseIndex[string](func(v *aliasedRecord) int { return v.id }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	a := &aliasedRecord{1, "a"}
+	b := &aliasedRecord{2, "b"}
+	l.Add("a", a)
+	l.Add("alias-a", a)
+	l.Add("b", b)
+	l.Add("alias-b", a)
+	// alias-b no longer caches record 1
+	l.Add("alias-b", b)
+
+	if n := RemoveByValueKey(l, 1); n != 2 {
+		t.Fatalf("bad: removed %d", n)
+	}
+	sort.Strings(evicted)
+	if !reflect.DeepEqual(evicted, []string{"a", "alias-a"}) {
+		t.Fatalf("bad: %v", evicted)
+	}
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []string{"b", "alias-b"}) {
+		t.Fatalf("bad: %v", keys)
+	}
+	if n := RemoveByValueKey(l, 1); n != 0 {
+		t.Fatalf("bad: removed %d twice", n)
+	}
+
+	// Keys re-added with another value are kept
+	l.Remove("b")
+	l.Add("b", &aliasedRecord{3, "c"})
+	if n := RemoveByValueKey(l, 2); n != 1 || !l.Contains("b") {
+		t.Fatalf("bad: removed %d", n)
+	}
+}
+
+func TestReverseIndex_Rebuild(t *testing.T) {
+	l, err := NewWithOpts(4, WithReverseIndex[int](func(v int) int { return v % 2 }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 10*reverseIndexSlack; i++ {
+		l.Add(i, i)
+	}
+	if l.reverse.size > 2*l.Len()+reverseIndexSlack {
+		t.Fatalf("stale keys are not dropped: %d", l.reverse.size)
+	}
+	if n := RemoveByValueKey(l, 0); n != 2 || l.Len() != 2 {
+		t.Fatalf("bad: removed %d, %d left", n, l.Len())
+	}
+
+	// Without an index nothing is removed
+	plain, _ := New[int, int](4)
+	plain.Add(0, 0)
+	if n := RemoveByValueKey(plain, 0); n != 0 {
+		t.Fatalf("bad: removed %d", n)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+
+	"github.com/hashicorp/golang-lru/v2/internal"
+)
+
+// ShardedCache is a thread-safe fixed size LRU cache partitioned into
+// shards by the hash of the keys, each a Cache with its own lock, so that
+// concurrent operations on different keys rarely contend. Recency is only
+// tracked within a shard: an addition evicts the oldest entry of the shard
+// of its key, which may be newer than the oldest entries of other shards.
+type ShardedCache[K comparable, V any] struct {
+	shards []*Cache[K, V]
+	hash   func(key K) uint64
+}
+
+// NewSharded creates a ShardedCache of the given total size split across
+// shards caches, each configured by opts, such as WithEvictCallback. hash
+// must map equal keys to equal values and should spread them evenly, e.g.
+// StringHash for string keys. size must be at least shards.
+func NewSharded[K comparable, V any](size, shards int, hash func(key K) uint64, opts ...Option[K, V]) (*ShardedCache[K, V], error) {
+	if shards <= 0 {
+		return nil, errors.New("must provide a positive number of shards")
+	}
+	if size < shards {
+		return nil, errors.New("size must be at least the number of shards")
+	}
+	if hash == nil {
+		return nil, errors.New("must provide a key hash function")
+	}
+	c := &ShardedCache[K, V]{shards: make([]*Cache[K, V], shards), hash: hash}
+	for i := range c.shards {
+		shard, err := NewWithOpts(shardSize(size, shards, i), opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.shards[i] = shard
+	}
+	return c, nil
+}
+
+// StringHash returns a hash function for string keys, for NewSharded, with
+// a per-process random seed.
+func StringHash() func(key string) uint64 {
+	return internal.HashString
+}
+
+// shardSize returns the size of shard i of shards splitting size, giving
+// the remainder to the first shards.
+func shardSize(size, shards, i int) int {
+	n := size / shards
+	if i < size%shards {
+		n++
+	}
+	return n
+}
+
+// shard returns the shard of key.
+func (c *ShardedCache[K, V]) shard(key K) *Cache[K, V] {
+	return c.shards[c.hash(key)%uint64(len(c.shards))]
+}
+
+// Add adds a value to the cache. Returns true if an eviction occurred.
+func (c *ShardedCache[K, V]) Add(key K, value V) (evicted bool) {
+	return c.shard(key).Add(key, value)
+}
+
+// Get looks up a key's value from the cache.
+func (c *ShardedCache[K, V]) Get(key K) (value V, ok bool) {
+	return c.shard(key).Get(key)
+}
+
+// GetOrCompute returns the value of key, computing and adding it on a miss,
+// see Cache.GetOrCompute.
+func (c *ShardedCache[K, V]) GetOrCompute(key K, compute func() (V, error)) (value V, err error) {
+	return c.shard(key).GetOrCompute(key, compute)
+}
+
+// Contains checks if a key is in the cache, without updating the
+// recent-ness or deleting it for being stale.
+func (c *ShardedCache[K, V]) Contains(key K) bool {
+	return c.shard(key).Contains(key)
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the "recently used"-ness of the key.
+func (c *ShardedCache[K, V]) Peek(key K) (value V, ok bool) {
+	return c.shard(key).Peek(key)
+}
+
+// ContainsOrAdd checks if a key is in the cache without updating the
+// recent-ness or deleting it for being stale, and if not, adds the value.
+// Returns whether found and whether an eviction occurred.
+func (c *ShardedCache[K, V]) ContainsOrAdd(key K, value V) (ok, evicted bool) {
+	return c.shard(key).ContainsOrAdd(key, value)
+}
+
+// PeekOrAdd checks if a key is in the cache without updating the
+// recent-ness or deleting it for being stale, and if not, adds the value.
+// Returns whether found and whether an eviction occurred.
+func (c *ShardedCache[K, V]) PeekOrAdd(key K, value V) (previous V, ok, evicted bool) {
+	return c.shard(key).PeekOrAdd(key, value)
+}
+
+// Remove removes the provided key from the cache.
+func (c *ShardedCache[K, V]) Remove(key K) (present bool) {
+	return c.shard(key).Remove(key)
+}
+
+// Pop removes the provided key from the cache and returns its value,
+// without invoking the evict callback.
+func (c *ShardedCache[K, V]) Pop(key K) (value V, ok bool) {
+	return c.shard(key).Pop(key)
+}
+
+// Resize changes the total size of the cache, splitting it across the
+// shards as NewSharded does. Sizes below the number of shards are raised
+// to it. Returns the number of evictions.
+func (c *ShardedCache[K, V]) Resize(size int) (evicted int) {
+	if size < len(c.shards) {
+		size = len(c.shards)
+	}
+	for i, shard := range c.shards {
+		evicted += shard.Resize(shardSize(size, len(c.shards), i))
+	}
+	return evicted
+}
+
+// Purge is used to completely clear the cache.
+func (c *ShardedCache[K, V]) Purge() {
+	for _, shard := range c.shards {
+		shard.Purge()
+	}
+}
+
+// Keys returns a slice of the keys in the cache, shard by shard, each from
+// oldest to newest. The shards are copied one at a time, so the result is
+// only a consistent snapshot if there are no concurrent writes.
+func (c *ShardedCache[K, V]) Keys() []K {
+	keys := make([]K, 0, c.Len())
+	for _, shard := range c.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// Values returns a slice of the values in the cache, in the same order as
+// Keys, with the same caveat.
+func (c *ShardedCache[K, V]) Values() []V {
+	values := make([]V, 0, c.Len())
+	for _, shard := range c.shards {
+		values = append(values, shard.Values()...)
+	}
+	return values
+}
+
+// Len returns the number of items in the cache.
+func (c *ShardedCache[K, V]) Len() (length int) {
+	for _, shard := range c.shards {
+		length += shard.Len()
+	}
+	return length
+}
+
+// Cap returns the total capacity of the shards.
+func (c *ShardedCache[K, V]) Cap() (capacity int) {
+	for _, shard := range c.shards {
+		capacity += shard.Cap()
+	}
+	return capacity
+}
+
+// Stats returns the sums of the Stats of the shards.
+func (c *ShardedCache[K, V]) Stats() (stats Stats) {
+	for _, shard := range c.shards {
+		s := shard.Stats()
+		stats.Hits += s.Hits
+		stats.Misses += s.Misses
+		stats.Adds += s.Adds
+		stats.Evictions += s.Evictions
+		stats.Expirations += s.Expirations
+	}
+	return stats
+}
+
+// ResetStats sets the numbers returned by Stats back to zero.
+func (c *ShardedCache[K, V]) ResetStats() {
+	for _, shard := range c.shards {
+		shard.ResetStats()
+	}
+}
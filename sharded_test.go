@@ -0,0 +1,116 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+func TestShardedCache(t *testing.T) {
+	evicted := 0
+	c, err := NewSharded(10, 4, func(k int) uint64 { return uint64(k) },
+		WithEvictCallback(func(k, v int) { evicted++ }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if c.Cap() != 10 {
+		t.Fatalf("bad cap: %d", c.Cap())
+	}
+	if _, err := NewSharded[int, int](3, 4, func(k int) uint64 { return uint64(k) }); err == nil {
+		t.Fatalf("expected an error for a size below the number of shards")
+	}
+
+	// shards 0 and 1 hold 3 entries, shards 2 and 3 hold 2
+	for i := 0; i < 16; i++ {
+		c.Add(i, i)
+	}
+	if c.Len() != 10 || evicted != 6 {
+		t.Fatalf("bad: %d entries, %d evicted", c.Len(), evicted)
+	}
+	keys := c.Keys()
+	sort.Ints(keys)
+	want := []int{4, 5, 8, 9, 10, 11, 12, 13, 14, 15}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	for _, k := range want {
+		if v, ok := c.Get(k); !ok || v != k {
+			t.Fatalf("%d should be cached: %v", k, keys)
+		}
+	}
+	if c.Contains(6) {
+		t.Fatalf("6 should have been evicted from its shard")
+	}
+	if !c.Remove(15) || c.Contains(15) {
+		t.Fatalf("15 should have been removed")
+	}
+
+	if n := c.Resize(4); n != 5 || c.Len() != 4 || c.Cap() != 4 {
+		t.Fatalf("bad: %d evicted, %d entries, cap %d", n, c.Len(), c.Cap())
+	}
+	if s := c.Stats(); s.Adds != 16 || s.Hits != 10 || s.Evictions != 11 {
+		t.Fatalf("bad stats: %+v", s)
+	}
+	c.Purge()
+	if c.Len() != 0 {
+		t.Fatalf("bad len: %d", c.Len())
+	}
+}
+
+func TestShardedCacheConcurrent(t *testing.T) {
+	c, err := NewSharded[string, int](256, 16, StringHash())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := strconv.Itoa(g*1000 + i%64)
+				c.Add(key, i)
+				c.Get(key)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if c.Len() > 256 {
+		t.Fatalf("bad len: %d", c.Len())
+	}
+}
+
+func TestShardedCacheAdmission(t *testing.T) {
+	// a single TinyLFU is shared by the shards, under different locks
+	f, err := simplelru.NewTinyLFU[int](64, func(k int) uint64 { return uint64(k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	c, err := NewSharded[int, int](64, 8, func(k int) uint64 { return uint64(k) }, WithAdmission[int, int](f))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := g*1000 + i%128
+				c.Add(key, i)
+				c.Get(key)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if c.Len() > 64 {
+		t.Fatalf("bad len: %d", c.Len())
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/v2/internal"
+)
+
+// AdmissionPolicy decides whether a new key is worth evicting an entry for,
+// so that keys used only once don't push out the ones used repeatedly.
+type AdmissionPolicy[K comparable] interface {
+	// Record is called for every key looked up with Get or GetHandle, or
+	// added, including keys the policy then rejects.
+	Record(key K)
+	// Admit is called when adding candidate would evict victim, the oldest
+	// entry, and returns false to evict candidate instead.
+	Admit(candidate, victim K) bool
+}
+
+// WithAdmission sets a policy consulted by Add and AddEx whenever adding a
+// new key would evict an entry. A rejected value is evicted right away,
+// invoking the evict callback, as if it was added as the oldest entry, and
+// Add returns true.
+func WithAdmission[K comparable, V any](policy AdmissionPolicy[K]) Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		if policy == nil {
+			return errors.New("must provide an admission policy")
+		}
+		c.admission = policy
+		return nil
+	}
+}
+
+// admits reports whether the policy, if any, admits the key of ent, just
+// added, at the expense of the oldest entry.
+func (c *LRU[K, V]) admits(ent *internal.Entry[K, V]) bool {
+	return c.admission == nil || c.admission.Admit(ent.Key, c.evictList.Back().Key)
+}
+
+// record passes a used key to the admission policy, if any.
+func (c *LRU[K, V]) record(key K) {
+	if c.admission != nil {
+		c.admission.Record(key)
+	}
+}
+
+// tinyLFUDepth is the number of rows of the count-min sketch of a TinyLFU,
+// and tinyLFUMax the count its 4-bit counters saturate at.
+const (
+	tinyLFUDepth = 4
+	tinyLFUMax   = 15
+)
+
+// TinyLFU is an AdmissionPolicy admitting a new key only if it was used more
+// often recently than the entry it would evict. Frequencies are estimated
+// by a count-min sketch behind a doorkeeper, a Bloom filter absorbing the
+// first use of each key, so that the many keys used once don't saturate
+// the sketch. Every 10 uses per cached entry the counts are halved and the
+// doorkeeper is cleared, so that the estimates follow changes in
+// popularity. Unlike LRU, it is safe for concurrent use, so that the
+// shards of a sharded cache can share one.
+type TinyLFU[K comparable] struct {
+	mu       sync.Mutex
+	hash     func(K) uint64
+	mask     uint64 // width of the sketch rows minus 1
+	counters [tinyLFUDepth][]uint8
+	door     []uint64 // bits of the doorkeeper, 4 per sketch column
+	uses     int
+	sample   int // uses after which counts are halved
+}
+
+// NewTinyLFU creates a TinyLFU for a cache of the given size, taking 5 to
+// 9 bytes per entry. hash must map equal keys to equal values; its
+// distribution does not matter.
+func NewTinyLFU[K comparable](size int, hash func(key K) uint64) (*TinyLFU[K], error) {
+	if size <= 0 {
+		return nil, errors.New("must provide a positive size")
+	}
+	if hash == nil {
+		return nil, errors.New("must provide a key hash function")
+	}
+	width := 16
+	for width < size {
+		width *= 2
+	}
+	t := &TinyLFU[K]{
+		hash:   hash,
+		mask:   uint64(width - 1),
+		door:   make([]uint64, width/16),
+		sample: 10 * size,
+	}
+	for i := range t.counters {
+		t.counters[i] = make([]uint8, width)
+	}
+	return t, nil
+}
+
+// Record counts a use of key.
+func (t *TinyLFU[K]) Record(key K) {
+	h := internal.Mix64(t.hash(key))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.doorkeeper(h, true) {
+		for i := range t.counters {
+			if c := &t.counters[i][t.column(h, i)]; *c < tinyLFUMax {
+				*c++
+			}
+		}
+	}
+	if t.uses++; t.uses >= t.sample {
+		t.reset()
+	}
+}
+
+// Admit returns whether candidate was used more often than victim.
+func (t *TinyLFU[K]) Admit(candidate, victim K) bool {
+	hc, hv := internal.Mix64(t.hash(candidate)), internal.Mix64(t.hash(victim))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.estimate(hc) > t.estimate(hv)
+}
+
+// Estimate returns the approximate number of recent uses of key, which
+// may be overestimated but not underestimated, up to 16.
+func (t *TinyLFU[K]) Estimate(key K) int {
+	h := internal.Mix64(t.hash(key))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.estimate(h)
+}
+
+// estimate returns the estimate of the hash h of a key. Has to be called
+// with lock!
+func (t *TinyLFU[K]) estimate(h uint64) int {
+	n := uint8(tinyLFUMax)
+	for i := range t.counters {
+		if c := t.counters[i][t.column(h, i)]; c < n {
+			n = c
+		}
+	}
+	if t.doorkeeper(h, false) {
+		return int(n) + 1
+	}
+	return int(n)
+}
+
+// column returns the column of the i-th row of the sketch for h, by double
+// hashing.
+func (t *TinyLFU[K]) column(h uint64, i int) uint64 {
+	return (h + uint64(i)*(h>>32|1)) & t.mask
+}
+
+// doorkeeper reports whether h is in the doorkeeper, adding it if add.
+func (t *TinyLFU[K]) doorkeeper(h uint64, add bool) bool {
+	bits := uint64(len(t.door)) * 64
+	in := true
+	for _, x := range [2]uint64{h, h>>32 | h<<32} {
+		b := x % bits
+		if t.door[b/64]&(1<<(b%64)) == 0 {
+			in = false
+			if add {
+				t.door[b/64] |= 1 << (b % 64)
+			}
+		}
+	}
+	return in
+}
+
+// reset halves the counts and clears the doorkeeper.
+func (t *TinyLFU[K]) reset() {
+	for i := range t.counters {
+		for j := range t.counters[i] {
+			t.counters[i][j] /= 2
+		}
+	}
+	for i := range t.door {
+		t.door[i] = 0
+	}
+	t.uses /= 2
+}
@@ -0,0 +1,88 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import "testing"
+
+func identity(k int) uint64 { return uint64(k) }
+
+func TestTinyLFU(t *testing.T) {
+	if _, err := NewTinyLFU[int](0, identity); err == nil {
+		t.Fatalf("should reject invalid size")
+	}
+	if _, err := NewTinyLFU[int](1, nil); err == nil {
+		t.Fatalf("should reject nil hash")
+	}
+	f, err := NewTinyLFU[int](100, identity)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		f.Record(1)
+	}
+	f.Record(2)
+	if n := f.Estimate(1); n < 10 {
+		t.Fatalf("bad estimate: %d", n)
+	}
+	if n := f.Estimate(2); n != 1 {
+		t.Fatalf("bad estimate: %d", n)
+	}
+	if n := f.Estimate(3); n != 0 {
+		t.Fatalf("bad estimate: %d", n)
+	}
+	if !f.Admit(1, 2) || f.Admit(2, 1) || f.Admit(3, 2) {
+		t.Fatalf("should only admit more frequent keys")
+	}
+
+	// counts are halved every 1000 uses
+	for i := 0; i < 1000-11; i++ {
+		f.Record(3)
+	}
+	if n := f.Estimate(1); n != 4 {
+		t.Fatalf("bad estimate after aging: %d", n)
+	}
+	if n := f.Estimate(2); n != 0 {
+		t.Fatalf("bad estimate after aging: %d", n)
+	}
+}
+
+func TestLRU_WithAdmission(t *testing.T) {
+	if _, err := NewLRUWithOpts[int, int](2, nil, WithAdmission[int, int](nil)); err == nil {
+		t.Fatalf("should reject nil policy")
+	}
+	f, err := NewTinyLFU[int](2, identity)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var evicted []int
+	l, err := NewLRUWithOpts(2, func(k, v int) { evicted = append(evicted, k) }, WithAdmission[int, int](f))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, k := range []int{1, 2} {
+		l.Add(k, k)
+		for i := 0; i < 3; i++ {
+			l.Get(k)
+		}
+	}
+
+	// a key used once doesn't displace the oldest entry
+	if !l.Add(3, 3) || l.Contains(3) || l.Len() != 2 {
+		t.Fatalf("3 should be rejected")
+	}
+	if len(evicted) != 1 || evicted[0] != 3 {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+
+	// lookups count, even misses
+	for i := 0; i < 5; i++ {
+		l.Get(3)
+	}
+	if !l.Add(3, 3) || !l.Contains(3) || l.Contains(1) {
+		t.Fatalf("3 should be admitted, evicting 1")
+	}
+	if len(evicted) != 2 || evicted[1] != 1 {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	if !c.Now().Equal(start) {
+		t.Fatalf("bad now: %v", c.Now())
+	}
+	c.Advance(time.Minute)
+	if !c.Now().Equal(start.Add(time.Minute)) {
+		t.Fatalf("bad now: %v", c.Now())
+	}
+	c.Set(start)
+	if !c.Now().Equal(start) {
+		t.Fatalf("bad now: %v", c.Now())
+	}
+
+	var clock Clock = RealClock{}
+	if d := time.Since(clock.Now()); d < 0 || d > time.Minute {
+		t.Fatalf("real clock is off by %v", d)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import "errors"
+
+// ClockLRU implements a non-thread safe fixed size CLOCK cache, an
+// approximated LRU. Entries sit in a ring swept by a hand, and a hit only
+// sets the referenced bit of the entry instead of moving it. To evict, the
+// hand clears the bits of the referenced entries it passes and stops at the
+// first unreferenced one, which the new entry replaces. Hits are therefore
+// cheaper than in LRU and never reorder anything, at the cost of sometimes
+// evicting an entry that is not the least recently used one.
+type ClockLRU[K comparable, V any] struct {
+	size    int
+	entries []clockEntry[K, V]
+	index   map[K]int // position of each key in entries
+	hand    int
+	onEvict EvictCallback[K, V]
+}
+
+type clockEntry[K comparable, V any] struct {
+	key        K
+	value      V
+	referenced bool // whether the entry was used since the hand last passed
+}
+
+// NewClockLRU constructs a ClockLRU of the given size.
+func NewClockLRU[K comparable, V any](size int, onEvict EvictCallback[K, V]) (*ClockLRU[K, V], error) {
+	if size <= 0 {
+		return nil, errors.New("must provide a positive size")
+	}
+	c := &ClockLRU[K, V]{
+		size:    size,
+		index:   make(map[K]int),
+		onEvict: onEvict,
+	}
+	return c, nil
+}
+
+// Purge is used to completely clear the cache.
+func (c *ClockLRU[K, V]) Purge() {
+	entries := c.entries
+	c.entries = nil
+	c.index = make(map[K]int)
+	c.hand = 0
+	if c.onEvict != nil {
+		for _, e := range entries {
+			c.onEvict(e.key, e.value)
+		}
+	}
+}
+
+// Add adds a value to the cache, replacing the entry under the hand if it
+// is full. Updating the value of a contained key counts as a hit. Returns
+// true if an eviction occurred.
+func (c *ClockLRU[K, V]) Add(key K, value V) (evicted bool) {
+	if i, ok := c.index[key]; ok {
+		c.entries[i].value = value
+		c.entries[i].referenced = true
+		return false
+	}
+
+	e := clockEntry[K, V]{key: key, value: value}
+	if len(c.entries) < c.size {
+		c.index[key] = len(c.entries)
+		c.entries = append(c.entries, e)
+		return false
+	}
+	i := c.sweep()
+	old := c.entries[i]
+	delete(c.index, old.key)
+	c.entries[i] = e
+	c.index[key] = i
+	c.hand = (i + 1) % len(c.entries)
+	if c.onEvict != nil {
+		c.onEvict(old.key, old.value)
+	}
+	return true
+}
+
+// Get looks up a key's value from the cache, marking it referenced.
+func (c *ClockLRU[K, V]) Get(key K) (value V, ok bool) {
+	if i, ok := c.index[key]; ok {
+		c.entries[i].referenced = true
+		return c.entries[i].value, true
+	}
+	return
+}
+
+// Contains checks if a key is in the cache, without updating the recent-ness
+// or deleting it for being stale.
+func (c *ClockLRU[K, V]) Contains(key K) (ok bool) {
+	_, ok = c.index[key]
+	return ok
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the "recently used"-ness of the key.
+func (c *ClockLRU[K, V]) Peek(key K) (value V, ok bool) {
+	if i, ok := c.index[key]; ok {
+		return c.entries[i].value, true
+	}
+	return
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *ClockLRU[K, V]) Remove(key K) (present bool) {
+	if i, ok := c.index[key]; ok {
+		c.removeIndex(i)
+		return true
+	}
+	return false
+}
+
+// RemoveOldest removes the entry that would be evicted next, which is only
+// approximately the oldest one. The hand clears the referenced bits it
+// passes, as it does for an eviction.
+func (c *ClockLRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	if len(c.entries) == 0 {
+		return
+	}
+	i := c.sweep()
+	e := c.entries[i]
+	c.removeIndex(i)
+	return e.key, e.value, true
+}
+
+// GetOldest returns the entry that would be evicted next, without moving
+// the hand.
+func (c *ClockLRU[K, V]) GetOldest() (key K, value V, ok bool) {
+	if len(c.entries) == 0 {
+		return
+	}
+	e := c.entries[c.hand]
+	for j := 0; j < len(c.entries); j++ {
+		if i := (c.hand + j) % len(c.entries); !c.entries[i].referenced {
+			e = c.entries[i]
+			break
+		}
+	}
+	return e.key, e.value, true
+}
+
+// Keys returns a slice of the keys in the cache, in the order the hand
+// visits them.
+func (c *ClockLRU[K, V]) Keys() []K {
+	keys := make([]K, len(c.entries))
+	for j := range keys {
+		keys[j] = c.entries[(c.hand+j)%len(c.entries)].key
+	}
+	return keys
+}
+
+// Values returns a slice of the values in the cache, in the order of Keys.
+func (c *ClockLRU[K, V]) Values() []V {
+	values := make([]V, len(c.entries))
+	for j := range values {
+		values[j] = c.entries[(c.hand+j)%len(c.entries)].value
+	}
+	return values
+}
+
+// Len returns the number of items in the cache.
+func (c *ClockLRU[K, V]) Len() int {
+	return len(c.entries)
+}
+
+// Cap returns the capacity of the cache
+func (c *ClockLRU[K, V]) Cap() int {
+	return c.size
+}
+
+// Resize changes the cache size.
+func (c *ClockLRU[K, V]) Resize(size int) (evicted int) {
+	diff := len(c.entries) - size
+	if diff < 0 {
+		diff = 0
+	}
+	for i := 0; i < diff; i++ {
+		c.removeIndex(c.sweep())
+	}
+	c.size = size
+	return diff
+}
+
+// sweep advances the hand, clearing the referenced bits it passes, to the
+// first unreferenced entry and returns its position. The cache must not be
+// empty.
+func (c *ClockLRU[K, V]) sweep() int {
+	for {
+		e := &c.entries[c.hand]
+		if !e.referenced {
+			return c.hand
+		}
+		e.referenced = false
+		c.hand = (c.hand + 1) % len(c.entries)
+	}
+}
+
+// removeIndex removes the entry at position i, moving the last entry into
+// its place.
+func (c *ClockLRU[K, V]) removeIndex(i int) {
+	e := c.entries[i]
+	last := len(c.entries) - 1
+	if i != last {
+		c.entries[i] = c.entries[last]
+		c.index[c.entries[i].key] = i
+	}
+	c.entries[last] = clockEntry[K, V]{}
+	c.entries = c.entries[:last]
+	delete(c.index, e.key)
+	if c.hand >= len(c.entries) {
+		c.hand = 0
+	}
+	if c.onEvict != nil {
+		c.onEvict(e.key, e.value)
+	}
+}
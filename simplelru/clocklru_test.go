@@ -0,0 +1,120 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import (
+	"reflect"
+	"testing"
+)
+
+var _ LRUCache[int, int] = (*ClockLRU[int, int])(nil)
+
+func TestClockLRU(t *testing.T) {
+	var evicted []int
+	onEvicted := func(k int, v int) {
+		if k != v {
+			t.Fatalf("Evict values not equal (%v!=%v)", k, v)
+		}
+		evicted = append(evicted, k)
+	}
+	l, err := NewClockLRU(4, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if l.Add(i, i) {
+			t.Fatalf("should not have evicted")
+		}
+	}
+	if l.Len() != 4 || l.Cap() != 4 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+
+	// referenced entries get a second chance
+	l.Get(0)
+	l.Get(1)
+	if !l.Add(4, 4) {
+		t.Fatalf("should have evicted")
+	}
+	if !reflect.DeepEqual(evicted, []int{2}) {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+	if !reflect.DeepEqual(l.Keys(), []int{3, 0, 1, 4}) || !reflect.DeepEqual(l.Values(), []int{3, 0, 1, 4}) {
+		t.Fatalf("bad: %v, %v", l.Keys(), l.Values())
+	}
+	if k, _, ok := l.GetOldest(); !ok || k != 3 {
+		t.Fatalf("bad oldest: %v", k)
+	}
+	l.Add(5, 5)
+	if k, _, ok := l.GetOldest(); !ok || k != 0 {
+		t.Fatalf("bad oldest: %v", k)
+	}
+
+	// Peek does not reference, updates do
+	if v, ok := l.Peek(0); !ok || v != 0 {
+		t.Fatalf("bad: %v", v)
+	}
+	if k, _, _ := l.GetOldest(); k != 0 {
+		t.Fatalf("Peek should not have referenced: %v", k)
+	}
+	l.Add(0, 0)
+	if k, _, _ := l.GetOldest(); k != 1 {
+		t.Fatalf("update should have referenced: %v", k)
+	}
+
+	if k, v, ok := l.RemoveOldest(); !ok || k != 1 || v != 1 {
+		t.Fatalf("bad: %v", k)
+	}
+	if !reflect.DeepEqual(l.Keys(), []int{5, 4, 0}) {
+		t.Fatalf("bad keys: %v", l.Keys())
+	}
+	if n := l.Resize(1); n != 2 || !reflect.DeepEqual(l.Keys(), []int{0}) {
+		t.Fatalf("bad resize: %v, %v", n, l.Keys())
+	}
+	if !reflect.DeepEqual(evicted, []int{2, 3, 1, 5, 4}) {
+		t.Fatalf("bad evicted: %v", evicted)
+	}
+
+	if !l.Remove(0) || l.Remove(0) || l.Contains(0) {
+		t.Fatalf("bad remove")
+	}
+	if _, _, ok := l.RemoveOldest(); ok {
+		t.Fatalf("should be empty")
+	}
+	l.Add(6, 6)
+	l.Add(7, 7)
+	evicted = nil
+	l.Purge()
+	if l.Len() != 0 || len(evicted) != 1 {
+		t.Fatalf("bad purge: %v", evicted)
+	}
+	if _, ok := l.Get(6); ok {
+		t.Fatalf("should be purged")
+	}
+}
+
+func TestClockLRU_AllReferenced(t *testing.T) {
+	l, err := NewClockLRU[int, int](3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		l.Add(i, i)
+		l.Get(i)
+	}
+
+	// the hand goes around once, then evicts the entry it started from
+	if k, _, _ := l.GetOldest(); k != 0 {
+		t.Fatalf("bad oldest: %v", k)
+	}
+	l.Add(3, 3)
+	if l.Contains(0) || !reflect.DeepEqual(l.Keys(), []int{1, 2, 3}) {
+		t.Fatalf("bad keys: %v", l.Keys())
+	}
+
+	if _, err := NewClockLRU[int, int](0, nil); err == nil {
+		t.Fatalf("should reject a zero size")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import "github.com/hashicorp/golang-lru/v2/internal"
+
+// Handle references a single cache entry, allowing repeated access to it
+// without hashing the key again. A Handle stays valid until its entry
+// leaves the cache, after which all of its methods report false.
+type Handle[K comparable, V any] struct {
+	lru *LRU[K, V]
+	ent *internal.Entry[K, V]
+	gen uint64
+}
+
+// Valid reports whether the referenced entry is still in the cache.
+func (h Handle[K, V]) Valid() bool {
+	return h.lru != nil && h.gen == h.lru.gen && h.lru.evictList.Contains(h.ent)
+}
+
+// Key returns the key of the referenced entry.
+func (h Handle[K, V]) Key() (key K) {
+	if h.ent != nil {
+		key = h.ent.Key
+	}
+	return
+}
+
+// Value returns the entry's value without updating the "recently used"-ness
+// of the key.
+func (h Handle[K, V]) Value() (value V, ok bool) {
+	if !h.Valid() {
+		return
+	}
+	return h.ent.Value, true
+}
+
+// Touch updates the "recently used"-ness of the entry.
+func (h Handle[K, V]) Touch() bool {
+	if !h.Valid() {
+		return false
+	}
+	h.lru.touch(h.ent)
+	return true
+}
+
+// Remove removes the entry from the cache, returning if it was contained.
+func (h Handle[K, V]) Remove() bool {
+	if !h.Valid() {
+		return false
+	}
+	h.lru.removeElement(h.ent)
+	return true
+}
+
+// Newer returns a Handle to the next newer entry, or false if the referenced
+// entry is the newest one or no longer in the cache.
+func (h Handle[K, V]) Newer() (next Handle[K, V], ok bool) {
+	if !h.Valid() {
+		return
+	}
+	if ent := h.ent.PrevEntry(); ent != nil {
+		return Handle[K, V]{lru: h.lru, ent: ent, gen: h.gen}, true
+	}
+	return
+}
@@ -0,0 +1,108 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import "testing"
+
+func TestHandle(t *testing.T) {
+	l, err := NewLRU[int, int](2, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := l.GetHandle(1); ok {
+		t.Fatalf("should not find missing key")
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	h, ok := l.GetHandle(1)
+	if !ok || h.Key() != 1 {
+		t.Fatalf("missing handle")
+	}
+	l.wantKeys(t, []int{2, 1})
+
+	// updates through Add are visible through the handle
+	l.Add(1, 10)
+	if v, ok := h.Value(); !ok || v != 10 {
+		t.Fatalf("bad value: %v, %v", v, ok)
+	}
+
+	l.Add(3, 3)
+	if !h.Touch() {
+		t.Fatalf("touch should succeed")
+	}
+	l.wantKeys(t, []int{3, 1})
+
+	if !h.Remove() {
+		t.Fatalf("remove should succeed")
+	}
+	if h.Valid() || h.Touch() || h.Remove() {
+		t.Fatalf("handle should be invalid after removal")
+	}
+	if _, ok := h.Value(); ok {
+		t.Fatalf("handle should be invalid after removal")
+	}
+
+	// re-adding the key does not revive the handle
+	l.Add(1, 1)
+	if h.Valid() {
+		t.Fatalf("handle should be invalid after re-add")
+	}
+
+	h, _ = l.GetHandle(1)
+	l.Purge()
+	if h.Valid() {
+		t.Fatalf("handle should be invalid after purge")
+	}
+
+	var zero Handle[int, int]
+	if zero.Valid() || zero.Touch() || zero.Remove() {
+		t.Fatalf("zero handle should be invalid")
+	}
+}
+
+func TestHandle_Evicted(t *testing.T) {
+	l, err := NewLRU[int, int](1, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	h, _ := l.GetHandle(1)
+	l.Add(2, 2)
+	if h.Valid() {
+		t.Fatalf("handle should be invalid after eviction")
+	}
+	if h.Remove() {
+		t.Fatalf("remove should not affect other entries")
+	}
+	l.wantKeys(t, []int{2})
+}
+
+func TestHandle_Newer(t *testing.T) {
+	l, err := NewLRU[int, int](3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := l.OldestHandle(); ok {
+		t.Fatalf("empty cache should have no oldest handle")
+	}
+	for i := 0; i < 3; i++ {
+		l.Add(i, i)
+	}
+
+	var keys []int
+	for h, ok := l.OldestHandle(); ok; h, ok = h.Newer() {
+		keys = append(keys, h.Key())
+	}
+	if len(keys) != 3 || keys[0] != 0 || keys[2] != 2 {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	l.wantKeys(t, []int{0, 1, 2})
+
+	h, _ := l.OldestHandle()
+	l.Remove(0)
+	if _, ok := h.Newer(); ok {
+		t.Fatalf("removed entry should have no newer handle")
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build go1.23
+
+package simplelru
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLRU_All(t *testing.T) {
+	l, err := NewLRU[int, int](4, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i*10)
+	}
+	l.Get(0)
+
+	var keys, values []int
+	for k, v := range l.All() {
+		if k == 3 {
+			break
+		}
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	if !reflect.DeepEqual(keys, []int{1, 2}) || !reflect.DeepEqual(values, []int{10, 20}) {
+		t.Fatalf("bad: %v %v", keys, values)
+	}
+}
@@ -5,6 +5,7 @@ package simplelru
 
 import (
 	"errors"
+	"time"
 
 	"github.com/hashicorp/golang-lru/v2/internal"
 )
@@ -12,12 +13,59 @@ import (
 // EvictCallback is used to get a callback when a cache entry is evicted
 type EvictCallback[K comparable, V any] func(key K, value V)
 
+// Entry is a key/value pair as returned by the bulk accessors.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
 // LRU implements a non-thread safe fixed size LRU cache
 type LRU[K comparable, V any] struct {
 	size      int
+	peakSize  int // largest size since items was last allocated
 	evictList *internal.LruList[K, V]
 	items     map[K]*internal.Entry[K, V]
 	onEvict   EvictCallback[K, V]
+	gen       uint64 // incremented on Purge to invalidate outstanding handles
+
+	// keep can veto capacity evictions, optional
+	keep EvictionFilter[K, V]
+
+	// pinned holds the keys exempt from capacity eviction by Pin
+	pinned map[K]struct{}
+
+	// updateInPlace keeps the position of existing keys updated by Add
+	updateInPlace bool
+
+	// admission can reject new keys that would evict an entry, optional
+	admission AdmissionPolicy[K]
+
+	// onReplace is called with values replaced by an update, optional
+	onReplace ReplaceCallback[K, V]
+
+	// meta holds the metadata attached by AddWithMeta, allocated on first use
+	meta        map[K]any
+	onEvictMeta MetaEvictCallback[K, V]
+
+	// capture receives the next removed entry while AddEx runs
+	capture *Entry[K, V]
+
+	// minResidency protects entries added more recently from capacity eviction
+	minResidency time.Duration
+
+	// weigher and maxWeight bound the total weight of the entries, optional
+	weigher   Weigher[K, V]
+	maxWeight int64
+	weight    int64
+	weights   map[K]int64
+
+	// mid is an entry about halfway through evictList where AddTransient
+	// inserts, recomputed once midTTL transient insertions used it
+	mid    *internal.Entry[K, V]
+	midTTL int
+
+	// sampler picks the entries to evict with WithApproximatedLRU, optional
+	sampler *sampler[K, V]
 }
 
 // NewLRU constructs an LRU of the given size
@@ -28,6 +76,7 @@ func NewLRU[K comparable, V any](size int, onEvict EvictCallback[K, V]) (*LRU[K,
 
 	c := &LRU[K, V]{
 		size:      size,
+		peakSize:  size,
 		evictList: internal.NewList[K, V](),
 		items:     make(map[K]*internal.Entry[K, V]),
 		onEvict:   onEvict,
@@ -38,44 +87,210 @@ func NewLRU[K comparable, V any](size int, onEvict EvictCallback[K, V]) (*LRU[K,
 // Purge is used to completely clear the cache.
 func (c *LRU[K, V]) Purge() {
 	for k, v := range c.items {
-		if c.onEvict != nil {
-			c.onEvict(k, v.Value)
-		}
+		c.evicted(k, v.Value)
 		delete(c.items, k)
 	}
 	c.evictList.Init()
+	if c.weigher != nil {
+		c.weight = 0
+		c.weights = make(map[K]int64)
+	}
+	c.pinned = nil
+	c.meta = nil
+	c.gen++
+	c.mid = nil
+	if c.sampler != nil {
+		c.sampler.slots = nil
+	}
 }
 
 // Add adds a value to the cache.  Returns true if an eviction occurred.
 func (c *LRU[K, V]) Add(key K, value V) (evicted bool) {
+	c.record(key)
 	// Check for existing item
 	if ent, ok := c.items[key]; ok {
-		c.evictList.MoveToFront(ent)
-		ent.Value = value
-		return false
+		if !c.updateInPlace {
+			c.touch(ent)
+		}
+		c.replace(ent, value)
+		c.setWeight(key, value)
+		return c.removeOverweight() > 0
 	}
 
 	// Add new item
-	ent := c.evictList.PushFront(key, value)
+	ent := c.linked(c.evictList.PushFront(key, value))
 	c.items[key] = ent
+	c.setWeight(key, value)
 
 	evict := c.evictList.Length() > c.size
 	// Verify size not exceeded
+	if evict && !c.admits(ent) {
+		c.removeElement(ent)
+		return true
+	}
+	if evict {
+		evict = c.removeOldest()
+	}
+	return c.removeOverweight() > 0 || evict
+}
+
+// replace updates the value of ent, passing the previous one to onReplace.
+func (c *LRU[K, V]) replace(ent *internal.Entry[K, V], value V) {
+	if c.onReplace != nil {
+		c.onReplace(ent.Key, ent.Value, value)
+	}
+	ent.Value = value
+}
+
+// AddEx adds a value to the cache like Add, and also returns the value it
+// replaced if the key was already contained, and the entry evicted to make
+// room if any. If the addition evicted several entries, as it may with
+// WithMaxWeight, the first one is returned.
+func (c *LRU[K, V]) AddEx(key K, value V) (oldValue V, replaced bool, evictedKey K, evictedValue V, evicted bool) {
+	if ent, ok := c.items[key]; ok {
+		oldValue, replaced = ent.Value, true
+	}
+	var first Entry[K, V]
+	c.capture = &first
+	evicted = c.Add(key, value)
+	c.capture = nil
+	return oldValue, replaced, first.Key, first.Value, evicted
+}
+
+// AddMany adds the entries in order, as if by Add, but runs a single
+// eviction pass once all of them are added. Returns the number of evictions.
+func (c *LRU[K, V]) AddMany(entries []Entry[K, V]) (evicted int) {
+	for _, e := range entries {
+		if ent, ok := c.items[e.Key]; ok {
+			if !c.updateInPlace {
+				c.touch(ent)
+			}
+			c.replace(ent, e.Value)
+			c.setWeight(e.Key, e.Value)
+			continue
+		}
+		c.items[e.Key] = c.linked(c.evictList.PushFront(e.Key, e.Value))
+		c.setWeight(e.Key, e.Value)
+	}
+	for c.evictList.Length() > c.size && c.removeOldest() {
+		evicted++
+	}
+	return evicted + c.removeOverweight()
+}
+
+// AddAsOldest adds a value to the cache as its least recently used entry,
+// making room by evicting the previous oldest one if necessary. If the key
+// is already contained, only its value is updated. Returns true if an
+// eviction occurred.
+func (c *LRU[K, V]) AddAsOldest(key K, value V) (evicted bool) {
+	if ent, ok := c.items[key]; ok {
+		c.replace(ent, value)
+		c.setWeight(key, value)
+		return c.removeOverweight() > 0
+	}
+
+	// Make room first, or the new entry would be evicted right away
+	if c.evictList.Length() >= c.size {
+		evicted = c.removeOldest()
+	}
+	ent := c.linked(c.evictList.PushBack(key, value))
+	if c.sampler != nil {
+		ent.ExpiresAt = 0 // stamped as never used
+	}
+	c.items[key] = ent
+	c.setWeight(key, value)
+	return c.removeOverweight() > 0 || evicted
+}
+
+// AddTransient adds a value at about the middle of the recency order instead
+// of the front, for data such as scan results that is unlikely to be used
+// again: unless it is used before, it is evicted once about half of the
+// cache has turned over, without displacing the more recently used half.
+// If the key is already contained, only its value is updated. Returns true
+// if an eviction occurred.
+func (c *LRU[K, V]) AddTransient(key K, value V) (evicted bool) {
+	if ent, ok := c.items[key]; ok {
+		c.replace(ent, value)
+		c.setWeight(key, value)
+		return c.removeOverweight() > 0
+	}
+
+	if mid := c.midpoint(); mid != nil {
+		ent := c.linked(c.evictList.InsertAfter(key, value, mid))
+		if c.sampler != nil {
+			ent.ExpiresAt = mid.ExpiresAt
+		}
+		c.items[key] = ent
+	} else {
+		c.items[key] = c.linked(c.evictList.PushFront(key, value))
+	}
+	c.setWeight(key, value)
+
+	evict := c.evictList.Length() > c.size
 	if evict {
-		c.removeOldest()
+		evict = c.removeOldest()
 	}
-	return evict
+	return c.removeOverweight() > 0 || evict
+}
+
+// midpoint returns an entry about halfway through the recency order. To keep
+// AddTransient amortized O(1), it only walks the list again once a quarter of
+// the cache has been inserted at the previous midpoint, or it left the middle.
+func (c *LRU[K, V]) midpoint() *internal.Entry[K, V] {
+	c.midTTL--
+	if c.mid == nil || c.midTTL < 0 || !c.evictList.Contains(c.mid) || c.mid == c.evictList.Front() {
+		n := c.evictList.Length()
+		c.mid = c.evictList.Back()
+		for i := 0; i < n/2 && c.mid != nil; i++ {
+			c.mid = c.mid.PrevEntry()
+		}
+		c.midTTL = n / 4
+	}
+	return c.mid
 }
 
 // Get looks up a key's value from the cache.
 func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
+	c.record(key)
 	if ent, ok := c.items[key]; ok {
-		c.evictList.MoveToFront(ent)
+		c.touch(ent)
 		return ent.Value, true
 	}
 	return
 }
 
+// GetMany looks up the values of keys as if by Get in order, and returns
+// those found.
+func (c *LRU[K, V]) GetMany(keys []K) map[K]V {
+	found := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, ok := c.Get(key); ok {
+			found[key] = value
+		}
+	}
+	return found
+}
+
+// GetHandle looks up a key and returns a Handle to its entry, updating the
+// "recently used"-ness of the key.
+func (c *LRU[K, V]) GetHandle(key K) (h Handle[K, V], ok bool) {
+	c.record(key)
+	if ent, ok := c.items[key]; ok {
+		c.touch(ent)
+		return Handle[K, V]{lru: c, ent: ent, gen: c.gen}, true
+	}
+	return
+}
+
+// OldestHandle returns a Handle to the oldest entry, without updating
+// its "recently used"-ness.
+func (c *LRU[K, V]) OldestHandle() (h Handle[K, V], ok bool) {
+	if ent := c.evictList.Back(); ent != nil {
+		return Handle[K, V]{lru: c, ent: ent, gen: c.gen}, true
+	}
+	return
+}
+
 // Contains checks if a key is in the cache, without updating the recent-ness
 // or deleting it for being stale.
 func (c *LRU[K, V]) Contains(key K) (ok bool) {
@@ -103,6 +318,63 @@ func (c *LRU[K, V]) Remove(key K) (present bool) {
 	return false
 }
 
+// Pin exempts a cached key from capacity eviction until Unpin is called or
+// the key is removed explicitly. Pinned entries still count toward Len.
+// Returns false if the key is not cached, or if pinning it would pin as
+// many entries as the cache size, which would leave additions no entry to
+// evict.
+func (c *LRU[K, V]) Pin(key K) bool {
+	if _, ok := c.items[key]; !ok {
+		return false
+	}
+	if _, ok := c.pinned[key]; ok {
+		return true
+	}
+	if len(c.pinned)+1 >= c.size {
+		return false
+	}
+	if c.pinned == nil {
+		c.pinned = make(map[K]struct{})
+	}
+	c.pinned[key] = struct{}{}
+	return true
+}
+
+// Unpin makes a key pinned by Pin evictable again, returning if it was
+// pinned.
+func (c *LRU[K, V]) Unpin(key K) bool {
+	if _, ok := c.pinned[key]; !ok {
+		return false
+	}
+	delete(c.pinned, key)
+	return true
+}
+
+// RemoveMany removes the provided keys from the cache, returning how many
+// were present.
+func (c *LRU[K, V]) RemoveMany(keys []K) (removed int) {
+	for _, key := range keys {
+		if c.Remove(key) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Pop removes the provided key from the cache and returns its value. The
+// evict callback is not invoked, as the caller takes ownership of the value.
+func (c *LRU[K, V]) Pop(key K) (value V, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		c.unlink(ent)
+		delete(c.items, ent.Key)
+		delete(c.pinned, ent.Key)
+		delete(c.meta, ent.Key)
+		c.dropWeight(ent.Key)
+		return ent.Value, true
+	}
+	return
+}
+
 // RemoveOldest removes the oldest item from the cache.
 func (c *LRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
 	if ent := c.evictList.Back(); ent != nil {
@@ -112,6 +384,14 @@ func (c *LRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
 	return
 }
 
+// EvictOldest evicts the oldest entry the way Add makes room, skipping the
+// pinned entries and those kept by the eviction filter or WithMinResidency,
+// e.g. to enforce a budget shared with other caches. Returns false if no
+// entry could be evicted.
+func (c *LRU[K, V]) EvictOldest() bool {
+	return c.removeOldest()
+}
+
 // GetOldest returns the oldest entry
 func (c *LRU[K, V]) GetOldest() (key K, value V, ok bool) {
 	if ent := c.evictList.Back(); ent != nil {
@@ -120,6 +400,38 @@ func (c *LRU[K, V]) GetOldest() (key K, value V, ok bool) {
 	return
 }
 
+// PeekOldestN returns up to n of the oldest entries, from oldest to newest,
+// without updating their "recently used"-ness.
+func (c *LRU[K, V]) PeekOldestN(n int) []Entry[K, V] {
+	if n > c.evictList.Length() {
+		n = c.evictList.Length()
+	}
+	if n <= 0 {
+		return nil
+	}
+	entries := make([]Entry[K, V], 0, n)
+	for ent := c.evictList.Back(); ent != nil && len(entries) < n; ent = ent.PrevEntry() {
+		entries = append(entries, Entry[K, V]{Key: ent.Key, Value: ent.Value})
+	}
+	return entries
+}
+
+// PopOldestN removes up to n of the oldest entries and returns them, from
+// oldest to newest. The evict callback is not invoked, as the caller takes
+// ownership of the values.
+func (c *LRU[K, V]) PopOldestN(n int) []Entry[K, V] {
+	entries := c.PeekOldestN(n)
+	for range entries {
+		ent := c.evictList.Back()
+		c.unlink(ent)
+		delete(c.items, ent.Key)
+		delete(c.pinned, ent.Key)
+		delete(c.meta, ent.Key)
+		c.dropWeight(ent.Key)
+	}
+	return entries
+}
+
 // Keys returns a slice of the keys in the cache, from oldest to newest.
 func (c *LRU[K, V]) Keys() []K {
 	keys := make([]K, c.evictList.Length())
@@ -131,6 +443,16 @@ func (c *LRU[K, V]) Keys() []K {
 	return keys
 }
 
+// Range calls fn for each entry from oldest to newest, until fn returns
+// false, without copying them. fn must not modify the cache.
+func (c *LRU[K, V]) Range(fn func(key K, value V) bool) {
+	for ent := c.evictList.Back(); ent != nil; ent = ent.PrevEntry() {
+		if !fn(ent.Key, ent.Value) {
+			return
+		}
+	}
+}
+
 // Values returns a slice of the values in the cache, from oldest to newest.
 func (c *LRU[K, V]) Values() []V {
 	values := make([]V, len(c.items))
@@ -152,6 +474,12 @@ func (c *LRU[K, V]) Cap() int {
 	return c.size
 }
 
+// Weight returns the total weight of the entries, or 0 unless WithWeigher
+// was given.
+func (c *LRU[K, V]) Weight() int64 {
+	return c.weight
+}
+
 // Resize changes the cache size.
 func (c *LRU[K, V]) Resize(size int) (evicted int) {
 	diff := c.Len() - size
@@ -159,24 +487,156 @@ func (c *LRU[K, V]) Resize(size int) (evicted int) {
 		diff = 0
 	}
 	for i := 0; i < diff; i++ {
-		c.removeOldest()
+		if !c.removeOldest() {
+			diff = i
+			break
+		}
 	}
 	c.size = size
+	if size > c.peakSize {
+		c.peakSize = size
+	} else if size < c.peakSize/shrinkFactor {
+		c.shrinkItems()
+	}
 	return diff
 }
 
-// removeOldest removes the oldest item from the cache.
-func (c *LRU[K, V]) removeOldest() {
-	if ent := c.evictList.Back(); ent != nil {
+// shrinkFactor is how many times smaller than its peak the cache has to get
+// before Resize reallocates the items map to return memory.
+const shrinkFactor = 2
+
+// shrinkItems rebuilds the items map sized to the current contents, since Go
+// maps never release their buckets once grown.
+func (c *LRU[K, V]) shrinkItems() {
+	items := make(map[K]*internal.Entry[K, V], len(c.items))
+	for k, ent := range c.items {
+		items[k] = ent
+	}
+	c.items = items
+	c.peakSize = c.size
+}
+
+// removeOldest evicts the oldest item not pinned or kept by the eviction
+// filter, returning false if there was none.
+func (c *LRU[K, V]) removeOldest() bool {
+	var young *internal.Entry[K, V]
+	var cutoff time.Time
+	if c.minResidency > 0 {
+		cutoff = c.evictList.Now().Add(-c.minResidency)
+	}
+	if c.sampler != nil {
+		ent := c.sampler.oldest(func(ent *internal.Entry[K, V]) bool {
+			return c.kept(ent) || c.young(ent, cutoff)
+		})
+		if ent != nil {
+			c.removeElement(ent)
+			return true
+		}
+		// every sampled entry is kept, look for one in insertion order
+	}
+	for ent := c.evictList.Back(); ent != nil; ent = ent.PrevEntry() {
+		if c.kept(ent) {
+			continue
+		}
+		if c.young(ent, cutoff) {
+			if young == nil {
+				young = ent
+			}
+			continue
+		}
 		c.removeElement(ent)
+		return true
+	}
+	// every candidate is too young, don't grow beyond the size for them
+	if young != nil {
+		c.removeElement(young)
+		return true
+	}
+	return false
+}
+
+// kept reports whether ent is exempt from capacity eviction, being pinned or
+// kept by the eviction filter.
+func (c *LRU[K, V]) kept(ent *internal.Entry[K, V]) bool {
+	if _, ok := c.pinned[ent.Key]; ok {
+		return true
+	}
+	return c.keep != nil && c.keep(ent.Key, ent.Value)
+}
+
+// young reports whether ent was added after cutoff, and is thus protected by
+// WithMinResidency.
+func (c *LRU[K, V]) young(ent *internal.Entry[K, V], cutoff time.Time) bool {
+	return c.minResidency > 0 && ent.Meta != nil && ent.Meta.AddedAt.After(cutoff)
+}
+
+// touch records a use of ent, moving it to the front of the recency order,
+// or with WithApproximatedLRU stamping it in place.
+func (c *LRU[K, V]) touch(ent *internal.Entry[K, V]) {
+	if c.sampler != nil {
+		c.evictList.Touch(ent)
+		c.sampler.touch(ent)
+		return
+	}
+	c.evictList.MoveToFront(ent)
+}
+
+// linked indexes ent, just inserted into evictList, for WithApproximatedLRU,
+// and returns it.
+func (c *LRU[K, V]) linked(ent *internal.Entry[K, V]) *internal.Entry[K, V] {
+	if c.sampler != nil {
+		c.sampler.add(ent)
+	}
+	return ent
+}
+
+// unlink removes ent from evictList, and from the index of
+// WithApproximatedLRU.
+func (c *LRU[K, V]) unlink(ent *internal.Entry[K, V]) {
+	c.evictList.Remove(ent)
+	if c.sampler != nil {
+		c.sampler.remove(ent)
 	}
 }
 
 // removeElement is used to remove a given list element from the cache
 func (c *LRU[K, V]) removeElement(e *internal.Entry[K, V]) {
-	c.evictList.Remove(e)
+	c.unlink(e)
 	delete(c.items, e.Key)
-	if c.onEvict != nil {
-		c.onEvict(e.Key, e.Value)
+	delete(c.pinned, e.Key)
+	c.dropWeight(e.Key)
+	if c.capture != nil {
+		*c.capture = Entry[K, V]{Key: e.Key, Value: e.Value}
+		c.capture = nil
+	}
+	c.evicted(e.Key, e.Value)
+}
+
+// setWeight records the weight of the value of key. Weights are computed
+// once, so that changes to the value made afterwards are not accounted.
+func (c *LRU[K, V]) setWeight(key K, value V) {
+	if c.weigher == nil {
+		return
+	}
+	w := c.weigher(key, value)
+	c.weight += w - c.weights[key]
+	c.weights[key] = w
+}
+
+// dropWeight forgets the weight of a removed key.
+func (c *LRU[K, V]) dropWeight(key K) {
+	if c.weigher == nil {
+		return
+	}
+	c.weight -= c.weights[key]
+	delete(c.weights, key)
+}
+
+// removeOverweight evicts the oldest entries until the total weight fits
+// the budget or a single entry is left, returning the number of evictions.
+func (c *LRU[K, V]) removeOverweight() (evicted int) {
+	for c.weight > c.maxWeight && c.evictList.Length() > 1 && c.removeOldest() {
+		evicted++
 	}
+	return evicted
 }
@@ -214,6 +214,43 @@ func TestLRU_Resize(t *testing.T) {
 	}
 }
 
+// Test that downsizing far below the peak capacity rebuilds the items map
+// without losing entries or their order
+func TestLRU_ResizeShrink(t *testing.T) {
+	l, err := NewLRU[int, int](1024, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 1024; i++ {
+		l.Add(i, i)
+	}
+
+	// Shrinking by less than half keeps the existing map
+	items := l.items
+	if evicted := l.Resize(768); evicted != 256 {
+		t.Fatalf("256 elements should have been evicted: %v", evicted)
+	}
+	if reflect.ValueOf(l.items).Pointer() != reflect.ValueOf(items).Pointer() {
+		t.Errorf("items map should not have been reallocated")
+	}
+
+	if evicted := l.Resize(4); evicted != 764 {
+		t.Fatalf("764 elements should have been evicted: %v", evicted)
+	}
+	if reflect.ValueOf(l.items).Pointer() == reflect.ValueOf(items).Pointer() {
+		t.Errorf("items map should have been reallocated")
+	}
+	l.wantKeys(t, []int{1020, 1021, 1022, 1023})
+	for _, k := range l.Keys() {
+		if v, ok := l.Get(k); !ok || v != k {
+			t.Fatalf("bad key: %v", k)
+		}
+	}
+
+	l.Add(2000, 2000)
+	l.wantKeys(t, []int{1021, 1022, 1023, 2000})
+}
+
 func (c *LRU[K, V]) wantKeys(t *testing.T, want []K) {
 	t.Helper()
 	got := c.Keys()
@@ -256,3 +293,311 @@ func TestCache_EvictionSameKey(t *testing.T) {
 		t.Errorf("evictedKeys got: %v want: %v", evictedKeys, want)
 	}
 }
+
+// Test that PeekOldestN returns the coldest entries without updating recent-ness
+func TestLRU_PeekOldestN(t *testing.T) {
+	l, err := NewLRU[int, int](4, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entries := l.PeekOldestN(2); len(entries) != 0 {
+		t.Fatalf("bad: %v", entries)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i*10)
+	}
+
+	want := []Entry[int, int]{{Key: 0, Value: 0}, {Key: 1, Value: 10}}
+	if got := l.PeekOldestN(2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	l.wantKeys(t, []int{0, 1, 2, 3})
+
+	if got := l.PeekOldestN(10); len(got) != 4 || got[3].Key != 3 {
+		t.Fatalf("bad: %v", got)
+	}
+	if got := l.PeekOldestN(0); got != nil {
+		t.Fatalf("bad: %v", got)
+	}
+}
+
+// Test that AddAsOldest inserts at the back without displacing recent entries
+func TestLRU_AddAsOldest(t *testing.T) {
+	var evicted []int
+	l, err := NewLRU(3, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if l.AddAsOldest(1, 1) {
+		t.Errorf("should not have an eviction")
+	}
+	l.Add(2, 2)
+	l.AddAsOldest(3, 3)
+	l.wantKeys(t, []int{3, 1, 2})
+
+	// the previous oldest entry makes room, not the new one
+	if !l.AddAsOldest(4, 4) {
+		t.Errorf("should have an eviction")
+	}
+	l.wantKeys(t, []int{4, 1, 2})
+	if len(evicted) != 1 || evicted[0] != 3 {
+		t.Errorf("bad evicted keys: %v", evicted)
+	}
+
+	// existing keys keep their position
+	l.AddAsOldest(2, 20)
+	l.wantKeys(t, []int{4, 1, 2})
+	if v, _ := l.Peek(2); v != 20 {
+		t.Errorf("value should have been updated: %v", v)
+	}
+}
+
+// Test that AddTransient inserts near the middle of the recency order
+func TestLRU_AddTransient(t *testing.T) {
+	l, err := NewLRU[int, int](8, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if l.AddTransient(100, 100) {
+		t.Errorf("should not have an eviction")
+	}
+	l.Remove(100)
+
+	for i := 0; i < 8; i++ {
+		l.Add(i, i)
+	}
+
+	// a scan only turns over the older half
+	for i := 100; i < 120; i++ {
+		l.AddTransient(i, i)
+	}
+	for i := 4; i < 8; i++ {
+		if !l.Contains(i) {
+			t.Errorf("recent entry %d should not have been evicted", i)
+		}
+	}
+	if l.Len() != 8 {
+		t.Errorf("bad len: %v", l.Len())
+	}
+
+	// existing keys keep their position
+	keys := l.Keys()
+	l.AddTransient(keys[0], -1)
+	l.wantKeys(t, keys)
+
+	// hits move transient entries to the front as usual
+	l.Get(keys[0])
+	if got := l.Keys(); got[7] != keys[0] {
+		t.Errorf("bad keys: %v", got)
+	}
+
+	l.Purge()
+	l.AddTransient(1, 1)
+	l.wantKeys(t, []int{1})
+}
+
+// Test that Pop returns the value without invoking the evict callback
+func TestLRU_Pop(t *testing.T) {
+	evictCounter := 0
+	l, err := NewLRU(2, func(k, v int) { evictCounter++ })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 10)
+	l.Add(2, 20)
+	if v, ok := l.Pop(1); !ok || v != 10 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if _, ok := l.Pop(1); ok {
+		t.Fatalf("should not be contained")
+	}
+	if evictCounter != 0 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+	l.wantKeys(t, []int{2})
+}
+
+// Test that PopOldestN removes the coldest entries without invoking the evict callback
+func TestLRU_PopOldestN(t *testing.T) {
+	evictCounter := 0
+	l, err := NewLRU(4, func(k, v int) { evictCounter++ })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i*10)
+	}
+
+	want := []Entry[int, int]{{Key: 0, Value: 0}, {Key: 1, Value: 10}}
+	if got := l.PopOldestN(2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	l.wantKeys(t, []int{2, 3})
+	if evictCounter != 0 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+
+	if got := l.PopOldestN(10); len(got) != 2 || l.Len() != 0 {
+		t.Fatalf("bad: %v", got)
+	}
+	if got := l.PopOldestN(1); got != nil {
+		t.Fatalf("bad: %v", got)
+	}
+}
+
+// Test that AddMany matches sequential Adds with a single eviction pass
+func TestLRU_GetManyRemoveMany(t *testing.T) {
+	var evicted []int
+	l, err := NewLRU(4, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i*10)
+	}
+
+	found := l.GetMany([]int{2, 0, 5})
+	if !reflect.DeepEqual(found, map[int]int{0: 0, 2: 20}) {
+		t.Fatalf("bad found: %v", found)
+	}
+	l.wantKeys(t, []int{1, 3, 2, 0})
+
+	if n := l.RemoveMany([]int{3, 5, 1}); n != 2 {
+		t.Fatalf("2 elements should have been removed: %v", n)
+	}
+	if !reflect.DeepEqual(evicted, []int{3, 1}) {
+		t.Fatalf("bad evicted keys: %v", evicted)
+	}
+	l.wantKeys(t, []int{2, 0})
+}
+
+func TestLRU_Pin(t *testing.T) {
+	l, err := NewLRU[int, int](3, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		l.Add(i, i)
+	}
+	if !l.Pin(1) || !l.Pin(1) || !l.Pin(2) {
+		t.Fatalf("1 and 2 should be pinned")
+	}
+	if l.Pin(3) {
+		t.Fatalf("the last evictable entry should not be pinned")
+	}
+	if l.Pin(4) {
+		t.Fatalf("missing keys should not be pinned")
+	}
+
+	// pinned entries are skipped by capacity evictions
+	l.Add(4, 4)
+	l.Add(5, 5)
+	l.wantKeys(t, []int{1, 2, 5})
+
+	if !l.Unpin(1) || l.Unpin(1) {
+		t.Fatalf("1 should have been unpinned once")
+	}
+	l.Add(6, 6)
+	l.wantKeys(t, []int{2, 5, 6})
+
+	// and by EvictOldest, unlike RemoveOldest
+	if !l.EvictOldest() {
+		t.Fatalf("5 should have been evicted")
+	}
+	l.wantKeys(t, []int{2, 6})
+
+	// explicit removals drop the pin
+	l.Remove(2)
+	if l.Unpin(2) {
+		t.Fatalf("removed keys should not stay pinned")
+	}
+	l.Add(2, 2)
+	l.Add(7, 7)
+	l.wantKeys(t, []int{6, 2, 7})
+}
+
+func TestLRU_AddWithMeta(t *testing.T) {
+	var evicted []any
+	l, err := NewLRUWithOpts[int, int](2, nil, WithMetaEvictCallback(func(k, v int, meta any) {
+		evicted = append(evicted, meta)
+	}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.AddWithMeta(1, 1, "one")
+	l.Add(2, 2)
+	if meta, ok := l.GetMeta(1); !ok || meta != "one" {
+		t.Fatalf("bad meta: %v, %v", meta, ok)
+	}
+	if _, ok := l.GetMeta(2); ok {
+		t.Fatalf("2 has no meta")
+	}
+
+	// plain updates keep the meta
+	l.Add(1, 10)
+	if meta, _ := l.GetMeta(1); meta != "one" {
+		t.Fatalf("bad meta: %v", meta)
+	}
+	l.AddWithMeta(3, 3, "three")
+	l.AddWithMeta(4, 4, "four")
+	if !reflect.DeepEqual(evicted, []any{nil, "one"}) {
+		t.Fatalf("bad evicted meta: %v", evicted)
+	}
+	if _, ok := l.GetMeta(1); ok {
+		t.Fatalf("the meta of evicted entries should be dropped")
+	}
+
+	l.AddWithMeta(3, 3, nil)
+	if _, ok := l.GetMeta(3); ok {
+		t.Fatalf("nil should detach the meta")
+	}
+	l.Pop(4)
+	if _, ok := l.GetMeta(4); ok {
+		t.Fatalf("the meta of popped entries should be dropped")
+	}
+}
+
+func TestLRU_AddEx(t *testing.T) {
+	var evicted []int
+	l, err := NewLRU(2, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, replaced, _, _, ev := l.AddEx(1, 1); replaced || ev {
+		t.Fatalf("nothing should have been replaced or evicted")
+	}
+	l.AddEx(2, 2)
+	if old, replaced, _, _, ev := l.AddEx(1, 10); !replaced || old != 1 || ev {
+		t.Fatalf("bad: %v, %v, %v", old, replaced, ev)
+	}
+	if _, replaced, k, v, ev := l.AddEx(3, 3); replaced || !ev || k != 2 || v != 2 {
+		t.Fatalf("bad: %v, %v=%v, %v", replaced, k, v, ev)
+	}
+	if !reflect.DeepEqual(evicted, []int{2}) {
+		t.Fatalf("the evict callback should still be invoked: %v", evicted)
+	}
+	l.wantKeys(t, []int{1, 3})
+}
+
+func TestLRU_AddMany(t *testing.T) {
+	var evicted []int
+	l, err := NewLRU(3, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+
+	n := l.AddMany([]Entry[int, int]{{Key: 3, Value: 3}, {Key: 1, Value: 10}, {Key: 4, Value: 4}, {Key: 5, Value: 5}})
+	if n != 2 {
+		t.Fatalf("2 elements should have been evicted: %v", n)
+	}
+	if !reflect.DeepEqual(evicted, []int{2, 3}) {
+		t.Fatalf("bad evicted keys: %v", evicted)
+	}
+	l.wantKeys(t, []int{1, 4, 5})
+	if v, _ := l.Peek(1); v != 10 {
+		t.Fatalf("value should have been updated: %v", v)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+// MetaEvictCallback is called with the metadata attached to an evicted
+// entry by AddWithMeta, nil if there was none.
+type MetaEvictCallback[K comparable, V any] func(key K, value V, meta any)
+
+// WithMetaEvictCallback sets a callback invoked, after the evict callback,
+// for every entry the evict callback is invoked for, along with its
+// metadata.
+func WithMetaEvictCallback[K comparable, V any](onEvict MetaEvictCallback[K, V]) Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		c.onEvictMeta = onEvict
+		return nil
+	}
+}
+
+// AddWithMeta adds a value to the cache like Add, attaching meta to the
+// entry, e.g. the provenance of the value or a trace ID, so that it needn't
+// be wrapped. The metadata is kept until the entry is removed or updated
+// with AddWithMeta again; nil detaches it. Returns true if an eviction
+// occurred.
+func (c *LRU[K, V]) AddWithMeta(key K, value V, meta any) (evicted bool) {
+	evicted = c.Add(key, value)
+	if _, ok := c.items[key]; !ok {
+		return evicted
+	}
+	if meta == nil {
+		delete(c.meta, key)
+		return evicted
+	}
+	if c.meta == nil {
+		c.meta = make(map[K]any)
+	}
+	c.meta[key] = meta
+	return evicted
+}
+
+// GetMeta returns the metadata attached to a cached key by AddWithMeta,
+// without updating its "recently used"-ness.
+func (c *LRU[K, V]) GetMeta(key K) (meta any, ok bool) {
+	meta, ok = c.meta[key]
+	return meta, ok
+}
+
+// evicted passes a removed entry to the evict callbacks, and drops its
+// metadata.
+func (c *LRU[K, V]) evicted(key K, value V) {
+	if c.onEvict != nil {
+		c.onEvict(key, value)
+	}
+	if c.onEvictMeta != nil {
+		c.onEvictMeta(key, value, c.meta[key])
+	}
+	delete(c.meta, key)
+}
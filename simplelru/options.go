@@ -0,0 +1,177 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import (
+	"errors"
+	"time"
+)
+
+// Option configures an LRU constructed by NewLRUWithOpts.
+type Option[K comparable, V any] func(*LRU[K, V]) error
+
+// NewLRUWithOpts constructs an LRU of the given size configured by opts.
+func NewLRUWithOpts[K comparable, V any](size int, onEvict EvictCallback[K, V], opts ...Option[K, V]) (*LRU[K, V], error) {
+	c, err := NewLRU(size, onEvict)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if (c.weigher != nil) != (c.maxWeight > 0) {
+		return nil, errors.New("must provide both a weigher and a max weight")
+	}
+	if c.weigher != nil {
+		c.weights = make(map[K]int64)
+	}
+	return c, nil
+}
+
+// EvictionFilter is consulted before an entry is evicted to make room,
+// and returns true to keep the entry, in which case the next oldest entry
+// is considered instead.
+type EvictionFilter[K comparable, V any] func(key K, value V) (keep bool)
+
+// WithEvictionFilter sets a filter that can veto capacity evictions of
+// individual entries, e.g. ones the application still has to write back.
+// If every entry is kept the cache temporarily grows beyond its size.
+// Explicit removals such as Remove, RemoveOldest and Purge are not filtered.
+func WithEvictionFilter[K comparable, V any](filter EvictionFilter[K, V]) Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		c.keep = filter
+		return nil
+	}
+}
+
+// Weigher returns the weight of an entry, e.g. the size of its value in
+// bytes.
+type Weigher[K comparable, V any] func(key K, value V) int64
+
+// WithWeigher sets the function weighing entries for WithMaxWeight. It is
+// called once whenever a value is added or updated.
+func WithWeigher[K comparable, V any](weigher Weigher[K, V]) Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		c.weigher = weigher
+		return nil
+	}
+}
+
+// WithMaxWeight bounds the total weight of the entries, as computed by the
+// weigher, on top of their number: additions taking the total past maxWeight
+// evict the oldest entries until it fits again. An entry weighing more than
+// maxWeight on its own is kept until the next addition.
+func WithMaxWeight[K comparable, V any](maxWeight int64) Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		if maxWeight <= 0 {
+			return errors.New("must provide a positive max weight")
+		}
+		c.maxWeight = maxWeight
+		return nil
+	}
+}
+
+// WithApproximatedLRU trades the exact recency order for cheaper hits, like
+// SampledLRU: using an entry only stamps it with a tick instead of moving it
+// to the front, and capacity evictions pick the least recently used of
+// sampleSize randomly sampled entries, DefaultSampleSize being a good
+// tradeoff, at a small cost in hit ratio. Entries stay listed in insertion
+// order, which is the order of Keys, Values, Range and the handles, and in
+// which GetOldest, RemoveOldest and the other *Oldest methods pick entries.
+// Samples are drawn by a generator seeded randomly unless WithSampleSeed
+// is given.
+func WithApproximatedLRU[K comparable, V any](sampleSize int) Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		if sampleSize <= 0 {
+			return errors.New("must provide a positive sample size")
+		}
+		c.sampler = &sampler[K, V]{sampleSize: sampleSize, rand: randomSeed()}
+		return nil
+	}
+}
+
+// WithSampleSeed fixes the seed of the generator drawing the samples of
+// WithApproximatedLRU, so that the same calls evict the same entries, e.g.
+// in tests. It has to follow WithApproximatedLRU, and has no effect without.
+func WithSampleSeed[K comparable, V any](seed uint64) Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		if c.sampler != nil {
+			c.sampler.rand = seedState(seed)
+		}
+		return nil
+	}
+}
+
+// ReplaceCallback is called with the previous value of a key whenever an
+// update replaces it.
+type ReplaceCallback[K comparable, V any] func(key K, old, new V)
+
+// WithReplaceCallback sets a callback invoked whenever an addition updates
+// the value of a key that is already contained, before the value is
+// replaced. The evict callback is not invoked for replaced values.
+func WithReplaceCallback[K comparable, V any](onReplace ReplaceCallback[K, V]) Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		c.onReplace = onReplace
+		return nil
+	}
+}
+
+// WithUpdateInPlace makes Add keep the position of keys that are already
+// contained, so that updating a value, e.g. from a background refresher,
+// is not counted as a use. By default such updates promote the key.
+func WithUpdateInPlace[K comparable, V any]() Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		c.updateInPlace = true
+		return nil
+	}
+}
+
+// EntryInfo describes how an entry has been used, as tracked when the LRU
+// was created with WithAccessTracking.
+type EntryInfo struct {
+	AddedAt    time.Time // when the key was added
+	AccessedAt time.Time // when the key was last used
+	Hits       uint64    // how many times the key was used since it was added
+}
+
+// WithAccessTracking records when each entry was added and last used, and
+// how often it was used, according to clock, at the cost of an allocation
+// per entry. Looking up or updating a key counts as a use.
+func WithAccessTracking[K comparable, V any](clock Clock) Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		if clock == nil {
+			return errors.New("must provide a clock")
+		}
+		c.evictList.Now = clock.Now
+		return nil
+	}
+}
+
+// Info returns the usage of a key tracked with WithAccessTracking, without
+// updating its "recently used"-ness.
+func (c *LRU[K, V]) Info(key K) (info EntryInfo, ok bool) {
+	ent, ok := c.items[key]
+	if !ok || ent.Meta == nil {
+		return info, false
+	}
+	return EntryInfo{AddedAt: ent.Meta.AddedAt, AccessedAt: ent.Meta.AccessedAt, Hits: ent.Meta.Hits}, true
+}
+
+// WithMinResidency protects entries added less than d ago according to
+// clock from capacity evictions, which pick the oldest entry that is old
+// enough instead, so freshly filled entries aren't evicted right away by a
+// burst of misses. If no entry is old enough, the oldest one is evicted
+// anyway. Implies WithAccessTracking.
+func WithMinResidency[K comparable, V any](d time.Duration, clock Clock) Option[K, V] {
+	return func(c *LRU[K, V]) error {
+		if clock == nil {
+			return errors.New("must provide a clock")
+		}
+		c.evictList.Now = clock.Now
+		c.minResidency = d
+		return nil
+	}
+}
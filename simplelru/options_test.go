@@ -0,0 +1,313 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewLRUWithOpts(t *testing.T) {
+	if _, err := NewLRUWithOpts[int, int](0, nil); err == nil {
+		t.Fatalf("should reject invalid size")
+	}
+	fail := func(*LRU[int, int]) error { return errors.New("fail") }
+	if _, err := NewLRUWithOpts[int, int](1, nil, fail); err == nil {
+		t.Fatalf("should return option error")
+	}
+}
+
+func TestLRU_EvictionFilter(t *testing.T) {
+	var evicted []int
+	dirty := map[int]bool{1: true, 2: true}
+	l, err := NewLRUWithOpts(3, func(k, v int) { evicted = append(evicted, k) },
+		WithEvictionFilter(func(k, v int) bool { return dirty[k] }))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 1; i <= 4; i++ {
+		l.Add(i, i)
+	}
+	l.wantKeys(t, []int{1, 2, 4})
+	if len(evicted) != 1 || evicted[0] != 3 {
+		t.Fatalf("bad evicted keys: %v", evicted)
+	}
+
+	// the new entry is the only candidate left
+	dirty[4] = true
+	if !l.Add(5, 5) || l.Contains(5) {
+		t.Fatalf("5 should have been evicted")
+	}
+
+	// all candidates are kept: the cache grows past its size
+	dirty[6] = true
+	if l.Add(6, 6) {
+		t.Fatalf("should not have an eviction")
+	}
+	if l.Len() != 4 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+
+	dirty = map[int]bool{4: true}
+	if n := l.Resize(2); n != 2 {
+		t.Fatalf("2 elements should have been evicted: %v", n)
+	}
+	l.wantKeys(t, []int{4, 6})
+
+	// explicit removals are not filtered
+	if k, _, ok := l.RemoveOldest(); !ok || k != 4 {
+		t.Fatalf("RemoveOldest should ignore the filter: %v", k)
+	}
+}
+
+func TestLRU_WithUpdateInPlace(t *testing.T) {
+	l, err := NewLRUWithOpts[int, int](2, nil, WithUpdateInPlace[int, int]())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(1, 10)
+	l.wantKeys(t, []int{1, 2})
+	if v, _ := l.Peek(1); v != 10 {
+		t.Fatalf("value should have been updated: %v", v)
+	}
+
+	// reads still promote
+	l.Get(1)
+	l.wantKeys(t, []int{2, 1})
+}
+
+func TestLRU_WithReplaceCallback(t *testing.T) {
+	var replaced []int
+	l, err := NewLRUWithOpts[int, int](2, nil, WithReplaceCallback(func(k, old, new int) {
+		replaced = append(replaced, old)
+	}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(1, 10)
+	l.AddAsOldest(1, 100)
+	l.AddTransient(1, 1000)
+	l.AddMany([]Entry[int, int]{{Key: 1, Value: 10000}, {Key: 2, Value: 2}})
+	if !reflect.DeepEqual(replaced, []int{1, 10, 100, 1000}) {
+		t.Fatalf("bad replaced: %v", replaced)
+	}
+}
+
+func TestLRU_WithAccessTracking(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	l, err := NewLRUWithOpts[int, int](2, nil, WithAccessTracking[int, int](clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	clock.Advance(time.Second)
+	l.Get(1)
+	clock.Advance(time.Second)
+	l.Get(1)
+	l.Peek(1)
+
+	info, ok := l.Info(1)
+	if !ok {
+		t.Fatalf("1 should be tracked")
+	}
+	want := EntryInfo{AddedAt: time.Unix(1000, 0), AccessedAt: time.Unix(1002, 0), Hits: 2}
+	if info != want {
+		t.Fatalf("got: %+v, want: %+v", info, want)
+	}
+	if _, ok := l.Info(2); ok {
+		t.Fatalf("2 should not be contained")
+	}
+
+	if _, err := NewLRUWithOpts[int, int](2, nil, WithAccessTracking[int, int](nil)); err == nil {
+		t.Fatalf("should have failed")
+	}
+	untracked, _ := NewLRU[int, int](2, nil)
+	untracked.Add(1, 1)
+	if _, ok := untracked.Info(1); ok {
+		t.Fatalf("untracked caches should not report usage")
+	}
+}
+
+func TestLRU_WithMinResidency(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	var evicted []int
+	l, err := NewLRUWithOpts(3, func(k, v int) { evicted = append(evicted, k) },
+		WithMinResidency[int, int](time.Minute, clock))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add(1, 1)
+	l.Add(2, 2)
+	clock.Advance(2 * time.Minute)
+	l.AddAsOldest(3, 3)
+
+	// 3 is the least recently used entry but too young to be evicted
+	l.Add(4, 4)
+	l.wantKeys(t, []int{3, 2, 4})
+	if len(evicted) != 1 || evicted[0] != 1 {
+		t.Fatalf("bad evicted keys: %v", evicted)
+	}
+
+	// without old enough entries the oldest one goes anyway
+	clock.Advance(2 * time.Minute)
+	l.Add(5, 5)
+	l.Add(6, 6)
+	l.Add(7, 7)
+	l.wantKeys(t, []int{5, 6, 7})
+	l.Add(8, 8)
+	l.wantKeys(t, []int{6, 7, 8})
+}
+
+func TestLRU_WithMaxWeight(t *testing.T) {
+	var evicted []string
+	l, err := NewLRUWithOpts(10, func(k string, v []byte) { evicted = append(evicted, k) },
+		WithWeigher(func(k string, v []byte) int64 { return int64(len(v)) }),
+		WithMaxWeight[string, []byte](10))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.Add("a", make([]byte, 4))
+	l.Add("b", make([]byte, 4))
+	if l.Weight() != 8 || len(evicted) != 0 {
+		t.Fatalf("bad: %d, %v", l.Weight(), evicted)
+	}
+	if !l.Add("c", make([]byte, 4)) || l.Weight() != 8 {
+		t.Fatalf("exceeding the max weight should evict: %d", l.Weight())
+	}
+
+	// updates are reweighed
+	if !l.Add("b", make([]byte, 8)) || l.Weight() != 8 || l.Contains("c") {
+		t.Fatalf("bad: %d, %v", l.Weight(), l.Keys())
+	}
+
+	// an entry too heavy on its own is kept until the next addition
+	l.Add("d", make([]byte, 20))
+	if l.Len() != 1 || l.Weight() != 20 {
+		t.Fatalf("bad: %d, %v", l.Weight(), l.Keys())
+	}
+	l.Add("e", make([]byte, 1))
+	if l.Len() != 1 || l.Weight() != 1 {
+		t.Fatalf("bad: %d, %v", l.Weight(), l.Keys())
+	}
+
+	l.Pop("e")
+	if l.Weight() != 0 {
+		t.Fatalf("removals should release their weight: %d", l.Weight())
+	}
+	if evicted := len(evicted); evicted != 4 {
+		t.Fatalf("bad: %d evicted", evicted)
+	}
+
+	if _, err := NewLRUWithOpts[string, []byte](10, nil, WithMaxWeight[string, []byte](10)); err == nil {
+		t.Fatalf("should require a weigher")
+	}
+	if _, err := NewLRUWithOpts[string, []byte](10, nil, WithMaxWeight[string, []byte](0)); err == nil {
+		t.Fatalf("should reject a zero max weight")
+	}
+}
+
+func TestLRU_WithApproximatedLRU(t *testing.T) {
+	if _, err := NewLRUWithOpts[int, int](1, nil, WithApproximatedLRU[int, int](0)); err == nil {
+		t.Fatalf("should reject invalid sample size")
+	}
+	newLRU := func(size, sampleSize int, seed uint64) (*LRU[int, int], *[]int) {
+		evicted := new([]int)
+		l, err := NewLRUWithOpts(size, func(k, v int) { *evicted = append(*evicted, k) },
+			WithApproximatedLRU[int, int](sampleSize), WithSampleSeed[int, int](seed))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return l, evicted
+	}
+	wantIndexed := func(l *LRU[int, int]) {
+		t.Helper()
+		if len(l.sampler.slots) != l.Len() {
+			t.Fatalf("bad index length: %d, want %d", len(l.sampler.slots), l.Len())
+		}
+		for i, ent := range l.sampler.slots {
+			if int(ent.Slot) != i || l.items[ent.Key] != ent {
+				t.Fatalf("bad index entry %d: %v", i, ent.Key)
+			}
+		}
+	}
+
+	l, evicted := newLRU(128, DefaultSampleSize, 1)
+	for i := 0; i < 128; i++ {
+		l.Add(i, i)
+	}
+	if !l.Pin(0) {
+		t.Fatalf("should pin 0")
+	}
+	// hits don't reorder the entries
+	l.Get(1)
+	if h, ok := l.OldestHandle(); !ok || h.Key() != 0 || !h.Touch() {
+		t.Fatalf("bad oldest handle: %v, %v", h.Key(), ok)
+	}
+	if keys := l.Keys(); keys[0] != 0 || keys[1] != 1 {
+		t.Fatalf("bad keys: %v", keys[:2])
+	}
+	for i := 128; i < 256; i++ {
+		l.Add(i, i)
+	}
+	if l.Len() != 128 || len(*evicted) != 128 || !l.Contains(0) {
+		t.Fatalf("bad len: %d, evicted %d", l.Len(), len(*evicted))
+	}
+	// the newest entries are rarely sampled as the oldest
+	for i := 248; i < 256; i++ {
+		if !l.Contains(i) {
+			t.Fatalf("%d should not have been evicted", i)
+		}
+	}
+	wantIndexed(l)
+
+	// the same seed evicts the same entries
+	other, otherEvicted := newLRU(128, DefaultSampleSize, 1)
+	for i := 0; i < 128; i++ {
+		other.Add(i, i)
+	}
+	other.Pin(0)
+	other.Get(1)
+	other.Get(0)
+	for i := 128; i < 256; i++ {
+		other.Add(i, i)
+	}
+	if !reflect.DeepEqual(*evicted, *otherEvicted) {
+		t.Fatalf("bad evictions with the same seed")
+	}
+
+	keys := l.Keys()
+	l.Remove(keys[1])
+	l.Pop(keys[2])
+	l.PopOldestN(3)
+	if h, ok := l.GetHandle(keys[10]); !ok || !h.Remove() {
+		t.Fatalf("should remove %d", keys[10])
+	}
+	wantIndexed(l)
+	if l.Len() != 122 {
+		t.Fatalf("bad len: %d", l.Len())
+	}
+	l.Purge()
+	wantIndexed(l)
+
+	// entries added as the oldest are evicted first once sampled
+	l, evicted = newLRU(4, 64, 1)
+	for i := 0; i < 3; i++ {
+		l.Add(i, i)
+	}
+	l.AddAsOldest(3, 3)
+	l.Add(4, 4)
+	if !reflect.DeepEqual(*evicted, []int{3}) {
+		t.Fatalf("bad evictions: %v", *evicted)
+	}
+	wantIndexed(l)
+}
@@ -0,0 +1,322 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import (
+	"errors"
+	"hash/maphash"
+	"sort"
+
+	"github.com/hashicorp/golang-lru/v2/internal"
+)
+
+// DefaultSampleSize is the number of entries SampledLRU compares when
+// picking one to evict, a good tradeoff between accuracy and speed.
+const DefaultSampleSize = 5
+
+// SampledLRU implements a non-thread safe fixed size approximated LRU
+// cache. Instead of keeping entries in recency order, it stores a
+// last-access tick per entry and evicts the least recently used of a random
+// sample of entries, like Redis does. This saves the linked list pointers
+// per entry and the work of moving entries on every hit, at the cost of
+// sometimes evicting an entry that is not the least recently used one.
+// Samples are drawn by a generator seeded randomly, so that a workload cannot
+// predict and steer the evictions; Seed fixes it to reproduce them.
+type SampledLRU[K comparable, V any] struct {
+	size       int
+	sampleSize int
+	entries    []sampledEntry[K, V]
+	index      map[K]int // position of each key in entries
+	tick       uint64
+	rand       uint64
+	onEvict    EvictCallback[K, V]
+}
+
+type sampledEntry[K comparable, V any] struct {
+	key   K
+	value V
+	tick  uint64 // when the entry was last used
+}
+
+// NewSampledLRU constructs a SampledLRU of the given size, comparing
+// sampleSize entries on every eviction.
+func NewSampledLRU[K comparable, V any](size, sampleSize int, onEvict EvictCallback[K, V]) (*SampledLRU[K, V], error) {
+	if size <= 0 {
+		return nil, errors.New("must provide a positive size")
+	}
+	if sampleSize <= 0 {
+		return nil, errors.New("must provide a positive sample size")
+	}
+	c := &SampledLRU[K, V]{
+		size:       size,
+		sampleSize: sampleSize,
+		index:      make(map[K]int),
+		rand:       randomSeed(),
+		onEvict:    onEvict,
+	}
+	return c, nil
+}
+
+// Seed sets the state of the generator drawing the samples, so that the
+// same calls evict the same entries, e.g. in tests.
+func (c *SampledLRU[K, V]) Seed(seed uint64) {
+	c.rand = seedState(seed)
+}
+
+// Purge is used to completely clear the cache.
+func (c *SampledLRU[K, V]) Purge() {
+	entries := c.entries
+	c.entries = nil
+	c.index = make(map[K]int)
+	if c.onEvict != nil {
+		for _, e := range entries {
+			c.onEvict(e.key, e.value)
+		}
+	}
+}
+
+// Add adds a value to the cache. Returns true if an eviction occurred.
+func (c *SampledLRU[K, V]) Add(key K, value V) (evicted bool) {
+	c.tick++
+	if i, ok := c.index[key]; ok {
+		c.entries[i].value = value
+		c.entries[i].tick = c.tick
+		return false
+	}
+
+	if len(c.entries) >= c.size && len(c.entries) > 0 {
+		c.removeIndex(c.sampleOldest())
+		evicted = true
+	}
+	c.index[key] = len(c.entries)
+	c.entries = append(c.entries, sampledEntry[K, V]{key: key, value: value, tick: c.tick})
+	return evicted
+}
+
+// Get looks up a key's value from the cache.
+func (c *SampledLRU[K, V]) Get(key K) (value V, ok bool) {
+	if i, ok := c.index[key]; ok {
+		c.tick++
+		c.entries[i].tick = c.tick
+		return c.entries[i].value, true
+	}
+	return
+}
+
+// Contains checks if a key is in the cache, without updating the recent-ness
+// or deleting it for being stale.
+func (c *SampledLRU[K, V]) Contains(key K) (ok bool) {
+	_, ok = c.index[key]
+	return ok
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the "recently used"-ness of the key.
+func (c *SampledLRU[K, V]) Peek(key K) (value V, ok bool) {
+	if i, ok := c.index[key]; ok {
+		return c.entries[i].value, true
+	}
+	return
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *SampledLRU[K, V]) Remove(key K) (present bool) {
+	if i, ok := c.index[key]; ok {
+		c.removeIndex(i)
+		return true
+	}
+	return false
+}
+
+// RemoveOldest removes the entry that would be evicted next, which is only
+// approximately the oldest one.
+func (c *SampledLRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	if len(c.entries) == 0 {
+		return
+	}
+	i := c.sampleOldest()
+	e := c.entries[i]
+	c.removeIndex(i)
+	return e.key, e.value, true
+}
+
+// GetOldest returns the entry that would be evicted next, which is only
+// approximately the oldest one, without drawing a new sample.
+func (c *SampledLRU[K, V]) GetOldest() (key K, value V, ok bool) {
+	if len(c.entries) == 0 {
+		return
+	}
+	i, _ := c.sample(c.rand)
+	e := c.entries[i]
+	return e.key, e.value, true
+}
+
+// Keys returns a slice of the keys in the cache, from oldest to newest.
+func (c *SampledLRU[K, V]) Keys() []K {
+	entries := c.sorted()
+	keys := make([]K, len(entries))
+	for i, e := range entries {
+		keys[i] = e.key
+	}
+	return keys
+}
+
+// Values returns a slice of the values in the cache, from oldest to newest.
+func (c *SampledLRU[K, V]) Values() []V {
+	entries := c.sorted()
+	values := make([]V, len(entries))
+	for i, e := range entries {
+		values[i] = e.value
+	}
+	return values
+}
+
+// Len returns the number of items in the cache.
+func (c *SampledLRU[K, V]) Len() int {
+	return len(c.entries)
+}
+
+// Cap returns the capacity of the cache
+func (c *SampledLRU[K, V]) Cap() int {
+	return c.size
+}
+
+// Resize changes the cache size.
+func (c *SampledLRU[K, V]) Resize(size int) (evicted int) {
+	diff := len(c.entries) - size
+	if diff < 0 {
+		diff = 0
+	}
+	for i := 0; i < diff; i++ {
+		c.removeIndex(c.sampleOldest())
+	}
+	c.size = size
+	return diff
+}
+
+// sorted returns a copy of the entries from oldest to newest.
+func (c *SampledLRU[K, V]) sorted() []sampledEntry[K, V] {
+	entries := make([]sampledEntry[K, V], len(c.entries))
+	copy(entries, c.entries)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tick < entries[j].tick })
+	return entries
+}
+
+// sampleOldest returns the position of the least recently used of
+// sampleSize randomly picked entries. The cache must not be empty.
+func (c *SampledLRU[K, V]) sampleOldest() int {
+	i, next := c.sample(c.rand)
+	c.rand = next
+	return i
+}
+
+// sample returns the position of the least recently used of sampleSize
+// entries picked by the random generator in state r, and its next state.
+func (c *SampledLRU[K, V]) sample(r uint64) (oldest int, next uint64) {
+	n := uint64(len(c.entries))
+	oldest = -1
+	for i := 0; i < c.sampleSize; i++ {
+		r = xorshift(r)
+		j := int(r % n)
+		if oldest < 0 || c.entries[j].tick < c.entries[oldest].tick {
+			oldest = j
+		}
+	}
+	return oldest, r
+}
+
+// removeIndex removes the entry at position i, moving the last entry into
+// its place.
+func (c *SampledLRU[K, V]) removeIndex(i int) {
+	e := c.entries[i]
+	last := len(c.entries) - 1
+	if i != last {
+		c.entries[i] = c.entries[last]
+		c.index[c.entries[i].key] = i
+	}
+	c.entries[last] = sampledEntry[K, V]{}
+	c.entries = c.entries[:last]
+	delete(c.index, e.key)
+	if c.onEvict != nil {
+		c.onEvict(e.key, e.value)
+	}
+}
+
+// randomSeed returns a random generator state for sampling.
+func randomSeed() uint64 {
+	var h maphash.Hash
+	h.SetSeed(maphash.MakeSeed())
+	return seedState(h.Sum64())
+}
+
+// seedState turns seed into a generator state, which must not be 0.
+func seedState(seed uint64) uint64 {
+	if seed == 0 {
+		return 0x9e3779b97f4a7c15
+	}
+	return seed
+}
+
+// xorshift returns the state following r of a xorshift64 generator.
+func xorshift(r uint64) uint64 {
+	r ^= r << 13
+	r ^= r >> 7
+	r ^= r << 17
+	return r
+}
+
+// sampler replaces the recency order of an LRU created with
+// WithApproximatedLRU. It stamps entries with the tick they were last used
+// at, in their ExpiresAt, and indexes them in slots, at their Slot, to draw
+// random samples.
+type sampler[K comparable, V any] struct {
+	sampleSize int
+	rand       uint64
+	tick       int64
+	slots      []*internal.Entry[K, V]
+}
+
+// add indexes a new entry, stamped as just used.
+func (s *sampler[K, V]) add(ent *internal.Entry[K, V]) {
+	ent.Slot = int32(len(s.slots))
+	s.slots = append(s.slots, ent)
+	s.touch(ent)
+}
+
+// touch stamps ent as just used.
+func (s *sampler[K, V]) touch(ent *internal.Entry[K, V]) {
+	s.tick++
+	ent.ExpiresAt = s.tick
+}
+
+// remove drops ent from the index, moving the last entry into its place.
+func (s *sampler[K, V]) remove(ent *internal.Entry[K, V]) {
+	last := len(s.slots) - 1
+	if i := int(ent.Slot); i != last {
+		s.slots[i] = s.slots[last]
+		s.slots[i].Slot = int32(i)
+	}
+	s.slots[last] = nil
+	s.slots = s.slots[:last]
+}
+
+// oldest returns the least recently used of sampleSize randomly picked
+// entries, leaving out those skip returns true for, or nil if it left out
+// all of them.
+func (s *sampler[K, V]) oldest(skip func(ent *internal.Entry[K, V]) bool) *internal.Entry[K, V] {
+	if len(s.slots) == 0 {
+		return nil
+	}
+	n := uint64(len(s.slots))
+	var oldest *internal.Entry[K, V]
+	for i := 0; i < s.sampleSize; i++ {
+		s.rand = xorshift(s.rand)
+		ent := s.slots[s.rand%n]
+		if (oldest == nil || ent.ExpiresAt < oldest.ExpiresAt) && !skip(ent) {
+			oldest = ent
+		}
+	}
+	return oldest
+}
@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import (
+	"reflect"
+	"testing"
+)
+
+var _ LRUCache[int, int] = (*SampledLRU[int, int])(nil)
+
+func TestSampledLRU(t *testing.T) {
+	evictCounter := 0
+	onEvicted := func(k int, v int) {
+		if k != v {
+			t.Fatalf("Evict values not equal (%v!=%v)", k, v)
+		}
+		evictCounter++
+	}
+	l, err := NewSampledLRU(128, DefaultSampleSize, onEvicted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < 256; i++ {
+		l.Add(i, i)
+	}
+	if l.Len() != 128 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if l.Cap() != 128 {
+		t.Fatalf("expect %d, but %d", 128, l.Cap())
+	}
+	if evictCounter != 128 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+
+	// the newest entries are rarely sampled as the oldest
+	for i := 248; i < 256; i++ {
+		if !l.Contains(i) {
+			t.Fatalf("%d should not have been evicted", i)
+		}
+	}
+
+	keys := l.Keys()
+	for i := 1; i < len(keys); i++ {
+		if keys[i] < keys[i-1] {
+			t.Fatalf("keys out of order: %v", keys)
+		}
+	}
+	if !reflect.DeepEqual(l.Values(), keys) {
+		t.Fatalf("values differ from keys")
+	}
+	// GetOldest predicts the next eviction
+	oldest, _, ok := l.GetOldest()
+	if k, _, _ := l.GetOldest(); !ok || k != oldest {
+		t.Fatalf("GetOldest should not draw a new sample: %v", k)
+	}
+	if k, _, _ := l.RemoveOldest(); k != oldest {
+		t.Fatalf("bad oldest: %v, removed %v", oldest, k)
+	}
+	l.Add(oldest, oldest)
+	keys = l.Keys()
+
+	// hits protect entries from eviction
+	l.Get(keys[0])
+	if k, _, _ := l.GetOldest(); k == keys[0] {
+		t.Fatalf("Get should have updated recent-ness")
+	}
+	if v, ok := l.Peek(keys[1]); !ok || v != keys[1] {
+		t.Fatalf("bad: %v", v)
+	}
+
+	if !l.Remove(keys[1]) || l.Remove(keys[1]) || l.Contains(keys[1]) {
+		t.Fatalf("bad remove")
+	}
+	if _, _, ok := l.RemoveOldest(); !ok || l.Len() != 126 {
+		t.Fatalf("bad len: %v", l.Len())
+	}
+	if n := l.Resize(100); n != 26 || l.Len() != 100 {
+		t.Fatalf("bad resize: %v", n)
+	}
+
+	evictCounter = 0
+	l.Purge()
+	if l.Len() != 0 || evictCounter != 100 {
+		t.Fatalf("bad len: %v, evicted: %v", l.Len(), evictCounter)
+	}
+	if _, ok := l.Get(200); ok {
+		t.Fatalf("should contain nothing")
+	}
+}
+
+func TestSampledLRU_BadParams(t *testing.T) {
+	if _, err := NewSampledLRU[int, int](0, 5, nil); err == nil {
+		t.Fatalf("should have failed")
+	}
+	if _, err := NewSampledLRU[int, int](1, 0, nil); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
+
+func TestSampledLRU_Seed(t *testing.T) {
+	evictions := func(seed uint64) []int {
+		var evicted []int
+		l, err := NewSampledLRU(16, 2, func(k, v int) { evicted = append(evicted, k) })
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if seed != 0 {
+			l.Seed(seed)
+		}
+		for i := 0; i < 64; i++ {
+			l.Add(i, i)
+		}
+		return evicted
+	}
+	if !reflect.DeepEqual(evictions(42), evictions(42)) {
+		t.Fatalf("the same seed should evict the same entries")
+	}
+
+	// unseeded caches draw different samples
+	first := evictions(0)
+	for i := 0; i < 10; i++ {
+		if !reflect.DeepEqual(evictions(0), first) {
+			return
+		}
+	}
+	t.Fatalf("evictions should be randomized: %v", first)
+}
@@ -0,0 +1,244 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import (
+	"errors"
+
+	"github.com/hashicorp/golang-lru/v2/internal"
+)
+
+// DefaultProtectedRatio is the fraction of a SegmentedLRU reserved for the
+// protected segment by default.
+const DefaultProtectedRatio = 0.8
+
+// SegmentedLRU implements a non-thread safe fixed size segmented LRU (SLRU)
+// cache, a middle ground between LRU and 2Q. New entries go to a probation
+// segment, and move to a protected one when they are hit again. Entries
+// pushed out of the protected segment go back to probation, and only
+// entries of the probation segment are evicted, unless it is empty, so that
+// a scan of keys used once cannot flush the entries used repeatedly.
+//
+// The evict callback is invoked for entries leaving the cache, not for
+// those moving between the segments.
+type SegmentedLRU[K comparable, V any] struct {
+	size           int
+	protectedSize  int
+	protectedRatio float64
+	probation      *internal.LruList[K, V]
+	protected      *internal.LruList[K, V]
+	items          map[K]*internal.Entry[K, V]
+	onEvict        EvictCallback[K, V]
+}
+
+// NewSegmentedLRU constructs a SegmentedLRU of the given size, of which
+// the protected segment takes up to protectedRatio, between 0 and 1.
+func NewSegmentedLRU[K comparable, V any](size int, protectedRatio float64, onEvict EvictCallback[K, V]) (*SegmentedLRU[K, V], error) {
+	if size <= 0 {
+		return nil, errors.New("must provide a positive size")
+	}
+	if protectedRatio < 0.0 || protectedRatio > 1.0 {
+		return nil, errors.New("invalid protected ratio")
+	}
+	c := &SegmentedLRU[K, V]{
+		size:           size,
+		protectedSize:  int(float64(size) * protectedRatio),
+		protectedRatio: protectedRatio,
+		probation:      internal.NewList[K, V](),
+		protected:      internal.NewList[K, V](),
+		items:          make(map[K]*internal.Entry[K, V]),
+		onEvict:        onEvict,
+	}
+	return c, nil
+}
+
+// Purge is used to completely clear the cache.
+func (c *SegmentedLRU[K, V]) Purge() {
+	for k, ent := range c.items {
+		if c.onEvict != nil {
+			c.onEvict(k, ent.Value)
+		}
+		delete(c.items, k)
+	}
+	c.probation.Init()
+	c.protected.Init()
+}
+
+// Add adds a value to the cache. A new key enters the probation segment,
+// while updating the value of a contained key counts as a hit. Returns true
+// if an eviction occurred.
+func (c *SegmentedLRU[K, V]) Add(key K, value V) (evicted bool) {
+	if ent, ok := c.items[key]; ok {
+		ent.Value = value
+		c.hit(ent)
+		return false
+	}
+	c.items[key] = c.probation.PushFront(key, value)
+	if c.Len() > c.size {
+		c.removeOldest()
+		return true
+	}
+	return false
+}
+
+// Get looks up a key's value from the cache, promoting it to the protected
+// segment.
+func (c *SegmentedLRU[K, V]) Get(key K) (value V, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		c.hit(ent)
+		return ent.Value, true
+	}
+	return
+}
+
+// Contains checks if a key is in the cache, without updating the recent-ness
+// or deleting it for being stale.
+func (c *SegmentedLRU[K, V]) Contains(key K) (ok bool) {
+	_, ok = c.items[key]
+	return ok
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the "recently used"-ness of the key.
+func (c *SegmentedLRU[K, V]) Peek(key K) (value V, ok bool) {
+	if ent, ok := c.items[key]; ok {
+		return ent.Value, true
+	}
+	return
+}
+
+// Remove removes the provided key from the cache, returning if the
+// key was contained.
+func (c *SegmentedLRU[K, V]) Remove(key K) (present bool) {
+	if ent, ok := c.items[key]; ok {
+		c.removeElement(ent)
+		return true
+	}
+	return false
+}
+
+// RemoveOldest removes the next entry to be evicted from the cache, the
+// oldest of the probation segment unless it is empty.
+func (c *SegmentedLRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	if ent := c.oldest(); ent != nil {
+		c.removeElement(ent)
+		return ent.Key, ent.Value, true
+	}
+	return
+}
+
+// GetOldest returns the next entry to be evicted from the cache.
+func (c *SegmentedLRU[K, V]) GetOldest() (key K, value V, ok bool) {
+	if ent := c.oldest(); ent != nil {
+		return ent.Key, ent.Value, true
+	}
+	return
+}
+
+// Keys returns a slice of the keys in the cache in eviction order, those of
+// the probation segment from oldest to newest first.
+func (c *SegmentedLRU[K, V]) Keys() []K {
+	keys := make([]K, 0, len(c.items))
+	for _, l := range []*internal.LruList[K, V]{c.probation, c.protected} {
+		for ent := l.Back(); ent != nil; ent = ent.PrevEntry() {
+			keys = append(keys, ent.Key)
+		}
+	}
+	return keys
+}
+
+// Values returns a slice of the values in the cache, in the order of Keys.
+func (c *SegmentedLRU[K, V]) Values() []V {
+	values := make([]V, 0, len(c.items))
+	for _, l := range []*internal.LruList[K, V]{c.probation, c.protected} {
+		for ent := l.Back(); ent != nil; ent = ent.PrevEntry() {
+			values = append(values, ent.Value)
+		}
+	}
+	return values
+}
+
+// Len returns the number of items in the cache.
+func (c *SegmentedLRU[K, V]) Len() int {
+	return len(c.items)
+}
+
+// ProtectedLen returns the number of items in the protected segment.
+func (c *SegmentedLRU[K, V]) ProtectedLen() int {
+	return c.protected.Length()
+}
+
+// Cap returns the capacity of the cache.
+func (c *SegmentedLRU[K, V]) Cap() int {
+	return c.size
+}
+
+// Resize changes the cache size, keeping the ratio of the protected
+// segment, and returns the number of evictions.
+func (c *SegmentedLRU[K, V]) Resize(size int) (evicted int) {
+	c.size = size
+	c.protectedSize = int(float64(size) * c.protectedRatio)
+	for c.protected.Length() > c.protectedSize {
+		c.demote()
+	}
+	for c.Len() > size {
+		c.removeOldest()
+		evicted++
+	}
+	return evicted
+}
+
+// hit moves ent to the front of the protected segment, demoting the oldest
+// protected entries if it overflows.
+func (c *SegmentedLRU[K, V]) hit(ent *internal.Entry[K, V]) {
+	if c.protected.Contains(ent) {
+		c.protected.MoveToFront(ent)
+		return
+	}
+	if c.protectedSize == 0 {
+		c.probation.MoveToFront(ent)
+		return
+	}
+	c.probation.Remove(ent)
+	c.items[ent.Key] = c.protected.PushFront(ent.Key, ent.Value)
+	for c.protected.Length() > c.protectedSize {
+		c.demote()
+	}
+}
+
+// demote moves the oldest protected entry to the front of the probation
+// segment.
+func (c *SegmentedLRU[K, V]) demote() {
+	ent := c.protected.Back()
+	c.protected.Remove(ent)
+	c.items[ent.Key] = c.probation.PushFront(ent.Key, ent.Value)
+}
+
+// oldest returns the next entry to be evicted, or nil if the cache is empty.
+func (c *SegmentedLRU[K, V]) oldest() *internal.Entry[K, V] {
+	if ent := c.probation.Back(); ent != nil {
+		return ent
+	}
+	return c.protected.Back()
+}
+
+// removeOldest removes the next entry to be evicted from the cache.
+func (c *SegmentedLRU[K, V]) removeOldest() {
+	if ent := c.oldest(); ent != nil {
+		c.removeElement(ent)
+	}
+}
+
+// removeElement is used to remove a given list element from the cache.
+func (c *SegmentedLRU[K, V]) removeElement(e *internal.Entry[K, V]) {
+	if c.protected.Contains(e) {
+		c.protected.Remove(e)
+	} else {
+		c.probation.Remove(e)
+	}
+	delete(c.items, e.Key)
+	if c.onEvict != nil {
+		c.onEvict(e.Key, e.Value)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package simplelru
+
+import (
+	"reflect"
+	"testing"
+)
+
+var _ LRUCache[int, int] = (*SegmentedLRU[int, int])(nil)
+
+func TestSegmentedLRU(t *testing.T) {
+	if _, err := NewSegmentedLRU[int, int](0, DefaultProtectedRatio, nil); err == nil {
+		t.Fatalf("should reject invalid size")
+	}
+	if _, err := NewSegmentedLRU[int, int](4, 1.5, nil); err == nil {
+		t.Fatalf("should reject invalid ratio")
+	}
+	var evicted []int
+	l, err := NewSegmentedLRU(4, 0.5, func(k, v int) { evicted = append(evicted, k) })
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		l.Add(i, i)
+	}
+	// 0 and 1 are protected, 2 and 3 on probation
+	l.Get(0)
+	l.Get(1)
+	if l.ProtectedLen() != 2 || !reflect.DeepEqual(l.Keys(), []int{2, 3, 0, 1}) {
+		t.Fatalf("bad: %v", l.Keys())
+	}
+
+	// a scan only displaces entries on probation
+	for i := 10; i < 14; i++ {
+		if !l.Add(i, i) {
+			t.Fatalf("%d should have evicted", i)
+		}
+	}
+	if !reflect.DeepEqual(evicted, []int{2, 3, 10, 11}) {
+		t.Fatalf("bad evictions: %v", evicted)
+	}
+	if !reflect.DeepEqual(l.Keys(), []int{12, 13, 0, 1}) {
+		t.Fatalf("bad: %v", l.Keys())
+	}
+
+	// promoting 12 demotes 0, the oldest protected entry, without evicting it
+	if v, ok := l.Get(12); !ok || v != 12 {
+		t.Fatalf("bad: %v, %v", v, ok)
+	}
+	if !reflect.DeepEqual(l.Keys(), []int{13, 0, 1, 12}) || len(evicted) != 4 {
+		t.Fatalf("bad: %v", l.Keys())
+	}
+	if k, _, ok := l.GetOldest(); !ok || k != 13 {
+		t.Fatalf("bad oldest: %v", k)
+	}
+	if v, ok := l.Peek(0); !ok || v != 0 || !reflect.DeepEqual(l.Keys(), []int{13, 0, 1, 12}) {
+		t.Fatalf("Peek should not promote")
+	}
+
+	// updates count as hits, demoting 1
+	if l.Add(13, 26) {
+		t.Fatalf("update should not evict")
+	}
+	if !reflect.DeepEqual(l.Keys(), []int{0, 1, 12, 13}) || !reflect.DeepEqual(l.Values(), []int{0, 1, 12, 26}) {
+		t.Fatalf("bad: %v, %v", l.Keys(), l.Values())
+	}
+
+	if k, v, ok := l.RemoveOldest(); !ok || k != 0 || v != 0 {
+		t.Fatalf("bad: %v, %v, %v", k, v, ok)
+	}
+	if !l.Remove(12) || l.Remove(12) || l.Contains(12) {
+		t.Fatalf("12 should have been removed once")
+	}
+	if l.Len() != 2 {
+		t.Fatalf("bad len: %d", l.Len())
+	}
+
+	l.Purge()
+	if l.Len() != 0 || l.ProtectedLen() != 0 || len(evicted) != 8 {
+		t.Fatalf("bad: %d left, %v", l.Len(), evicted)
+	}
+	if _, _, ok := l.RemoveOldest(); ok {
+		t.Fatalf("expected an empty cache")
+	}
+}
+
+func TestSegmentedLRU_Resize(t *testing.T) {
+	l, err := NewSegmentedLRU[int, int](8, 0.5, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		l.Add(i, i)
+		if i < 4 {
+			l.Get(i)
+		}
+	}
+	// the protected segment shrinks to 2, demoting 0 and 1 ahead of 4 to 7
+	if n := l.Resize(4); n != 4 || l.Cap() != 4 {
+		t.Fatalf("bad: %d evicted", n)
+	}
+	if !reflect.DeepEqual(l.Keys(), []int{0, 1, 2, 3}) || l.ProtectedLen() != 2 {
+		t.Fatalf("bad: %v", l.Keys())
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package sqlcache provides a read-through cache of database/sql query
+// results, expiring them after a fixed TTL.
+package sqlcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// Querier runs queries, such as a *sql.DB, *sql.Conn or *sql.Tx.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// ScanFunc reads the result of a query into a value to cache. The rows are
+// closed by the cache afterwards.
+type ScanFunc[T any] func(rows *sql.Rows) (T, error)
+
+// Cache caches the results of queries, keyed by a digest of the statement
+// and its arguments. Errors are not cached. Concurrent misses on the same
+// key each run the query.
+type Cache[T any] struct {
+	db   Querier
+	scan ScanFunc[T]
+	lru  *expirable.LRU[string, entry[T]]
+}
+
+// entry is a cached result along with the query it came from, for
+// InvalidateFunc.
+type entry[T any] struct {
+	query string
+	args  []any
+	value T
+}
+
+// New creates a Cache of up to size results, each kept for at most ttl,
+// reading query results with scan.
+func New[T any](db Querier, size int, ttl time.Duration, scan ScanFunc[T]) (*Cache[T], error) {
+	if db == nil {
+		return nil, errors.New("must provide a querier")
+	}
+	if size <= 0 {
+		return nil, errors.New("must provide a positive size")
+	}
+	if scan == nil {
+		return nil, errors.New("must provide a scan function")
+	}
+	c := &Cache[T]{
+		db:   db,
+		scan: scan,
+		lru:  expirable.NewLRU[string, entry[T]](size, nil, ttl),
+	}
+	return c, nil
+}
+
+// Query returns the cached result of query with args, running and caching
+// it on a miss. Queries with arguments database/sql cannot convert to a
+// driver.Value by itself are run without caching.
+func (c *Cache[T]) Query(ctx context.Context, query string, args ...any) (value T, err error) {
+	key, cacheable := digest(query, args)
+	if cacheable {
+		if e, ok := c.lru.Get(key); ok {
+			return e.value, nil
+		}
+	}
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return value, err
+	}
+	defer rows.Close()
+	if value, err = c.scan(rows); err != nil {
+		return value, err
+	}
+	if err = rows.Err(); err != nil {
+		return value, err
+	}
+	if err = rows.Close(); err != nil {
+		return value, err
+	}
+
+	if cacheable {
+		c.lru.Add(key, entry[T]{query: query, args: args, value: value})
+	}
+	return value, nil
+}
+
+// Invalidate removes the cached result of query with args, returning if it
+// was cached.
+func (c *Cache[T]) Invalidate(query string, args ...any) bool {
+	key, ok := digest(query, args)
+	return ok && c.lru.Remove(key)
+}
+
+// InvalidateFunc removes the cached results of the queries for which match
+// returns true, e.g. all those reading a table after a write to it, and
+// returns how many were removed. match must not modify args.
+func (c *Cache[T]) InvalidateFunc(match func(query string, args []any) bool) (removed int) {
+	for _, key := range c.lru.Keys() {
+		e, ok := c.lru.Peek(key)
+		if ok && match(e.query, e.args) && c.lru.Remove(key) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Purge removes all cached results.
+func (c *Cache[T]) Purge() {
+	c.lru.Purge()
+}
+
+// Len returns the number of cached results.
+func (c *Cache[T]) Len() int {
+	return c.lru.Len()
+}
+
+// digest returns the cache key of query with args. Arguments are told apart
+// by the driver.Value they are converted to, as by database/sql for drivers
+// without their own converter, so that arguments are only keyed alike if
+// they are passed to the driver alike. Returns false if an argument cannot
+// be converted, in which case the query is not cached.
+func digest(query string, args []any) (key string, ok bool) {
+	h := sha256.New()
+	var n [8]byte
+	writeUint := func(v uint64) {
+		binary.LittleEndian.PutUint64(n[:], v)
+		h.Write(n[:])
+	}
+	write := func(s string) {
+		writeUint(uint64(len(s)))
+		h.Write([]byte(s))
+	}
+	write(query)
+	for _, arg := range args {
+		name := ""
+		if na, ok := arg.(sql.NamedArg); ok {
+			name, arg = na.Name, na.Value
+		}
+		write(name)
+		v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			return "", false
+		}
+		switch v := v.(type) {
+		case nil:
+			h.Write([]byte{0})
+		case int64:
+			h.Write([]byte{1})
+			writeUint(uint64(v))
+		case float64:
+			h.Write([]byte{2})
+			writeUint(math.Float64bits(v))
+		case bool:
+			h.Write([]byte{3})
+			if v {
+				writeUint(1)
+			} else {
+				writeUint(0)
+			}
+		case []byte:
+			h.Write([]byte{4})
+			write(string(v))
+		case string:
+			h.Write([]byte{5})
+			write(v)
+		case time.Time:
+			b, err := v.MarshalBinary()
+			if err != nil {
+				return "", false
+			}
+			h.Write([]byte{6})
+			write(string(b))
+		default:
+			return "", false
+		}
+	}
+	return string(h.Sum(nil)), true
+}
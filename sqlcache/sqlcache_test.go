@@ -0,0 +1,223 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testDriver answers every query with a single row holding the query text
+// followed by its arguments, and fails queries starting with "fail".
+type testDriver struct{ queries int64 }
+
+func (d *testDriver) Open(string) (driver.Conn, error) { return testConn{d}, nil }
+
+type testConn struct{ d *testDriver }
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) { return testStmt{c.d, query}, nil }
+func (testConn) Close() error                                { return nil }
+func (testConn) Begin() (driver.Tx, error)                   { return nil, errors.New("not supported") }
+
+type testStmt struct {
+	d     *testDriver
+	query string
+}
+
+func (testStmt) Close() error  { return nil }
+func (testStmt) NumInput() int { return -1 }
+func (testStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	atomic.AddInt64(&s.d.queries, 1)
+	if strings.HasPrefix(s.query, "fail") {
+		return nil, errors.New("query failed")
+	}
+	row := s.query
+	for _, arg := range args {
+		row += " " + fmt.Sprint(arg)
+	}
+	return &testRows{row: row}, nil
+}
+
+type testRows struct {
+	row  string
+	done bool
+}
+
+func (*testRows) Columns() []string { return []string{"row"} }
+func (*testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.row
+	return nil
+}
+
+func scanRow(rows *sql.Rows) (row string, err error) {
+	if !rows.Next() {
+		return "", sql.ErrNoRows
+	}
+	err = rows.Scan(&row)
+	return row, err
+}
+
+var testDrivers int64
+
+func newTestCache(t *testing.T) (*Cache[string], *testDriver) {
+	d := &testDriver{}
+	name := fmt.Sprintf("sqlcache_test%d", atomic.AddInt64(&testDrivers, 1))
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	c, err := New[string](db, 8, time.Hour, scanRow)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return c, d
+}
+
+func TestCache_Query(t *testing.T) {
+	c, d := newTestCache(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		row, err := c.Query(ctx, "select a", "x")
+		if err != nil || row != "select a x" {
+			t.Fatalf("bad: %q, %v", row, err)
+		}
+	}
+	if d.queries != 1 {
+		t.Fatalf("hits should not query: %d queries", d.queries)
+	}
+
+	// Different arguments are different keys
+	if row, _ := c.Query(ctx, "select a", "y"); row != "select a y" {
+		t.Fatalf("bad: %q", row)
+	}
+	if row, _ := c.Query(ctx, "select a", int64(1)); row != "select a 1" {
+		t.Fatalf("bad: %q", row)
+	}
+	if d.queries != 3 || c.Len() != 3 {
+		t.Fatalf("bad: %d queries, %d cached", d.queries, c.Len())
+	}
+
+	// Errors are not cached
+	for i := 0; i < 2; i++ {
+		if _, err := c.Query(ctx, "fail"); err == nil {
+			t.Fatalf("should fail")
+		}
+	}
+	if d.queries != 5 || c.Len() != 3 {
+		t.Fatalf("bad: %d queries, %d cached", d.queries, c.Len())
+	}
+}
+
+// opaqueID formats all its values alike.
+type opaqueID int64
+
+func (opaqueID) String() string { return "id" }
+
+// upper is passed to drivers in upper case.
+type upper string
+
+func (u upper) Value() (driver.Value, error) { return strings.ToUpper(string(u)), nil }
+
+func TestCache_QueryArgs(t *testing.T) {
+	c, d := newTestCache(t)
+	ctx := context.Background()
+
+	// Arguments formatted alike are still different keys
+	if row, _ := c.Query(ctx, "select a", opaqueID(1)); row != "select a 1" {
+		t.Fatalf("bad: %q", row)
+	}
+	if row, _ := c.Query(ctx, "select a", opaqueID(2)); row != "select a 2" {
+		t.Fatalf("bad: %q", row)
+	}
+	if d.queries != 2 || c.Len() != 2 {
+		t.Fatalf("bad: %d queries, %d cached", d.queries, c.Len())
+	}
+
+	// Arguments passed to the driver alike share a key
+	if row, _ := c.Query(ctx, "select a", int32(1)); row != "select a 1" {
+		t.Fatalf("bad: %q", row)
+	}
+	if row, _ := c.Query(ctx, "select b", upper("x")); row != "select b X" {
+		t.Fatalf("bad: %q", row)
+	}
+	if row, _ := c.Query(ctx, "select b", "X"); row != "select b X" {
+		t.Fatalf("bad: %q", row)
+	}
+	if d.queries != 3 || c.Len() != 3 {
+		t.Fatalf("bad: %d queries, %d cached", d.queries, c.Len())
+	}
+
+	// Arguments that cannot be converted are not cached
+	if _, err := c.Query(ctx, "select c", struct{}{}); err == nil {
+		t.Fatalf("should fail")
+	}
+	if c.Len() != 3 || c.Invalidate("select c", struct{}{}) {
+		t.Fatalf("bad: %d cached", c.Len())
+	}
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	c, d := newTestCache(t)
+	ctx := context.Background()
+
+	c.Query(ctx, "select a", "x")
+	c.Query(ctx, "select a", "y")
+	c.Query(ctx, "select b", "x")
+
+	if !c.Invalidate("select a", "x") {
+		t.Fatalf("should invalidate a cached result")
+	}
+	if c.Invalidate("select a", "x") {
+		t.Fatalf("should not invalidate twice")
+	}
+	c.Query(ctx, "select a", "x")
+	if d.queries != 4 {
+		t.Fatalf("invalidated results should be queried again: %d queries", d.queries)
+	}
+
+	removed := c.InvalidateFunc(func(query string, args []any) bool {
+		return strings.HasPrefix(query, "select a")
+	})
+	if removed != 2 || c.Len() != 1 {
+		t.Fatalf("bad: removed %d, %d cached", removed, c.Len())
+	}
+
+	c.Purge()
+	if c.Len() != 0 {
+		t.Fatalf("bad: %d cached", c.Len())
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New[string](nil, 8, time.Hour, scanRow); err == nil {
+		t.Fatalf("should reject a nil querier")
+	}
+	c, _ := newTestCache(t)
+	if _, err := New[string](c.db, 0, time.Hour, scanRow); err == nil {
+		t.Fatalf("should reject a zero size")
+	}
+	if _, err := New[string](c.db, 8, time.Hour, nil); err == nil {
+		t.Fatalf("should reject a nil scan function")
+	}
+}
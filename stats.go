@@ -0,0 +1,56 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import "github.com/hashicorp/golang-lru/v2/simplelru"
+
+// Stats are the numbers of operations of a cache, see simplelru.Stats.
+type Stats = simplelru.Stats
+
+// Stats returns the numbers of lookups with Get and GetHandle, additions,
+// and capacity evictions since the cache was created or ResetStats was
+// called. Entries of Cache do not expire, so Expirations is always 0.
+func (c *Cache[K, V]) Stats() Stats {
+	return c.stats.Stats()
+}
+
+// ResetStats sets the numbers returned by Stats back to zero.
+func (c *Cache[K, V]) ResetStats() {
+	c.stats.Reset()
+}
+
+// ReuseDistance returns the approximate size of the smallest LRU cache in
+// which a fraction p of the lookups with Get or GetHandle since the cache
+// was created would have hit, e.g. 0.95 to size a cache for a 95% hit ratio
+// on live traffic. It returns false unless WithReuseDistance was given, or
+// if too many lookups were of new keys or of keys last looked up too long
+// ago to tell.
+func (c *Cache[K, V]) ReuseDistance(p float64) (size int, ok bool) {
+	if c.reuse == nil {
+		return 0, false
+	}
+	c.lock.RLock()
+	size, ok = c.reuse.Percentile(p)
+	c.lock.RUnlock()
+	return size, ok
+}
+
+// countAdd counts an addition in the stats, which caused evicted evictions.
+func (c *Cache[K, V]) countAdd(evicted int) {
+	c.stats.Add(1)
+	c.stats.Evict(evicted)
+}
+
+// Stats returns the numbers of lookups with Get, additions with Add and
+// AddTransient, and capacity evictions since the cache was created or
+// ResetStats was called. Entries of TwoQueueCache do not expire, so
+// Expirations is always 0.
+func (c *TwoQueueCache[K, V]) Stats() Stats {
+	return c.stats.Stats()
+}
+
+// ResetStats sets the numbers returned by Stats back to zero.
+func (c *TwoQueueCache[K, V]) ResetStats() {
+	c.stats.Reset()
+}
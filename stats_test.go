@@ -0,0 +1,119 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import "testing"
+
+func TestCache_Stats(t *testing.T) {
+	l, err := New[int, int](2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	l.Get(3)
+	l.Get(1)
+	l.GetHandle(2)
+	l.Peek(3)
+
+	want := Stats{Hits: 2, Misses: 1, Adds: 3, Evictions: 1}
+	if s := l.Stats(); s != want {
+		t.Fatalf("got %+v, want %+v", s, want)
+	}
+	if r := l.Stats().HitRatio(); r < 0.66 || r > 0.67 {
+		t.Fatalf("bad: %v", r)
+	}
+
+	l.ResetStats()
+	if s := l.Stats(); s != (Stats{}) {
+		t.Fatalf("bad: %+v", s)
+	}
+	if r := l.Stats().HitRatio(); r != 0 {
+		t.Fatalf("bad: %v", r)
+	}
+}
+
+func Test2Q_Stats(t *testing.T) {
+	l, err := New2Q[int, int](2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 1)
+	l.Get(1)
+	l.Add(2, 2)
+	l.Add(3, 3)
+	l.Get(4)
+
+	want := Stats{Hits: 1, Misses: 1, Adds: 3, Evictions: 1}
+	if s := l.Stats(); s != want {
+		t.Fatalf("got %+v, want %+v", s, want)
+	}
+	l.ResetStats()
+	if s := l.Stats(); s != (Stats{}) {
+		t.Fatalf("bad: %+v", s)
+	}
+}
+
+func TestCache_ReuseDistance(t *testing.T) {
+	hash := func(k int) uint64 { return uint64(k) }
+	if _, err := NewWithOpts[int, int](1, WithReuseDistance[int, int](0, hash)); err == nil {
+		t.Fatalf("should reject a zero sampling rate")
+	}
+	if _, err := NewWithOpts[int, int](1, WithReuseDistance[int, int](1, nil)); err == nil {
+		t.Fatalf("should reject nil hash")
+	}
+	l, err := New[int, int](10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Get(1)
+	l.Get(1)
+	if _, ok := l.ReuseDistance(0.5); ok {
+		t.Fatalf("untracked cache should report nothing")
+	}
+
+	// cycling over 100 keys, every lookup but the first of each key needs
+	// them all cached
+	l, err = NewWithOpts(10, WithReuseDistance[int, int](1, hash))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := l.ReuseDistance(0.5); ok {
+		t.Fatalf("should report nothing before any lookup")
+	}
+	for i := 0; i < 5000; i++ {
+		l.Get(i % 100)
+	}
+	if n, ok := l.ReuseDistance(0.95); !ok || n != 100 {
+		t.Fatalf("got %d, %v", n, ok)
+	}
+	if _, ok := l.ReuseDistance(0.99); ok {
+		t.Fatalf("2%% of the lookups are of new keys")
+	}
+
+	// cycling over 20000 keys, of which about 2000 are tracked
+	l, err = NewWithOpts(10, WithReuseDistance[int, int](10, hash))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 200000; i++ {
+		l.Get(i % 20000)
+	}
+	if n, ok := l.ReuseDistance(0.9); !ok || n < 19000 || n > 21000 {
+		t.Fatalf("got %d, %v", n, ok)
+	}
+
+	// distances beyond the tracked keys count as misses
+	l, err = NewWithOpts(10, WithReuseDistance[int, int](1, hash))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 50000; i++ {
+		l.Get(i % 5000)
+	}
+	if _, ok := l.ReuseDistance(0.5); ok {
+		t.Fatalf("distances of 5000 keys should not be tracked")
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build go1.20
+
+package lru
+
+// CacheV1Compat is the v1 Cache API, with interface{} keys and values,
+// implemented by the generic Cache. Code still written against v1 can switch
+// its import path and constructor while sharing the v2 implementation, and
+// then migrate to concrete type parameters one call site at a time.
+//
+// Using interface{} as a comparable type argument requires Go 1.20.
+type CacheV1Compat = Cache[interface{}, interface{}]
+
+// TwoQueueCacheV1Compat is the v1 TwoQueueCache API, with interface{} keys
+// and values, implemented by the generic TwoQueueCache.
+type TwoQueueCacheV1Compat = TwoQueueCache[interface{}, interface{}]
+
+// NewV1Compat creates a v1 compatible LRU of the given size.
+func NewV1Compat(size int) (*CacheV1Compat, error) {
+	return New[interface{}, interface{}](size)
+}
+
+// NewV1CompatWithEvict constructs a v1 compatible fixed size cache with the
+// given eviction callback.
+func NewV1CompatWithEvict(size int, onEvicted func(key interface{}, value interface{})) (*CacheV1Compat, error) {
+	return NewWithEvict[interface{}, interface{}](size, onEvicted)
+}
+
+// New2QV1Compat creates a v1 compatible TwoQueueCache using the default
+// values for the parameters.
+func New2QV1Compat(size int) (*TwoQueueCacheV1Compat, error) {
+	return New2Q[interface{}, interface{}](size)
+}
+
+// New2QParamsV1Compat creates a v1 compatible TwoQueueCache using the
+// provided parameter values.
+func New2QParamsV1Compat(size int, recentRatio, ghostRatio float64) (*TwoQueueCacheV1Compat, error) {
+	return New2QParams[interface{}, interface{}](size, recentRatio, ghostRatio)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build go1.20
+
+package lru
+
+import "testing"
+
+// v1Cache is the method set of the v1 Cache.
+type v1Cache interface {
+	Purge()
+	Add(key, value interface{}) (evicted bool)
+	Get(key interface{}) (value interface{}, ok bool)
+	Contains(key interface{}) bool
+	Peek(key interface{}) (value interface{}, ok bool)
+	ContainsOrAdd(key, value interface{}) (ok, evicted bool)
+	PeekOrAdd(key, value interface{}) (previous interface{}, ok, evicted bool)
+	Remove(key interface{}) (present bool)
+	Resize(size int) (evicted int)
+	RemoveOldest() (key, value interface{}, ok bool)
+	GetOldest() (key, value interface{}, ok bool)
+	Keys() []interface{}
+	Len() int
+}
+
+// v1TwoQueueCache is the method set of the v1 TwoQueueCache.
+type v1TwoQueueCache interface {
+	Get(key interface{}) (value interface{}, ok bool)
+	Add(key, value interface{})
+	Len() int
+	Keys() []interface{}
+	Remove(key interface{})
+	Purge()
+	Contains(key interface{}) bool
+	Peek(key interface{}) (value interface{}, ok bool)
+}
+
+func TestV1Compat(t *testing.T) {
+	evictCounter := 0
+	l, err := NewV1CompatWithEvict(2, func(k, v interface{}) {
+		if k.(string) != "a" || v.(int) != 1 {
+			t.Fatalf("bad evicted entry: %v, %v", k, v)
+		}
+		evictCounter++
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var c v1Cache = l
+
+	c.Add("a", 1)
+	c.Add("b", "two")
+	c.Add(3, 3.0)
+	if evictCounter != 1 {
+		t.Fatalf("bad evict count: %v", evictCounter)
+	}
+	if v, ok := c.Get("b"); !ok || v.(string) != "two" {
+		t.Fatalf("bad value: %v", v)
+	}
+	if c.Len() != 2 {
+		t.Fatalf("bad len: %v", c.Len())
+	}
+
+	if _, err := NewV1Compat(0); err == nil {
+		t.Fatalf("should reject invalid size")
+	}
+
+	q, err := New2QV1Compat(4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var qc v1TwoQueueCache = q
+	qc.Add("a", 1)
+	if v, ok := qc.Get("a"); !ok || v.(int) != 1 {
+		t.Fatalf("bad value: %v", v)
+	}
+	if _, err := New2QParamsV1Compat(4, 2, 0.5); err == nil {
+		t.Fatalf("should reject invalid recent ratio")
+	}
+}
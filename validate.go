@@ -0,0 +1,127 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+var (
+	// ErrZeroKey rejects additions of zero-value keys, see WithRejectZeroKey.
+	ErrZeroKey = errors.New("lru: zero-value key")
+
+	// ErrZeroValue rejects additions of zero values, see WithRejectZeroValue.
+	ErrZeroValue = errors.New("lru: zero value")
+)
+
+// Validator returns a non-nil error for entries that must not be added.
+type Validator[K comparable, V any] func(key K, value V) error
+
+// validation holds the validators checked before additions.
+type validation[K comparable, V any] struct {
+	validators []Validator[K, V]
+	onReject   func(key K, value V, err error)
+}
+
+// WithValidator adds a validator checked before every addition, by any of
+// the Add methods, a prefetch or GetOrCompute. Entries it rejects are not
+// added, as if the cache was frozen, and are passed to the reject callback
+// set by WithRejectCallback; without one the addition panics with the
+// error, which makes tests fail loudly. Validators are called in the order
+// given, without holding the lock.
+func WithValidator[K comparable, V any](validator Validator[K, V]) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if validator == nil {
+			return errors.New("must provide a validator")
+		}
+		v := c.initValidation()
+		v.validators = append(v.validators, validator)
+		return nil
+	}
+}
+
+// WithRejectZeroKey rejects additions of zero-value keys with ErrZeroKey,
+// which usually come from callers ignoring a parse error.
+func WithRejectZeroKey[K comparable, V any]() Option[K, V] {
+	return WithValidator(func(key K, _ V) error {
+		var zero K
+		if key == zero {
+			return ErrZeroKey
+		}
+		return nil
+	})
+}
+
+// WithRejectZeroValue rejects additions of zero values, such as nil
+// pointers or empty strings, with ErrZeroValue.
+func WithRejectZeroValue[K comparable, V any]() Option[K, V] {
+	return WithValidator(func(_ K, value V) error {
+		if reflect.ValueOf(&value).Elem().IsZero() {
+			return ErrZeroValue
+		}
+		return nil
+	})
+}
+
+// WithRejectCallback sets the callback receiving the entries rejected by
+// validators, with the error, instead of panicking. It is called without
+// holding the lock.
+func WithRejectCallback[K comparable, V any](onReject func(key K, value V, err error)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if onReject == nil {
+			return errors.New("must provide a reject callback")
+		}
+		c.initValidation().onReject = onReject
+		return nil
+	}
+}
+
+func (c *Cache[K, V]) initValidation() *validation[K, V] {
+	if c.validation == nil {
+		c.validation = new(validation[K, V])
+	}
+	return c.validation
+}
+
+// rejects reports whether key and value fail validation, after passing
+// them to the reject callback or panicking.
+func (c *Cache[K, V]) rejects(key K, value V) bool {
+	if c.validation == nil {
+		return false
+	}
+	for _, validate := range c.validation.validators {
+		if err := validate(key, value); err != nil {
+			if c.validation.onReject == nil {
+				panic(err)
+			}
+			c.validation.onReject(key, value, err)
+			return true
+		}
+	}
+	return false
+}
+
+// validEntries returns the entries that pass validation, sharing entries
+// if all of them do.
+func (c *Cache[K, V]) validEntries(entries []simplelru.Entry[K, V]) []simplelru.Entry[K, V] {
+	if c.validation == nil {
+		return entries
+	}
+	for i, e := range entries {
+		if !c.rejects(e.Key, e.Value) {
+			continue
+		}
+		valid := append(make([]simplelru.Entry[K, V], 0, len(entries)-1), entries[:i]...)
+		for _, e := range entries[i+1:] {
+			if !c.rejects(e.Key, e.Value) {
+				valid = append(valid, e)
+			}
+		}
+		return valid
+	}
+	return entries
+}
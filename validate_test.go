@@ -0,0 +1,76 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lru
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+func TestCacheRejectZeroKey(t *testing.T) {
+	type rejection struct {
+		key   string
+		value *int
+		err   error
+	}
+	var rejected []rejection
+	l, err := NewWithOpts(4,
+		WithRejectZeroKey[string, *int](),
+		WithRejectZeroValue[string, *int](),
+		WithRejectCallback(func(key string, value *int, err error) {
+			rejected = append(rejected, rejection{key, value, err})
+		}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	one := 1
+	l.Add("", &one)
+	l.Add("a", nil)
+	l.ContainsOrAdd("", &one)
+	l.PeekOrAdd("b", nil)
+	l.AddMany([]simplelru.Entry[string, *int]{{Key: "c", Value: &one}, {Key: "", Value: &one}, {Key: "d", Value: &one}})
+	if keys := l.Keys(); !reflect.DeepEqual(keys, []string{"c", "d"}) {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	want := []error{ErrZeroKey, ErrZeroValue, ErrZeroKey, ErrZeroValue, ErrZeroKey}
+	if len(rejected) != len(want) {
+		t.Fatalf("got %d rejections, want %d", len(rejected), len(want))
+	}
+	for i, r := range rejected {
+		if r.err != want[i] {
+			t.Fatalf("rejection %d: got %v, want %v", i, r.err, want[i])
+		}
+	}
+	if s := l.Stats(); s.Adds != 2 {
+		t.Fatalf("rejected entries should not be counted: %+v", s)
+	}
+}
+
+func TestCacheValidatorPanics(t *testing.T) {
+	errOdd := errors.New("odd value")
+	l, err := NewWithOpts(4, WithValidator(func(key, value int) error {
+		if value%2 != 0 {
+			return errOdd
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l.Add(1, 2)
+
+	defer func() {
+		if r := recover(); r != errOdd {
+			t.Fatalf("got %v, want a panic with %v", r, errOdd)
+		}
+		if l.Len() != 1 {
+			t.Fatalf("the rejected entry should not be added")
+		}
+	}()
+	l.Add(2, 3)
+}